@@ -0,0 +1,52 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"github.com/mdhender/server/internal/engine"
+	"github.com/mdhender/server/internal/obsolete/auth"
+	"github.com/mdhender/server/internal/way"
+	"net/http"
+)
+
+// authorizer returns the authorization for a request, or nil if the
+// request is not authorized.
+type authorizer func(r *http.Request) *auth.Authorization
+
+// refuseAll authorizes no requests. It stands in until users can log in,
+// so the polity routes are refused rather than served to anyone who asks.
+func refuseAll(r *http.Request) *auth.Authorization {
+	return nil
+}
+
+// polityOwner refuses the request unless the caller is the polity
+// named in the route or is an admin.
+func polityOwner(authorize authorizer, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a := authorize(r)
+		if a == nil {
+			engineError(w, r, fmt.Errorf("missing authorization: %w", engine.ERRUNAUTHORIZED))
+			return
+		}
+		if id := way.Param(r.Context(), "polity_id"); a.ID != id && !a.HasRole("admin") {
+			engineError(w, r, fmt.Errorf("polity %q: %w", id, engine.ERRFORBIDDEN))
+			return
+		}
+		next(w, r)
+	}
+}
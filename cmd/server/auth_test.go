@@ -0,0 +1,95 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/matryer/is"
+	"github.com/mdhender/server/internal/engine"
+	"github.com/mdhender/server/internal/obsolete/auth"
+	"github.com/mdhender/server/internal/way"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_PolityOwner(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		auth   *auth.Authorization
+		method string
+		path   string
+		status int
+	}{
+		{"owner", &auth.Authorization{ID: "usagi"}, "GET", "/api/polity/usagi/inbox", http.StatusOK},
+		{"another polity", &auth.Authorization{ID: "kuma"}, "GET", "/api/polity/usagi/inbox", http.StatusForbidden},
+		{"admin", &auth.Authorization{ID: "admin", Roles: map[string]bool{"admin": true}}, "GET", "/api/polity/usagi/inbox", http.StatusOK},
+		{"not authorized", nil, "GET", "/api/polity/usagi/inbox", http.StatusUnauthorized},
+		{"owner marks read", &auth.Authorization{ID: "usagi"}, "POST", "/api/polity/usagi/inbox/m1/read", http.StatusNotFound},
+		{"another polity marks read", &auth.Authorization{ID: "kuma"}, "POST", "/api/polity/usagi/inbox/m1/read", http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, err := engine.NewState("admin")
+			is.NoErr(err)
+			var rc routeConfig
+			rc.engine = &engineHolder{st: st}
+			rc.authorize = func(r *http.Request) *auth.Authorization { return tc.auth }
+			router := routes(&server{}, rc)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			is.Equal(w.Code, tc.status) // status
+		})
+	}
+}
+
+func Test_RefuseAll(t *testing.T) {
+	is := is.New(t)
+	st, err := engine.NewState("admin")
+	is.NoErr(err)
+	var rc routeConfig
+	rc.engine = &engineHolder{st: st}
+	rc.authorize = refuseAll
+	router := routes(&server{}, rc)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/polity/usagi/inbox", nil))
+	is.Equal(w.Code, http.StatusUnauthorized) // no one is authorized until users can log in
+}
+
+func Test_EngineHolder(t *testing.T) {
+	is := is.New(t)
+	st, err := engine.NewState("admin")
+	is.NoErr(err)
+	e := &engineHolder{st: st}
+	router := way.NewRouter()
+	router.Handle("GET", "/api/polity/:polity_id/inbox", getInbox(e))
+
+	e.Lock()
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/polity/usagi/inbox", nil))
+		done <- w.Code
+	}()
+	select {
+	case <-done:
+		t.Fatal("handler used the engine without holding the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	e.Unlock()
+	is.Equal(<-done, http.StatusOK) // handler runs once the lock is released
+}
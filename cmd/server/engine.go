@@ -0,0 +1,29 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/mdhender/server/internal/engine"
+	"sync"
+)
+
+// engineHolder guards the game engine. The engine is not safe for
+// concurrent use, so handlers must hold the lock while they use it.
+type engineHolder struct {
+	sync.Mutex
+	st *engine.State
+}
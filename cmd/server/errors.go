@@ -16,9 +16,34 @@
 
 package main
 
-import "errors"
+import (
+	"errors"
+	"github.com/mdhender/server/internal/engine"
+	"github.com/mdhender/server/internal/jsonapi"
+	"net/http"
+)
 
 var ErrBadRequest = errors.New("bad request")
 var ErrDuplicateAddress = errors.New("duplicate address")
 var ErrDuplicateUser = errors.New("duplicate user")
 var ErrNoData = errors.New("no data found")
+
+// engineError writes an error from the engine as a JSON:API error,
+// using the error's code to pick the http status.
+func engineError(w http.ResponseWriter, r *http.Request, err error) {
+	e := engine.AsError(err)
+	status := http.StatusInternalServerError
+	switch e.Code {
+	case engine.ERRBADREQUEST:
+		status = http.StatusBadRequest
+	case engine.ERRFORBIDDEN:
+		status = http.StatusForbidden
+	case engine.ERRNOTFOUND:
+		status = http.StatusNotFound
+	case engine.ERRNOTIMPLEMENTED:
+		status = http.StatusNotImplemented
+	case engine.ERRUNAUTHORIZED:
+		status = http.StatusUnauthorized
+	}
+	jsonapi.Error(w, r, status, e)
+}
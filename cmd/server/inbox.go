@@ -0,0 +1,58 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/mdhender/server/internal/jsonapi"
+	"github.com/mdhender/server/internal/way"
+	"net/http"
+)
+
+// getInbox returns the messages delivered to a polity.
+// If the "unread" query parameter is "true", only unread messages are returned.
+func getInbox(e *engineHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.Lock()
+		defer e.Unlock()
+		id := way.Param(r.Context(), "polity_id")
+		messages, err := e.st.Inbox(id, r.URL.Query().Get("unread") == "true")
+		if err != nil {
+			engineError(w, r, err)
+			return
+		}
+		jsonapi.Ok(w, r, http.StatusOK, messages)
+	}
+}
+
+// postInboxRead marks a message in a polity's inbox as read or unread.
+func postInboxRead(e *engineHolder, read bool) http.HandlerFunc {
+	type response struct {
+		ID   string `json:"id"`
+		Read bool   `json:"read"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.Lock()
+		defer e.Unlock()
+		id := way.Param(r.Context(), "polity_id")
+		messageID := way.Param(r.Context(), "message_id")
+		if err := e.st.MarkMessage(id, messageID, read); err != nil {
+			engineError(w, r, err)
+			return
+		}
+		jsonapi.Ok(w, r, http.StatusOK, response{ID: messageID, Read: read})
+	}
+}
@@ -0,0 +1,56 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/matryer/is"
+	"github.com/mdhender/server/internal/engine"
+	"github.com/mdhender/server/internal/way"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_GetOrderResults(t *testing.T) {
+	is := is.New(t)
+	st, err := engine.NewState("admin")
+	is.NoErr(err)
+	var orders engine.Orders
+	is.NoErr(json.Unmarshal([]byte(`[{"give": {"asset_id": "tosa", "target_id": "tora"}}]`), &orders))
+	orders[0].Stamp("usagi")
+	st.ProcessOrders(orders, false)
+
+	router := way.NewRouter()
+	router.Handle("GET", "/api/polity/:polity_id/orders", getOrderResults(&engineHolder{st: st}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/polity/usagi/orders", nil))
+	is.Equal(w.Code, http.StatusOK)
+
+	var response struct {
+		Data []struct {
+			Kind    string `json:"kind"`
+			Status  string `json:"status"`
+			Pointer string `json:"pointer"`
+		} `json:"data"`
+	}
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &response))
+	is.Equal(len(response.Data), 1)
+	is.Equal(response.Data[0].Kind, "give")
+	is.Equal(response.Data[0].Status, "rejected")
+	is.Equal(response.Data[0].Pointer, "/orders/0/give/target_id") // clients can find the field at fault
+}
@@ -0,0 +1,269 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/mdhender/server/internal/engine"
+	"github.com/mdhender/server/internal/jsonapi"
+	"github.com/mdhender/server/internal/lists"
+	"github.com/mdhender/server/internal/way"
+	"net/http"
+	"net/url"
+)
+
+// getPolity returns a polity as a JSON:API resource.
+// It supports including the polity's colonies and ships.
+func getPolity(e *engineHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.Lock()
+		defer e.Unlock()
+		q, err := jsonapi.ParseQuery(r, "colonies", "ships")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		id := way.Param(r.Context(), "polity_id")
+		polity, err := e.st.PolityView(id)
+		if err != nil {
+			engineError(w, r, err)
+			return
+		}
+		doc := jsonapi.Document{Data: polityResource(polity)}
+		if q.Includes("colonies") {
+			colonies, err := e.st.ColonyViews(id)
+			if err != nil {
+				engineError(w, r, err)
+				return
+			}
+			for _, colony := range colonies {
+				doc.Included = append(doc.Included, colonyResource(colony))
+			}
+		}
+		if q.Includes("ships") {
+			ships, err := e.st.ShipViews(id)
+			if err != nil {
+				engineError(w, r, err)
+				return
+			}
+			for _, ship := range ships {
+				doc.Included = append(doc.Included, shipResource(ship))
+			}
+		}
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
+	}
+}
+
+// getColonies returns a page of the colonies that a polity controls as JSON:API resources.
+// It supports sorting, filtering, and including the polity.
+func getColonies(e *engineHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.Lock()
+		defer e.Unlock()
+		q, err := jsonapi.ParseQuery(r, "polity")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		id := way.Param(r.Context(), "polity_id")
+		colonies, total, err := e.st.ListColonies(id, listOptions(q))
+		if err != nil {
+			engineError(w, r, err)
+			return
+		}
+		list := []jsonapi.Resource{} // create an empty list since we never return nil
+		for _, colony := range colonies {
+			list = append(list, colonyResource(colony))
+		}
+		doc := jsonapi.Document{Data: list}
+		q.Paginate(r, &doc, total)
+		if q.Includes("polity") {
+			polity, err := e.st.PolityView(id)
+			if err != nil {
+				engineError(w, r, err)
+				return
+			}
+			doc.Included = append(doc.Included, polityResource(polity))
+		}
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
+	}
+}
+
+// getShips returns a page of the ships that a polity controls as JSON:API resources.
+// It supports sorting, filtering, and including the polity and the ships' home ports.
+func getShips(e *engineHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.Lock()
+		defer e.Unlock()
+		q, err := jsonapi.ParseQuery(r, "polity", "home_port")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		id := way.Param(r.Context(), "polity_id")
+		ships, total, err := e.st.ListShips(id, listOptions(q))
+		if err != nil {
+			engineError(w, r, err)
+			return
+		}
+		list := []jsonapi.Resource{} // create an empty list since we never return nil
+		for _, ship := range ships {
+			list = append(list, shipResource(ship))
+		}
+		doc := jsonapi.Document{Data: list}
+		q.Paginate(r, &doc, total)
+		if q.Includes("polity") {
+			polity, err := e.st.PolityView(id)
+			if err != nil {
+				engineError(w, r, err)
+				return
+			}
+			doc.Included = append(doc.Included, polityResource(polity))
+		}
+		if q.Includes("home_port") {
+			colonies, err := e.st.ColonyViews(id)
+			if err != nil {
+				engineError(w, r, err)
+				return
+			}
+			// only the colonies that are a home port for one of the ships
+			ports := make(map[string]bool)
+			for _, ship := range ships {
+				ports[ship.HomePortID] = true
+			}
+			for _, colony := range colonies {
+				if ports[colony.ID] {
+					doc.Included = append(doc.Included, colonyResource(colony))
+				}
+			}
+		}
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
+	}
+}
+
+// getSystems returns a page of the systems that a polity knows about as JSON:API resources.
+// It supports sorting and filtering.
+func getSystems(e *engineHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.Lock()
+		defer e.Unlock()
+		q, err := jsonapi.ParseQuery(r)
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		id := way.Param(r.Context(), "polity_id")
+		systems, total, err := e.st.ListSystems(id, listOptions(q))
+		if err != nil {
+			engineError(w, r, err)
+			return
+		}
+		list := []jsonapi.Resource{} // create an empty list since we never return nil
+		for _, system := range systems {
+			list = append(list, systemResource(system))
+		}
+		doc := jsonapi.Document{Data: list}
+		q.Paginate(r, &doc, total)
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
+	}
+}
+
+// listOptions returns the options for an engine list from the query.
+func listOptions(q jsonapi.Query) lists.Options {
+	return lists.Options{Sort: q.Sort, Filter: q.Filter, Offset: q.Offset, Limit: q.Limit}
+}
+
+// polityResource returns the resource for a polity.
+func polityResource(polity engine.PolityView) jsonapi.Resource {
+	self := "/api/polity/" + url.PathEscape(polity.ID)
+	colonies := jsonapi.ToMany("colonies", polity.ColonyIDs...)
+	colonies.Links = jsonapi.Links{"related": self + "/colonies"}
+	ships := jsonapi.ToMany("ships", polity.ShipIDs...)
+	ships.Links = jsonapi.Links{"related": self + "/ships"}
+	rs := jsonapi.Resource{
+		Type: "polities",
+		ID:   polity.ID,
+		Attributes: map[string]interface{}{
+			"name": polity.Name,
+		},
+		Relationships: map[string]jsonapi.Relationship{
+			"colonies": colonies,
+			"ships":    ships,
+		},
+		Links: jsonapi.Links{"self": self},
+	}
+	if polity.HomeColonyID != "" {
+		rs.Relationships["home_colony"] = jsonapi.ToOne("colonies", polity.HomeColonyID)
+	}
+	if polity.ViceroyOf != "" {
+		rs.Relationships["viceroy_of"] = jsonapi.ToOne("polities", polity.ViceroyOf)
+	}
+	return rs
+}
+
+// colonyResource returns the resource for a colony.
+func colonyResource(colony engine.ColonyView) jsonapi.Resource {
+	return jsonapi.Resource{
+		Type: "colonies",
+		ID:   colony.ID,
+		Attributes: map[string]interface{}{
+			"name":       colony.Name,
+			"kind":       colony.Kind,
+			"system_id":  colony.SystemID,
+			"orbit_id":   colony.OrbitID,
+			"planet_id":  colony.PlanetID,
+			"population": colony.Population,
+		},
+		Relationships: map[string]jsonapi.Relationship{
+			"polity": jsonapi.ToOne("polities", colony.PolityID),
+		},
+	}
+}
+
+// shipResource returns the resource for a ship.
+func shipResource(ship engine.ShipView) jsonapi.Resource {
+	rs := jsonapi.Resource{
+		Type: "ships",
+		ID:   ship.ID,
+		Attributes: map[string]interface{}{
+			"name":       ship.Name,
+			"system_id":  ship.SystemID,
+			"orbit_id":   ship.OrbitID,
+			"population": ship.Population,
+		},
+		Relationships: map[string]jsonapi.Relationship{
+			"polity": jsonapi.ToOne("polities", ship.PolityID),
+		},
+	}
+	if ship.HomePortID != "" {
+		rs.Relationships["home_port"] = jsonapi.ToOne("colonies", ship.HomePortID)
+	}
+	return rs
+}
+
+// systemResource returns the resource for a system.
+func systemResource(system engine.SystemView) jsonapi.Resource {
+	return jsonapi.Resource{
+		Type: "systems",
+		ID:   system.ID,
+		Attributes: map[string]interface{}{
+			"name":        system.Name,
+			"coordinates": system.Coords,
+			"colonies":    system.Colonies,
+			"ships":       system.Ships,
+		},
+	}
+}
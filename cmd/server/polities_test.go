@@ -0,0 +1,115 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/matryer/is"
+	"github.com/mdhender/server/internal/engine"
+	"github.com/mdhender/server/internal/jsonapi"
+	"github.com/mdhender/server/internal/way"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_GetPolity(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		path     string
+		status   int
+		included int
+	}{
+		{"polity", "/api/polity/usagi", http.StatusOK, 0},
+		{"include colonies", "/api/polity/usagi?include=colonies", http.StatusOK, 2},
+		{"unsupported include", "/api/polity/usagi?include=systems", http.StatusBadRequest, 0},
+		{"unknown polity", "/api/polity/kuma", http.StatusNotFound, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, err := engine.NewState("admin")
+			is.NoErr(err)
+			router := way.NewRouter()
+			router.Handle("GET", "/api/polity/:polity_id", getPolity(&engineHolder{st: st}))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+			is.Equal(w.Code, tc.status) // status
+			if tc.status != http.StatusOK {
+				return
+			}
+			var doc struct {
+				Data     jsonapi.Resource   `json:"data"`
+				Included []jsonapi.Resource `json:"included"`
+			}
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &doc))
+			is.Equal(doc.Data.Type, "polities")
+			is.Equal(doc.Data.ID, "usagi")
+			is.Equal(doc.Data.Links["self"], "/api/polity/usagi")
+			is.Equal(len(doc.Included), tc.included) // compound document
+			for _, rs := range doc.Included {
+				is.Equal(rs.Type, "colonies")
+			}
+		})
+	}
+}
+
+func Test_GetSystems(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		path   string
+		status int
+		ids    []string
+		total  int
+	}{
+		{"systems", "/api/polity/usagi/systems", http.StatusOK, []string{"mizugame"}, 1},
+		{"owner me", "/api/polity/usagi/systems?filter[owner]=me&sort=-name", http.StatusOK, []string{"mizugame"}, 1},
+		{"page past the end", "/api/polity/usagi/systems?page[offset]=5&page[limit]=2", http.StatusOK, []string{}, 1},
+		{"invalid sort key", "/api/polity/usagi/systems?sort=mass", http.StatusBadRequest, nil, 0},
+		{"invalid filter", "/api/polity/usagi/systems?filter[kind]=open", http.StatusBadRequest, nil, 0},
+		{"invalid page", "/api/polity/usagi/systems?page[limit]=0", http.StatusBadRequest, nil, 0},
+		{"unknown polity", "/api/polity/kuma/systems", http.StatusNotFound, nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, err := engine.NewState("admin")
+			is.NoErr(err)
+			router := way.NewRouter()
+			router.Handle("GET", "/api/polity/:polity_id/systems", getSystems(&engineHolder{st: st}))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+			is.Equal(w.Code, tc.status) // status
+			if tc.status != http.StatusOK {
+				return
+			}
+			var doc struct {
+				Data  []jsonapi.Resource     `json:"data"`
+				Links jsonapi.Links          `json:"links"`
+				Meta  map[string]interface{} `json:"meta"`
+			}
+			is.NoErr(json.Unmarshal(w.Body.Bytes(), &doc))
+			ids := []string{}
+			for _, rs := range doc.Data {
+				is.Equal(rs.Type, "systems")
+				ids = append(ids, rs.ID) // the engine's id for the system
+			}
+			is.Equal(ids, tc.ids)
+			is.Equal(doc.Meta["total"], float64(tc.total))
+			is.True(doc.Links["first"] != "") // paging links
+			is.True(doc.Links["last"] != "")
+		})
+	}
+}
@@ -37,6 +37,13 @@ func routes(s *server, rc routeConfig) http.Handler {
 	router.Handle("GET", "/api/game/:id/system/:system_name", rest.GetGameSystem(rc.services.listing))
 	router.Handle("GET", "/api/game/:id/systems", rest.GetGameSystems(rc.services.listing))
 	router.Handle("GET", "/api/games", rest.GetGames(rc.services.listing))
+	router.Handle("GET", "/api/polity/:polity_id", polityOwner(rc.authorize, getPolity(rc.engine)))
+	router.Handle("GET", "/api/polity/:polity_id/colonies", polityOwner(rc.authorize, getColonies(rc.engine)))
+	router.Handle("GET", "/api/polity/:polity_id/events", polityOwner(rc.authorize, getEvents(rc.engine)))
+	router.Handle("GET", "/api/polity/:polity_id/inbox", polityOwner(rc.authorize, getInbox(rc.engine)))
+	router.Handle("GET", "/api/polity/:polity_id/orders", polityOwner(rc.authorize, getOrderResults(rc.engine)))
+	router.Handle("GET", "/api/polity/:polity_id/ships", polityOwner(rc.authorize, getShips(rc.engine)))
+	router.Handle("GET", "/api/polity/:polity_id/systems", polityOwner(rc.authorize, getSystems(rc.engine)))
 	router.Handle("GET", "/api/user/:id", rest.GetUser(rc.services.listing))
 	router.Handle("GET", "/api/users", rest.GetUsers(rc.services.listing))
 	router.Handle("GET", "/api/version", rest.GetVersion(rc.services.listing))
@@ -46,12 +53,16 @@ func routes(s *server, rc routeConfig) http.Handler {
 	router.Handle("POST", "/api/game/orders", rest.UpdateGameOrders(rc.services.updating))
 	router.Handle("POST", "/api/game/save", rest.UpdateGame(rc.services.updating))
 	router.Handle("POST", "/api/games/create", rest.AddGame(rc.services.adding))
+	router.Handle("POST", "/api/polity/:polity_id/inbox/:message_id/read", polityOwner(rc.authorize, postInboxRead(rc.engine, true)))
+	router.Handle("POST", "/api/polity/:polity_id/inbox/:message_id/unread", polityOwner(rc.authorize, postInboxRead(rc.engine, false)))
 	router.Handle("POST", "/api/users/create", rest.AddUser(rc.services.adding))
 
 	return router
 }
 
 type routeConfig struct {
+	authorize        authorizer
+	engine           *engineHolder
 	gameFileSavePath string
 	services         struct {
 		adding    adding.Service
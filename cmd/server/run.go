@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mdhender/server/internal/engine"
+	"github.com/mdhender/server/internal/obsolete/listing"
 	"github.com/mdhender/server/internal/storage/memory"
 	"log"
 	"net/http"
@@ -38,7 +39,7 @@ func run(cfg *config) error {
 		ds.MockData()
 	}
 	rc.services.adding = ds
-	rc.services.listing = ds
+	rc.services.listing = listing.NewService(ds, ds)
 	rc.services.updating = ds
 
 	var options []func(*server) error
@@ -48,14 +49,15 @@ func run(cfg *config) error {
 	if err != nil {
 		return err
 	}
-	srv.Handler = CorsHandler(routes(srv, rc))
-
 	admin := cfg.Setup.DefaultAdmin
 	st, err := engine.NewState(admin)
 	if err != nil {
 		return fmt.Errorf("engine: %w", err)
 	}
 	log.Printf("[run] state created with default admin of %q\n", admin)
+	rc.engine = &engineHolder{st: st}
+	rc.authorize = refuseAll
+	srv.Handler = CorsHandler(routes(srv, rc))
 	fmt.Printf("admins are %v\n", st.Admins())
 	fmt.Printf("------------------------------------------------------------\n")
 	fmt.Println(st.String())
@@ -45,7 +45,7 @@ func (st *State) Accept(issuedByID, assetID string) error {
 	if colony := st.Colony(assetID); colony != nil {
 		// asset must be controlled by a viceroy of the polity issuing the order
 		if !colony.polity.isViceroyOf(issuedBy) {
-			return fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN)
+			return fieldError("asset_id", fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN))
 		}
 		from, to := colony.polity, issuedBy
 		return st.transferColony(colony, from, to)
@@ -54,11 +54,11 @@ func (st *State) Accept(issuedByID, assetID string) error {
 	if ship := st.Ship(assetID); ship != nil {
 		// asset must be controlled by a viceroy of the polity issuing the order
 		if !ship.polity.isViceroyOf(issuedBy) {
-			return fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN)
+			return fieldError("asset_id", fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN))
 		}
 		from, to := ship.polity, issuedBy
 		return st.transferShip(ship, from, to)
 	}
 
-	return fmt.Errorf("invalid assetID %q: %w", assetID, ERRBADREQUEST)
+	return fieldError("asset_id", fmt.Errorf("invalid assetID %q: %w", assetID, ERRBADREQUEST))
 }
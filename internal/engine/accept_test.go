@@ -19,12 +19,49 @@
 package engine
 
 import (
+	"errors"
 	"github.com/matryer/is"
 	"testing"
 )
 
 func Test_Accept(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		issuedBy string
+		asset    string
+		err      error
+		accepted bool
+	}{
+		{"colony", "usagi", "tosa", nil, true},
+		{"ship", "usagi", "S1", nil, true},
+		{"not the ruler", "tora", "tosa", ERRFORBIDDEN, false},
+		{"own asset", "usagi", "sanuki", ERRFORBIDDEN, false},
+		{"unknown asset", "usagi", "nowhere", ERRBADREQUEST, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			kuma.diplomacy["usagi"] = ALLY
+			mkrival(st, "tora")
+			is.NoErr(st.AppointViceroy("usagi", "kuma"))
+			tosa := st.Colony("tosa")
+			s := mktestship(st, usagi, tosa, "S1")
+			is.NoErr(st.transferColony(tosa, usagi, kuma))
+			is.NoErr(st.transferShip(s, usagi, kuma))
+			err := st.Accept(tc.issuedBy, tc.asset)
+			is.True(errors.Is(err, tc.err)) // error
+			switch tc.asset {
+			case "tosa":
+				is.Equal(tosa.polity == usagi, tc.accepted)
+			case "S1":
+				is.Equal(s.polity == usagi, tc.accepted)
+			}
+			is.True(kuma.isViceroyOf(usagi)) // the viceroy keeps the appointment
+			is.Equal(len(st.checkOwnership()), 0)
+		})
+	}
 	is := is.New(t)
-
-	is.NoErr(ERRNOTIMPLEMENTED)
+	st, _ := Make()
+	is.Equal(st.Accept("nobody", "tosa"), ERRBUG)
 }
@@ -0,0 +1,308 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+)
+
+// massPerConstructor is the number of mass units that one construction
+// worker can assemble in a turn. Disassembly takes half the effort and
+// scrapping takes a third.
+const massPerConstructor = 100
+
+// massPerPower is the number of mass units that one unit of power
+// can assemble in a turn.
+const massPerPower = 50
+
+// scrapRecovery is the fraction of an item's materials that is
+// recovered when it is scrapped. The rest is lost as waste.
+const scrapRecovery = 0.70
+
+// assemblyCost returns the construction workers and power needed to
+// assemble the units. Both are rounded up.
+func assemblyCost(u Unit) (workers, power int) {
+	mass := u.Mass()
+	return int(math.Ceil(mass / massPerConstructor)), int(math.Ceil(mass / massPerPower))
+}
+
+// assemblable returns true if the kind of unit is assembled from
+// parts in storage. Raw resources and consumer goods are never assembled.
+func assemblable(kind UnitKind) bool {
+	switch kind {
+	case ANTIMISSILE, ENERGYWEAPON, ENGINE, FACTORY, FARM, LIGHTSTRUCTURAL, MINE, MISSILE, POWER, STRUCTURAL, TRANSPORT:
+		return true
+	}
+	return false
+}
+
+// constructors returns the number of construction workers at the depot
+// that have not been given work this turn.
+func (d depot) constructors() int {
+	return d.population.construction - *d.constructionUsed
+}
+
+// power returns the power that is still available to the depot this turn.
+// Power plants are not charged until production, so this is the output
+// of the assembled plants less anything already drawn from the batteries.
+func (d depot) power() int {
+	var output int
+	for _, u := range *d.units {
+		if u.Kind == POWER && u.Assembled {
+			output += u.Produce().Quantity
+		}
+	}
+	if d.colony != nil {
+		output = int(float64(output) * d.colony.productionModifier())
+	}
+	return output - d.batteries.used
+}
+
+// assemblyLimit returns the number of units, up to quantity, that can
+// be assembled with the labor and power remaining at the depot.
+func (d depot) assemblyLimit(u Unit, quantity int) int {
+	one := u
+	one.Quantity = 1
+	workers, power := assemblyCost(one)
+	if workers > 0 {
+		quantity = minInt(quantity, d.constructors()*massPerConstructor/int(math.Ceil(one.Mass())))
+	}
+	if power > 0 {
+		quantity = minInt(quantity, d.power()*massPerPower/int(math.Ceil(one.Mass())))
+	}
+	if quantity < 0 {
+		return 0
+	}
+	return quantity
+}
+
+// useAssembly charges the depot for the labor and power used to
+// assemble the units.
+func (d depot) useAssembly(u Unit) {
+	workers, power := assemblyCost(u)
+	*d.constructionUsed += workers
+	d.batteries.used += power
+}
+
+// storageCapacity returns the number of volume units that the depot can store.
+// Open colonies on a planet's surface have no limit. Ships store only what
+// fits in their cargo hold. Everywhere else, space is enclosed by assembled
+// structural and light structural units.
+func (d depot) storageCapacity() (capacity int, limited bool) {
+	if d.ship != nil {
+		return d.ship.cargoHold, true
+	} else if d.colony.kind == OPEN && d.colony.planet != nil {
+		return 0, false
+	}
+	return hullSpace(*d.units), true
+}
+
+// storageUsed returns the number of volume units used by unassembled
+// units and raw resources in storage. Passengers on a ship ride in the
+// hold; the crew does not.
+func (d depot) storageUsed() float64 {
+	var used float64
+	if d.ship != nil {
+		used += Unit{Kind: POPULATION, Quantity: d.passengerCount()}.Volume()
+	}
+	for _, u := range *d.units {
+		if !u.Assembled {
+			used += u.Volume()
+		}
+	}
+	used += Unit{Kind: FOOD, Quantity: d.storage.food}.Volume()
+	used += Unit{Kind: FUEL, Quantity: d.storage.fuel}.Volume()
+	used += Unit{Kind: GOLD, Quantity: d.storage.gold}.Volume()
+	used += Unit{Kind: METAL, Quantity: d.storage.metal}.Volume()
+	used += Unit{Kind: NONMETAL, Quantity: d.storage.nonmetal}.Volume()
+	return used
+}
+
+// storageLimit returns the number of items, up to quantity, that fit
+// into the depot when each item needs perItem volume units of storage
+// and takes away lost volume units of capacity.
+func (d depot) storageLimit(perItem, lost float64, quantity int) int {
+	capacity, limited := d.storageCapacity()
+	if !limited || perItem+lost <= 0 {
+		return quantity
+	}
+	free := float64(capacity) - d.storageUsed()
+	if free <= 0 {
+		return 0
+	}
+	return minInt(quantity, int(free/(perItem+lost)))
+}
+
+// AssembleItem turns unassembled units in storage into working units.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. Quantity must be greater than zero.
+// 3. Item must be something that is assembled. Factories and mines are
+// assembled into groups with their own orders.
+// 4. Tech level must not exceed the polity's tech level for the item.
+// 5. One construction worker is needed per 100 mass units (or portion).
+// 6. One unit of power is needed per 50 mass units (or portion).
+// 7. On a ship, working units must fit into the hull that isn't used by
+// other parts or the cargo hold.
+// 8. Quantity may exceed the number in storage or the labor, power, and
+// hull available; the overage is ignored and the order is partially
+// executed. The order is rejected if nothing can be assembled.
+func (st *State) AssembleItem(issuedByID, sourceID string, quantity int, item string, techLevel int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.AssembleItem: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	kind, ok := unitKindFromString(item)
+	if !ok {
+		return fieldError("item", fmt.Errorf("invalid item %q: %w", item, ERRBADREQUEST))
+	} else if !assemblable(kind) {
+		return fieldError("item", fmt.Errorf("item %q can not be assembled: %w", item, ERRBADREQUEST))
+	} else if kind == FACTORY || kind == MINE {
+		return fieldError("item", fmt.Errorf("item %q must be assembled into a group: %w", item, ERRBADREQUEST))
+	}
+
+	u := Unit{Kind: kind, TechLevel: techLevel}
+	if err := d.polity().canAssemble(u); err != nil {
+		return fieldError("tech_level", err)
+	}
+	u.Quantity = minInt(quantity, d.countUnit(kind, techLevel, false))
+	if u.Quantity == 0 {
+		return fieldError("item", fmt.Errorf("no %s units in storage: %w", u, ERRBADREQUEST))
+	}
+	u.Quantity = d.assemblyLimit(u, u.Quantity)
+	if d.ship != nil && kind != STRUCTURAL && kind != LIGHTSTRUCTURAL {
+		one := Unit{Kind: kind, TechLevel: techLevel, Quantity: 1, Assembled: true}
+		u.Quantity = minInt(u.Quantity, int(float64(d.ship.hull())/one.Volume()))
+	}
+	if u.Quantity == 0 {
+		return fmt.Errorf("not enough labor, power, or hull to assemble %s: %w", u, ERRBADREQUEST)
+	}
+	d.useAssembly(u)
+	d.removeUnit(kind, techLevel, false, u.Quantity)
+	if u.Quantity < quantity {
+		d.polity().logf("%s: assembled %s of %s %s (not enough units, labor, power, or hull)", sourceID, utils.Commas(u.Quantity), utils.Commas(quantity), u)
+	} else {
+		d.polity().logf("%s: assembled %s %s", sourceID, utils.Commas(u.Quantity), u)
+	}
+	u.Assembled = true
+	d.addUnit(u)
+	if u.Quantity < quantity {
+		return fmt.Errorf("assembled %d of %d: %w", u.Quantity, quantity, ERRPARTIAL)
+	}
+	return nil
+}
+
+// Disassemble returns working units to storage as unassembled units.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. Quantity must be greater than zero.
+// 3. Factories are taken from the group identified by GroupID.
+// 4. One construction worker is needed per 200 mass units (or portion).
+// 5. The unassembled units must fit into storage. Disassembling structural
+// units reduces the space available. On a ship, that space must come from
+// hull that isn't used by parts or the cargo hold.
+// 6. Quantity may exceed the number of working units or the labor and
+// storage available; the overage is ignored and the order is partially
+// executed. The order is rejected if nothing can be disassembled.
+func (st *State) Disassemble(issuedByID, sourceID, item string, techLevel int, groupID string, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Disassemble: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	kind, ok := unitKindFromString(item)
+	if !ok {
+		return fieldError("item", fmt.Errorf("invalid item %q: %w", item, ERRBADREQUEST))
+	} else if !assemblable(kind) {
+		return fieldError("item", fmt.Errorf("item %q can not be disassembled: %w", item, ERRBADREQUEST))
+	} else if kind == MINE {
+		return fieldError("item", fmt.Errorf("disassembling mines: %w", ERRNOTIMPLEMENTED))
+	}
+
+	u := Unit{Kind: kind, TechLevel: techLevel}
+	var group *FactoryGroup
+	if kind == FACTORY {
+		if group = d.factoryGroup(groupID); group == nil {
+			return fieldError("group_id", fmt.Errorf("invalid group %q: %w", groupID, ERRBADREQUEST))
+		}
+		u.TechLevel = group.units.TechLevel
+		u.Quantity = minInt(quantity, group.units.Quantity)
+	} else {
+		u.Quantity = minInt(quantity, d.countUnit(kind, techLevel, true))
+	}
+	if u.Quantity == 0 {
+		return fieldError("item", fmt.Errorf("no working %s units: %w", u, ERRBADREQUEST))
+	}
+
+	// limit by labor, then by storage
+	one := Unit{Kind: u.Kind, TechLevel: u.TechLevel, Quantity: 1}
+	mass := int(math.Ceil(one.Mass()))
+	if mass > 0 {
+		u.Quantity = minInt(u.Quantity, d.constructors()*2*massPerConstructor/mass)
+	}
+	var lost float64
+	switch kind {
+	case STRUCTURAL:
+		lost = float64(u.TechLevel * u.TechLevel)
+	case LIGHTSTRUCTURAL:
+		lost = float64(u.TechLevel*u.TechLevel) / 5
+	}
+	if d.ship != nil && lost > 0 {
+		// a ship's hull must still enclose its parts and cargo hold
+		u.Quantity = minInt(u.Quantity, int(float64(d.ship.hull())/lost))
+		lost = 0
+	}
+	u.Quantity = d.storageLimit(one.Volume(), lost, u.Quantity)
+	if u.Quantity <= 0 {
+		return fmt.Errorf("not enough labor or storage to disassemble %s: %w", u, ERRBADREQUEST)
+	} else if u.Quantity < quantity {
+		d.polity().logf("%s: disassembled %s of %s %s (not enough units, labor, or storage)", sourceID, utils.Commas(u.Quantity), utils.Commas(quantity), u)
+	} else {
+		d.polity().logf("%s: disassembled %s %s", sourceID, utils.Commas(u.Quantity), u)
+	}
+
+	*d.constructionUsed += int(math.Ceil(u.Mass() / (2 * massPerConstructor)))
+	if group != nil {
+		group.units.Quantity -= u.Quantity
+		d.removeIdleFactoryGroup(group)
+	} else {
+		d.removeUnit(kind, u.TechLevel, true, u.Quantity)
+	}
+	d.addUnit(u)
+	if u.Quantity < quantity {
+		return fmt.Errorf("disassembled %d of %d: %w", u.Quantity, quantity, ERRPARTIAL)
+	}
+	return nil
+}
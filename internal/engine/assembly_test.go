@@ -0,0 +1,161 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_AssembleItem(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		constructors int
+		power        int
+		stored       int // unassembled ENGINE-1 in storage
+		item         string
+		techLevel    int
+		quantity     int
+		err          error
+		assembled    int
+	}{
+		{"assembles all", 10, 10, 10, "ENGINE", 1, 10, nil, 10},
+		{"limited by storage", 10, 10, 10, "ENGINE", 1, 20, ERRPARTIAL, 10},
+		{"limited by labor", 1, 100, 50, "ENGINE", 1, 50, ERRPARTIAL, 10},
+		{"limited by power", 10, 1, 50, "ENGINE", 1, 50, ERRPARTIAL, 5},
+		{"no labor", 0, 10, 10, "ENGINE", 1, 10, ERRBADREQUEST, 0},
+		{"zero quantity", 10, 10, 10, "ENGINE", 1, 0, ERRBADREQUEST, 0},
+		{"unknown item", 10, 10, 10, "WIDGET", 1, 10, ERRBADREQUEST, 0},
+		{"not assembled", 10, 10, 10, "GOODS", 1, 10, ERRBADREQUEST, 0},
+		{"factories are assembled into groups", 10, 10, 10, "FACTORY", 1, 10, ERRBADREQUEST, 0},
+		{"tech level too high", 10, 10, 10, "ENGINE", 2, 10, ERRFORBIDDEN, 0},
+		{"nothing in storage", 10, 10, 0, "ENGINE", 1, 10, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			mktestcrew(c, tc.constructors, tc.power, Unit{Kind: ENGINE, TechLevel: 1, Quantity: tc.stored})
+			err := st.AssembleItem("usagi", "tosa", tc.quantity, tc.item, tc.techLevel)
+			is.True(errors.Is(err, tc.err)) // error
+			d := c.depot()
+			is.Equal(d.countUnit(ENGINE, 1, true), tc.assembled)            // working units
+			is.Equal(d.countUnit(ENGINE, 1, false), tc.stored-tc.assembled) // left in storage
+		})
+	}
+}
+
+func Test_AssembleItemAuthority(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	mktestcrew(st.Colony("tosa"), 10, 10, Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10})
+	mkrival(st, "kuma")
+	is.True(errors.Is(st.AssembleItem("kuma", "tosa", 10, "ENGINE", 1), ERRFORBIDDEN)) // rival may not give orders to tosa
+	is.Equal(st.AssembleItem("nobody", "tosa", 10, "ENGINE", 1), ERRBUG)               // issuer must exist
+}
+
+func Test_Disassemble(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		constructors int
+		structural   int // assembled SU-1 enclosing storage
+		working      int // assembled ENGINE-1
+		item         string
+		quantity     int
+		err          error
+		stored       int // unassembled units of the item afterwards
+	}{
+		{"disassembles all", 10, 100, 10, "ENGINE", 10, nil, 10},
+		{"limited by storage", 10, 100, 30, "ENGINE", 30, ERRPARTIAL, 20},
+		{"limited by labor", 1, 1000, 30, "ENGINE", 30, ERRPARTIAL, 20},
+		{"structure shrinks storage", 10, 100, 0, "SU", 100, ERRPARTIAL, 66},
+		{"no labor", 0, 100, 10, "ENGINE", 10, ERRBADREQUEST, 0},
+		{"zero quantity", 10, 100, 10, "ENGINE", 0, ERRBADREQUEST, 0},
+		{"not assembled", 10, 100, 10, "GOODS", 10, ERRBADREQUEST, 0},
+		{"mines", 10, 100, 10, "MINE", 10, ERRNOTIMPLEMENTED, 0},
+		{"nothing working", 10, 100, 0, "ENGINE", 10, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			mktestcrew(c, tc.constructors, 0,
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: tc.structural, Assembled: true},
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: tc.working, Assembled: true})
+			err := st.Disassemble("usagi", "tosa", tc.item, 1, "", tc.quantity)
+			is.True(errors.Is(err, tc.err)) // error
+			if kind, ok := unitKindFromString(tc.item); ok {
+				is.Equal(c.depot().countUnit(kind, 1, false), tc.stored) // unassembled units
+			}
+		})
+	}
+}
+
+func Test_DisassembleFactoryGroup(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	c := st.Colony("tosa")
+	mktestcrew(c, 100, 0, Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 1000, Assembled: true})
+	g := &FactoryGroup{id: "F1", units: Unit{Kind: FACTORY, TechLevel: 1, Quantity: 10, Assembled: true}}
+	c.factories = append(c.factories, g)
+
+	is.True(errors.Is(st.Disassemble("usagi", "tosa", "FACTORY", 1, "F2", 5), ERRBADREQUEST)) // unknown group
+	is.NoErr(st.Disassemble("usagi", "tosa", "FACTORY", 1, "F1", 5))
+	is.Equal(g.units.Quantity, 5)                       // factories left in the group
+	is.Equal(c.depot().countUnit(FACTORY, 1, false), 5) // factories returned to storage
+	is.NoErr(st.Disassemble("usagi", "tosa", "FACTORY", 1, "F1", 5))
+	is.Equal(c.depot().factoryGroup("F1"), (*FactoryGroup)(nil)) // empty group is removed
+}
+
+func Test_Scrap(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		constructors int
+		stored       int // unassembled ENGINE-1 in storage
+		item         string
+		quantity     int
+		err          error
+		scrapped     int
+	}{
+		{"scraps all", 10, 10, "ENGINE", 10, nil, 10},
+		{"limited by storage", 10, 10, "ENGINE", 20, ERRPARTIAL, 10},
+		{"limited by labor", 1, 50, "ENGINE", 50, ERRPARTIAL, 30},
+		{"no labor", 0, 10, "ENGINE", 10, ERRBADREQUEST, 0},
+		{"zero quantity", 10, 10, "ENGINE", 0, nil, 0},
+		{"negative quantity", 10, 10, "ENGINE", -1, ERRBADREQUEST, 0},
+		{"unknown item", 10, 10, "WIDGET", 10, ERRBADREQUEST, 0},
+		{"raw resource", 10, 10, "FOOD", 10, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			mktestcrew(c, tc.constructors, 0,
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 1000, Assembled: true},
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: tc.stored})
+			err := st.Scrap("usagi", "tosa", tc.item, 1, tc.quantity)
+			is.True(errors.Is(err, tc.err)) // error
+			d := c.depot()
+			is.Equal(d.countUnit(ENGINE, 1, false), tc.stored-tc.scrapped)          // left in storage
+			is.Equal(d.storage.metal, int(scrapRecovery*7*float64(tc.scrapped)))    // metal recovered
+			is.Equal(d.storage.nonmetal, int(scrapRecovery*3*float64(tc.scrapped))) // non-metal recovered
+		})
+	}
+}
@@ -0,0 +1,401 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+)
+
+// transports hold the mass that a depot's transport units can move
+// this turn. The capacity expires at the end of the turn.
+type transports struct {
+	capacity int
+	used     int
+}
+
+// available returns the mass that the transports can still move this turn.
+func (t *transports) available() int {
+	return t.capacity - t.used
+}
+
+// cargo is a quantity of something that can be moved between depots:
+// raw resources, unassembled units, or people.
+type cargo struct {
+	unit       Unit           // the item being moved
+	population PopulationKind // the kind of people when unit.Kind is POPULATION
+}
+
+// String implements the stringer interface
+func (c cargo) String() string {
+	if c.unit.Kind == POPULATION {
+		return c.population.String()
+	}
+	return c.unit.String()
+}
+
+// each returns the mass and volume of one item of cargo. Population is
+// scaled by one hundred, so the rates are taken from one hundred items.
+func (c cargo) each() (mass, volume float64) {
+	u := Unit{Kind: c.unit.Kind, TechLevel: c.unit.TechLevel, Quantity: 100}
+	return u.Mass() / 100, u.Volume() / 100
+}
+
+// itemCargo returns the cargo for an item and tech level.
+// Raw resources, unassembled units, and people are cargo. People are
+// named by their kind of population and have no tech level.
+func itemCargo(item string, techLevel, quantity int) (cargo, error) {
+	if quantity <= 0 {
+		return cargo{}, fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	} else if kind, ok := populationKindFromString(item); ok {
+		return cargo{unit: Unit{Kind: POPULATION, Quantity: quantity}, population: kind}, nil
+	}
+	kind, ok := unitKindFromString(item)
+	if !ok || kind == NOOP || kind == POPULATION || kind == MINEGROUP {
+		return cargo{}, fieldError("item", fmt.Errorf("invalid item %q: %w", item, ERRBADREQUEST))
+	}
+	switch kind {
+	case FOOD, FUEL, GOLD, METAL, NONMETAL:
+		techLevel = 1
+	}
+	return cargo{unit: Unit{Kind: kind, TechLevel: techLevel, Quantity: quantity}}, nil
+}
+
+// stored returns the amount of the cargo that the depot has.
+func (d depot) stored(c cargo) int {
+	switch c.unit.Kind {
+	case FOOD:
+		return d.storage.food
+	case FUEL:
+		return d.storage.fuel
+	case GOLD:
+		return d.storage.gold
+	case METAL:
+		return d.storage.metal
+	case NONMETAL:
+		return d.storage.nonmetal
+	case POPULATION:
+		return d.riding(c.population)
+	}
+	return d.countUnit(c.unit.Kind, c.unit.TechLevel, false)
+}
+
+// riding returns the people of the given kind that can be moved out of
+// the depot. A ship's crew stays aboard, so only its passengers can be
+// moved. Passengers that left the ship some other way, such as soldiers
+// lost in ground combat, are no longer counted.
+func (d depot) riding(kind PopulationKind) int {
+	if d.passengers == nil {
+		return *d.population.count(kind)
+	}
+	return minInt(*d.passengers.count(kind), *d.population.count(kind))
+}
+
+// passengerCount returns the number of people riding in the depot's cargo hold.
+func (d depot) passengerCount() (n int) {
+	if d.passengers == nil {
+		return 0
+	}
+	for kind := OTHERS; kind <= UNSKILLED; kind++ {
+		n += d.riding(kind)
+	}
+	return n
+}
+
+// unload removes the cargo from the depot.
+func (d depot) unload(c cargo) {
+	switch c.unit.Kind {
+	case FOOD:
+		d.storage.food -= c.unit.Quantity
+	case FUEL:
+		d.storage.fuel -= c.unit.Quantity
+	case GOLD:
+		d.storage.gold -= c.unit.Quantity
+	case METAL:
+		d.storage.metal -= c.unit.Quantity
+	case NONMETAL:
+		d.storage.nonmetal -= c.unit.Quantity
+	case POPULATION:
+		*d.population.count(c.population) -= c.unit.Quantity
+		d.population.total -= c.unit.Quantity
+		if d.passengers != nil {
+			*d.passengers.count(c.population) -= c.unit.Quantity
+			d.passengers.total -= c.unit.Quantity
+		}
+	default:
+		d.removeUnit(c.unit.Kind, c.unit.TechLevel, false, c.unit.Quantity)
+	}
+}
+
+// load adds the cargo to the depot.
+func (d depot) load(c cargo) {
+	if c.unit.Kind == POPULATION {
+		*d.population.count(c.population) += c.unit.Quantity
+		d.population.total += c.unit.Quantity
+		if d.passengers != nil {
+			*d.passengers.count(c.population) += c.unit.Quantity
+			d.passengers.total += c.unit.Quantity
+		}
+		return
+	}
+	d.store(c.unit)
+}
+
+// orbit returns the orbit that the depot is in. Ships at the jump
+// point or in deep space are not in an orbit.
+func (d depot) orbit() *Orbit {
+	if d.colony != nil {
+		return d.colony.orbitOf()
+	}
+	return d.ship.orbit
+}
+
+// moveCargo moves cargo between two depots in the same orbit using the
+// transports of the carrier, which must be one of the two. It returns the
+// quantity moved, which is limited by the cargo available, the storage
+// at the destination, and the mass that the carrier's transports can move.
+func moveCargo(from, to, carrier depot, c cargo) (int, error) {
+	if from.id == to.id {
+		return 0, fmt.Errorf("can not move cargo to itself: %w", ERRBADREQUEST)
+	} else if from.orbit() == nil || from.orbit() != to.orbit() {
+		return 0, fmt.Errorf("%q and %q are not in the same orbit: %w", from.id, to.id, ERRBADREQUEST)
+	}
+	mass, volume := c.each()
+	quantity := minInt(c.unit.Quantity, from.stored(c))
+	if c.unit.Kind != POPULATION || to.ship != nil {
+		quantity = to.storageLimit(volume, 0, quantity)
+	}
+	if mass > 0 {
+		quantity = minInt(quantity, int(float64(carrier.transports.available())/mass))
+	}
+	if quantity <= 0 {
+		return 0, nil
+	}
+	moved := c
+	moved.unit.Quantity = quantity
+	from.unload(moved)
+	to.load(moved)
+	carrier.transports.used += int(math.Ceil(mass * float64(quantity)))
+	return quantity, nil
+}
+
+// reportCargo adds the results of a cargo order to the polity's journal.
+// It returns ERRPARTIAL if less than the full quantity was moved, or
+// ERRBADREQUEST if nothing was moved.
+func reportCargo(p *Polity, verb, fromID, toID string, c cargo, moved int) error {
+	if moved == 0 && c.unit.Quantity > 0 {
+		return fmt.Errorf("%s none of %s to %s (not enough cargo, storage, or transport): %w", verb, c, toID, ERRBADREQUEST)
+	} else if moved < c.unit.Quantity {
+		p.logf("%s: %s %s of %s %s to %s (not enough cargo, storage, or transport)", fromID, verb, utils.Commas(moved), utils.Commas(c.unit.Quantity), c, toID)
+		return fmt.Errorf("%s %d of %d: %w", verb, moved, c.unit.Quantity, ERRPARTIAL)
+	}
+	p.logf("%s: %s %s %s to %s", fromID, verb, utils.Commas(moved), c, toID)
+	return nil
+}
+
+// cargoDepots returns the depots for a cargo order. Both must accept
+// orders from the polity issuing the order. Errors name the order's
+// field for the depot that failed.
+func (st *State) cargoDepots(issuedBy *Polity, sourceField, sourceID, toField, toID string) (from, to depot, err error) {
+	if from, err = st.findDepot(issuedBy, sourceID); err != nil {
+		return depot{}, depot{}, fieldError(sourceField, err)
+	} else if to, err = st.findDepot(issuedBy, toID); err != nil {
+		return depot{}, depot{}, fieldError(toField, err)
+	}
+	return from, to, nil
+}
+
+// UnloadCargo moves cargo from a ship to a colony.
+//
+// 1. Colony identified by ColonyID and the ship identified by ShipID must
+// accept orders from the polity issuing the order.
+// 2. The ship must be in the colony's orbit.
+// 3. The colony's transports carry the cargo.
+// 4. Item may name a kind of population to unload passengers. The ship's
+// crew stays aboard.
+// 5. Quantity may exceed the cargo on the ship, the colony's storage, or the
+// capacity of the transports; the overage is ignored and reported.
+func (st *State) UnloadCargo(issuedByID, colonyID, shipID, item string, techLevel, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.UnloadCargo: issuedByID is invalid\n")
+		return ERRBUG
+	} else if colony := st.Colony(colonyID); colony == nil {
+		return fieldError("colony_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	} else if ship := st.Ship(shipID); ship == nil {
+		return fieldError("ship_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
+	}
+	to, from, err := st.cargoDepots(issuedBy, "colony_id", colonyID, "ship_id", shipID)
+	if err != nil {
+		return err
+	}
+	c, err := itemCargo(item, techLevel, quantity)
+	if err != nil {
+		return err
+	}
+	moved, err := moveCargo(from, to, to, c)
+	if err != nil {
+		return fieldError("ship_id", err)
+	}
+	return reportCargo(to.polity(), "unloaded", shipID, colonyID, c, moved)
+}
+
+// Transfer moves cargo from a ship or colony to another ship or colony.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. The recipient identified by ToID must accept orders from the polity issuing
+// the order or be controlled by a polity that stands with it as a FRIEND.
+// 3. Both must be in the same orbit.
+// 4. The source's transports carry the cargo.
+// 5. Item may name a kind of population to move people. A ship's crew
+// stays aboard.
+// 6. Quantity may exceed the cargo at the source, the recipient's storage,
+// or the capacity of the transports; the overage is ignored and reported.
+func (st *State) Transfer(issuedByID, sourceID, toID, item string, techLevel, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Transfer: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	from, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	to, err := st.findTarget(toID)
+	if err != nil {
+		return fieldError("ship_id", err)
+	} else if !issuedBy.permits(to.polity(), FRIEND) {
+		return fieldError("ship_id", fmt.Errorf("recipient refuses cargo: %w", ERRFORBIDDEN))
+	}
+	c, err := itemCargo(item, techLevel, quantity)
+	if err != nil {
+		return err
+	}
+	moved, err := moveCargo(from, to, from, c)
+	if err != nil {
+		return fieldError("ship_id", err)
+	}
+	if to.polity() != from.polity() && moved != 0 {
+		to.polity().logf("%s: received %s %s from %s", toID, utils.Commas(moved), c, sourceID)
+	}
+	return reportCargo(from.polity(), "transferred", sourceID, toID, c, moved)
+}
+
+// PickUpItem moves cargo from a ship or colony to a ship.
+//
+// 1. Source identified by SourceID and the ship identified by ToID must
+// accept orders from the polity issuing the order.
+// 2. Both must be in the same orbit.
+// 3. The ship's transports carry the cargo.
+// 4. Quantity may exceed the cargo at the source, the ship's cargo hold,
+// or the capacity of the transports; the overage is ignored and reported.
+func (st *State) PickUpItem(issuedByID, sourceID, toID, item string, techLevel, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.PickUpItem: issuedByID is invalid\n")
+		return ERRBUG
+	} else if ship := st.Ship(toID); ship == nil {
+		return fieldError("to_id", fmt.Errorf("invalid ship %q: %w", toID, ERRBADREQUEST))
+	}
+	from, to, err := st.cargoDepots(issuedBy, "source_id", sourceID, "to_id", toID)
+	if err != nil {
+		return err
+	}
+	c, err := itemCargo(item, techLevel, quantity)
+	if err != nil {
+		return err
+	}
+	moved, err := moveCargo(from, to, to, c)
+	if err != nil {
+		return fieldError("to_id", err)
+	}
+	return reportCargo(to.polity(), "picked up", sourceID, toID, c, moved)
+}
+
+// PickUpPopulation moves people from a ship or colony to a ship.
+//
+// 1. Source identified by SourceID and the ship identified by ToID must
+// accept orders from the polity issuing the order.
+// 2. Both must be in the same orbit.
+// 3. PopulationType must be a kind of population. Polities have a single
+// race, so RaceID is not checked.
+// 4. Passengers ride in the ship's cargo hold and are carried by the
+// ship's transports.
+// 5. Quantity may exceed the people at the source, the ship's cargo hold,
+// or the capacity of the transports; the overage is ignored and reported.
+func (st *State) PickUpPopulation(issuedByID, sourceID, toID, populationType string, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.PickUpPopulation: issuedByID is invalid\n")
+		return ERRBUG
+	} else if ship := st.Ship(toID); ship == nil {
+		return fieldError("to_id", fmt.Errorf("invalid ship %q: %w", toID, ERRBADREQUEST))
+	}
+	from, to, err := st.cargoDepots(issuedBy, "source_id", sourceID, "to_id", toID)
+	if err != nil {
+		return err
+	}
+	kind, ok := populationKindFromString(populationType)
+	if !ok {
+		return fieldError("population_type", fmt.Errorf("invalid population type %q: %w", populationType, ERRBADREQUEST))
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	c := cargo{unit: Unit{Kind: POPULATION, Quantity: quantity}, population: kind}
+	moved, err := moveCargo(from, to, to, c)
+	if err != nil {
+		return fieldError("to_id", err)
+	}
+	return reportCargo(to.polity(), "picked up", sourceID, toID, c, moved)
+}
+
+// LoadCargo moves cargo from a colony to a ship.
+//
+// 1. Colony identified by ColonyID and the ship identified by ToID must
+// accept orders from the polity issuing the order.
+// 2. The ship must be in the colony's orbit.
+// 3. The colony's transports carry the cargo.
+// 4. Quantity may exceed the cargo at the colony, the ship's cargo hold, or
+// the capacity of the transports; the overage is ignored and reported.
+func (st *State) LoadCargo(issuedByID, colonyID, toID, item string, techLevel, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.LoadCargo: issuedByID is invalid\n")
+		return ERRBUG
+	} else if colony := st.Colony(colonyID); colony == nil {
+		return fieldError("colony_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	} else if ship := st.Ship(toID); ship == nil {
+		return fieldError("to_id", fmt.Errorf("invalid ship %q: %w", toID, ERRBADREQUEST))
+	}
+	from, to, err := st.cargoDepots(issuedBy, "colony_id", colonyID, "to_id", toID)
+	if err != nil {
+		return err
+	}
+	c, err := itemCargo(item, techLevel, quantity)
+	if err != nil {
+		return err
+	}
+	moved, err := moveCargo(from, to, from, c)
+	if err != nil {
+		return fieldError("to_id", err)
+	}
+	return reportCargo(from.polity(), "loaded", colonyID, toID, c, moved)
+}
@@ -0,0 +1,182 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_LoadCargo(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		stock     int // metal at tosa
+		transport int // mass tosa's transports can move
+		item      string
+		quantity  int
+		err       error
+		loaded    int
+	}{
+		{"loads", 100, 1_000, "METAL", 10, nil, 10},
+		{"limited by hold", 100, 1_000, "METAL", 30, ERRPARTIAL, 20},
+		{"limited by transport", 100, 5, "METAL", 10, ERRPARTIAL, 5},
+		{"limited by stock", 8, 1_000, "METAL", 10, ERRPARTIAL, 8},
+		{"nothing in stock", 0, 1_000, "METAL", 10, ERRBADREQUEST, 0},
+		{"no transport", 100, 0, "METAL", 10, ERRBADREQUEST, 0},
+		{"zero quantity", 100, 1_000, "METAL", 0, ERRBADREQUEST, 0},
+		{"unknown item", 100, 1_000, "WIDGET", 10, ERRBADREQUEST, 0},
+		{"population is not an item", 100, 1_000, "POPULATION", 10, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa, s1 := mktestfreighter(st)
+			tosa.storage.metal = tc.stock
+			tosa.transports.capacity = tc.transport
+			err := st.LoadCargo("usagi", "tosa", "S1", tc.item, 1, tc.quantity)
+			is.True(errors.Is(err, tc.err))                  // error
+			is.Equal(s1.storage.metal, tc.loaded)            // on the ship
+			is.Equal(tosa.storage.metal, tc.stock-tc.loaded) // left at the colony
+			is.Equal(tosa.transports.used, tc.loaded)        // one unit of transport per mass unit
+		})
+	}
+}
+
+func Test_UnloadCargo(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa, s1 := mktestfreighter(st)
+	s1.depot().addUnit(Unit{Kind: ENGINE, TechLevel: 1, Quantity: 2})
+	tosa.transports.capacity = 1_000
+	is.NoErr(st.UnloadCargo("usagi", "tosa", "S1", "ENGINE", 1, 2))
+	is.Equal(tosa.depot().countUnit(ENGINE, 1, false), 2) // units are unloaded unassembled
+	is.Equal(s1.depot().countUnit(ENGINE, 1, false), 0)
+	is.Equal(tosa.transports.used, 20) // the colony's transports carried them
+
+	is.True(errors.Is(st.UnloadCargo("usagi", "nowhere", "S1", "ENGINE", 1, 2), ERRBADREQUEST)) // unknown colony
+	is.True(errors.Is(st.UnloadCargo("usagi", "tosa", "S2", "ENGINE", 1, 2), ERRBADREQUEST))    // unknown ship
+	s1.setOrbit(nil)
+	is.True(errors.Is(st.UnloadCargo("usagi", "tosa", "S1", "METAL", 1, 2), ERRBADREQUEST)) // ship at the jump point
+}
+
+func Test_PickUpPopulation(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa, s1 := mktestfreighter(st)
+	tosa.population = Population{unskilled: 1_000, total: 1_000}
+	s1.cargoHold = 1_000
+	s1.transports.capacity = 1_000_000
+	is.NoErr(st.PickUpPopulation("usagi", "tosa", "S1", "unskilled", 100))
+	is.Equal(s1.population, Population{unskilled: 100, total: 100})
+	is.Equal(s1.passengers, Population{unskilled: 100, total: 100}) // they ride as passengers
+	is.Equal(tosa.population, Population{unskilled: 900, total: 900})
+	is.True(errors.Is(st.PickUpPopulation("usagi", "tosa", "S1", "robots", 100), ERRBADREQUEST))
+	is.True(errors.Is(st.PickUpPopulation("usagi", "tosa", "S1", "unskilled", 0), ERRBADREQUEST))
+	is.True(errors.Is(st.PickUpPopulation("usagi", "tosa", "tosa", "unskilled", 100), ERRBADREQUEST)) // only ships pick up
+}
+
+func Test_UnloadPopulation(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		kind     string
+		quantity int
+		err      error
+		unloaded int
+	}{
+		{"unloads passengers", "unskilled", 200, nil, 200},
+		{"crew stays aboard", "unskilled", 300, ERRPARTIAL, 200},
+		{"no passengers of that kind", "soldiers", 10, ERRBADREQUEST, 0},
+		{"unknown kind", "robots", 10, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa, s1 := mktestfreighter(st)
+			s1.population = Population{unskilled: 300, total: 300}
+			s1.passengers = Population{unskilled: 200, total: 200}
+			tosa.transports.capacity = 1_000_000
+			err := st.UnloadCargo("usagi", "tosa", "S1", tc.kind, 0, tc.quantity)
+			is.True(errors.Is(err, tc.err))                    // error
+			is.Equal(tosa.population.unskilled, tc.unloaded)   // at the colony
+			is.Equal(s1.population.unskilled, 300-tc.unloaded) // left on the ship
+			is.Equal(s1.passengers.unskilled, 200-tc.unloaded) // left in the hold
+		})
+	}
+}
+
+func Test_TransferPassengers(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa, s1 := mktestfreighter(st)
+	s2 := mktestship(st, st.Polity("usagi"), tosa, "S2")
+	s2.cargoHold = 10
+	s1.population = Population{soldiers: 150, total: 150}
+	s1.passengers = Population{soldiers: 100, total: 100}
+	s1.transports.capacity = 1_000_000
+	is.NoErr(st.Transfer("usagi", "S1", "S2", "soldiers", 0, 100))
+	is.Equal(s1.population, Population{soldiers: 50, total: 50}) // the crew stays aboard
+	is.Equal(s1.passengers, Population{})
+	is.Equal(s2.population, Population{soldiers: 100, total: 100})
+	is.Equal(s2.passengers, Population{soldiers: 100, total: 100}) // and ride in the other ship's hold
+
+	// passengers lost some other way can't be moved
+	s2.population, s2.transports.capacity = Population{soldiers: 40, total: 40}, 1_000_000
+	is.True(errors.Is(st.Transfer("usagi", "S2", "S1", "soldiers", 0, 100), ERRPARTIAL))
+	is.Equal(s1.population.soldiers, 90)
+	is.Equal(s2.population.soldiers, 0)
+}
+
+func Test_Transfer(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		usagiToKuma DiplomaticStatus
+		kumaToUsagi DiplomaticStatus
+		err         error
+		received    int
+	}{
+		{"friends", FRIEND, FRIEND, nil, 10},
+		{"allies", ALLY, ALLY, nil, 10},
+		{"one-sided friendship", FRIEND, ACQUAINTANCE, ERRFORBIDDEN, 0},
+		{"strangers", UNKNOWN, UNKNOWN, ERRFORBIDDEN, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa, s1 := mktestfreighter(st)
+			kuma := mkrival(st, "kuma")
+			k1 := mktestship(st, kuma, tosa, "K1")
+			k1.cargoHold = 10
+			usagi := st.Polity("usagi")
+			usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = tc.usagiToKuma, tc.kumaToUsagi
+			s1.storage.metal, s1.transports.capacity = 10, 1_000
+			err := st.Transfer("usagi", "S1", "K1", "METAL", 1, 10)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(k1.storage.metal, tc.received)
+		})
+	}
+}
+
+func Test_MoveCargoToItself(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	_, s1 := mktestfreighter(st)
+	s1.storage.metal, s1.transports.capacity = 10, 1_000
+	is.True(errors.Is(st.Transfer("usagi", "S1", "S1", "METAL", 1, 10), ERRBADREQUEST))
+}
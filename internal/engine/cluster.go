@@ -79,6 +79,7 @@ func mkcluster() *Cluster {
 	openColony := mkcolony(polity, nil, planet, OPEN)
 	openColony.id = "sanuki"
 	cluster.colonies[openColony.id] = openColony
+	polity.addColony(openColony)
 	polity.home.colony = openColony
 
 	openColony.units = append(openColony.units, Unit{Kind: FARM, Assembled: true, TechLevel: 1, Quantity: 500_000})
@@ -93,6 +94,7 @@ func mkcluster() *Cluster {
 	orbitingColony := mkcolony(polity, orbit, nil, ENCLOSED)
 	orbitingColony.id = "tosa"
 	cluster.colonies[orbitingColony.id] = orbitingColony
+	polity.addColony(orbitingColony)
 
 	for j, kind := range []ResourceKind{RFUEL, RGOLD, RMETAL, RNONMETAL} {
 		resource := mkresource(kind, true)
@@ -31,7 +31,10 @@ func mkcolony(polity *Polity, orbit *Orbit, planet *Planet, kind ColonyKind) *Co
 		kind:   kind,
 		number: polity.nextColonyNumber(),
 		polity: polity,
+		ration: 1,
+		pay:    1,
 	}
+	colony.controls.ships = make(map[string]*Ship)
 	if planet == nil {
 		colony.system = orbit.star.system
 		colony.star = orbit.star
@@ -52,6 +55,7 @@ type Colony struct {
 	number            string
 	polity            *Polity
 	originalPolity    *Polity // set only if this is a Home Colony
+	previousPolity    *Polity // polity that controlled the colony before it last changed hands
 	system            *System
 	star              *Star
 	orbit             *Orbit  // must be in an orbit
@@ -70,22 +74,43 @@ type Colony struct {
 		unskilled     float64
 		others        float64
 	}
-	storage struct {
-		food     int
-		fuel     int
-		gold     int
-		metal    int
-		nonmetal int
-	}
+	storage   storage
+	factories []*FactoryGroup
 	// percent of a full food allotment to be dispersed each turn
-	ration   float64
+	ration float64
+	// percent of the standard pay rate to be dispersed each turn
+	pay      float64
 	controls struct {
 		ships map[string]*Ship // acts as home port to
 	}
-	batteries struct {
-		charged int // power from a plant that expires at the end of the turn
-		used    int
-	}
+	batteries  batteries
+	transports transports
+	// construction workers that are busy for the rest of the turn
+	constructionUsed int
+	// forces that have landed on the colony and are still fighting
+	invaders []*invasion
+	// people that have been drafted and are still training
+	training []*training
+}
+
+// batteries hold power from a plant. The charge expires at the end of the turn.
+type batteries struct {
+	charged int
+	used    int
+}
+
+// available returns the amount of power that has not been used this turn.
+func (b *batteries) available() int {
+	return b.charged - b.used
+}
+
+// storage holds raw resources at a colony or on a ship.
+type storage struct {
+	food     int
+	fuel     int
+	gold     int
+	metal    int
+	nonmetal int
 }
 
 func (c *Colony) addShip(s *Ship) {
@@ -101,7 +126,7 @@ func (c *Colony) delShip(s *Ship) {
 		return
 	}
 	delete(c.controls.ships, s.id)
-	// todo: this should be set to the polity's home world
+	// the caller is responsible for finding the ship a new home port (see Polity.rehome)
 	s.homePort = nil
 }
 
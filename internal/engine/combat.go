@@ -0,0 +1,777 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// tacticalUnitsPerRing is the tactical distance between adjacent orbits.
+const tacticalUnitsPerRing = 100
+
+// tacticalSpeedFactor scales engine thrust to tactical units of speed
+// per unit of ship mass.
+const tacticalSpeedFactor = 10_000
+
+// closeProximityDistance is the tactical distance inside which weapons
+// held for close proximity targeting will fire.
+const closeProximityDistance = 10
+
+// weapon describes how a unit behaves when it is fired.
+type weapon struct {
+	energy bool // energy weapons fire beams; everything else launches missiles
+	damage int  // damage done by each unit when fired
+	reach  int  // maximum tactical distance to the target
+	power  int  // power drawn by each unit when fired
+}
+
+// weaponOf returns the weapon characteristics of the unit if it is a
+// weapon. Only assembled units can be fired.
+//
+// Energy weapons are short ranged and need power to fire. Missiles reach
+// much farther but can be intercepted by anti-missiles.
+func weaponOf(u Unit) (weapon, bool) {
+	if !u.Assembled {
+		return weapon{}, false
+	}
+	switch u.Kind {
+	case ENERGYWEAPON:
+		return weapon{energy: true, damage: 5 * u.TechLevel, reach: 10 * u.TechLevel, power: u.TechLevel}, true
+	case MISSILE:
+		return weapon{damage: 10 * u.TechLevel, reach: 50 * u.TechLevel}, true
+	}
+	return weapon{}, false
+}
+
+// interception returns the missile damage that the assembled
+// anti-missiles in the units can stop in a single segment.
+func interception(units []Unit) float64 {
+	var stopped float64
+	for _, u := range units {
+		if u.Kind == ANTIMISSILE && u.Assembled {
+			stopped += float64(5 * u.TechLevel * u.Quantity)
+		}
+	}
+	return stopped
+}
+
+// weaponClass returns the name used in reports for a class of weapons.
+func weaponClass(energy bool) string {
+	if energy {
+		return "energy weapons"
+	}
+	return "missile launchers"
+}
+
+// hostile returns true if forces of the two polities will fight each other.
+// Polities fight anyone that they don't stand with as friends or allies, but
+// never their own forces or those of their viceroys.
+func hostile(p, t *Polity) bool {
+	return !p.permits(t, FRIEND)
+}
+
+// combatant is a ship or colony that takes part in combat this turn.
+type combatant struct {
+	id     string
+	polity *Polity
+	ship   *Ship
+	colony *Colony
+	depot  depot
+
+	dodge          float64 // fraction of speed used to dodge fire
+	returnFire     float64 // fraction of weapons that automatically return fire
+	closeProximity float64 // fraction of weapons held for targets that come close
+	moved          int     // tactical units moved this turn
+	ran            bool    // ran from combat and may not fire after maneuvering
+	returned       bool    // has already returned fire this turn
+
+	fired       map[bool]float64   // fraction of each class of weapons fired this segment
+	launched    map[int]float64    // missiles launched this segment, by tech level
+	intercepted float64            // missile damage stopped by anti-missiles this segment
+	damage      map[string]float64 // damage taken this segment, by target category
+	attackers   []*combatant       // combatants that fired on this one this segment
+
+	log []string // blow-by-blow combat log
+}
+
+func (c *combatant) logf(format string, args ...interface{}) {
+	c.log = append(c.log, fmt.Sprintf(format, args...))
+}
+
+// system returns the system that the combatant is in.
+func (c *combatant) system() *System {
+	if c.ship != nil {
+		return c.ship.system
+	}
+	return c.colony.system
+}
+
+// position returns the ring and tactical offset of the combatant.
+func (c *combatant) position() (ring, offset int) {
+	if c.ship != nil {
+		return c.ship.ring(), c.ship.offset
+	} else if c.colony.planet != nil {
+		return c.colony.planet.orbit.ring, 0
+	}
+	return c.colony.orbit.ring, 0
+}
+
+// distance returns the tactical distance between two combatants in the same system.
+func (c *combatant) distance(t *combatant) int {
+	cRing, cOffset := c.position()
+	tRing, tOffset := t.position()
+	if cRing == tRing {
+		if cOffset > tOffset {
+			return cOffset - tOffset
+		}
+		return tOffset - cOffset
+	}
+	rings := cRing - tRing
+	if rings < 0 {
+		rings = -rings
+	}
+	return rings*tacticalUnitsPerRing + cOffset + tOffset
+}
+
+// speed returns the tactical units that the combatant can still move this turn.
+// Colonies never move and speed used for dodging isn't available.
+func (c *combatant) speed() int {
+	if c.ship == nil {
+		return 0
+	}
+	speed := int(float64(c.ship.speed()) * (1 - c.dodge))
+	if speed < c.moved {
+		return 0
+	}
+	return speed - c.moved
+}
+
+// evasion returns the fraction of incoming damage that the combatant dodges.
+func (c *combatant) evasion() float64 {
+	if c.ship == nil || c.dodge <= 0 {
+		return 0
+	}
+	thrust, _ := c.ship.engines()
+	mass := c.ship.mass()
+	if thrust == 0 || mass <= 0 {
+		return 0
+	}
+	dodging := float64(thrust) * tacticalSpeedFactor / mass * c.dodge
+	return dodging / (dodging + tacticalUnitsPerRing)
+}
+
+// combat holds the combatants for the turn.
+type combat struct {
+	st         *State
+	combatants map[string]*combatant
+	order      []*combatant // in order of first appearance, for stable reports
+}
+
+func (st *State) newCombat() *combat {
+	return &combat{st: st, combatants: make(map[string]*combatant)}
+}
+
+// combatant returns the ship or colony with the given id, adding it
+// to the combat if it isn't already taking part.
+func (cb *combat) combatant(id string) *combatant {
+	if c, ok := cb.combatants[id]; ok {
+		return c
+	}
+	c := &combatant{id: id, fired: make(map[bool]float64), launched: make(map[int]float64), damage: make(map[string]float64)}
+	if colony := cb.st.Colony(id); colony != nil {
+		c.polity, c.colony, c.depot = colony.polity, colony, colony.depot()
+	} else if ship := cb.st.Ship(id); ship != nil {
+		c.polity, c.ship, c.depot = ship.polity, ship, ship.depot()
+	} else {
+		return nil
+	}
+	cb.combatants[id] = c
+	cb.order = append(cb.order, c)
+	return c
+}
+
+// engage adds the ships and colonies of every polity that has a hostile
+// polity's forces in the same system. Engaged forces return fire on anyone
+// that fires on them and fire on hostiles that come within close range,
+// without an order naming a target, unless their orders set other
+// percentages. Independent colonies are only engaged by orders that name them.
+func (cb *combat) engage() {
+	type force struct {
+		id     string
+		polity *Polity
+	}
+	forces := make(map[string][]force) // by system id
+	for id, s := range cb.st.ships {
+		if s.system != nil && s.polity != nil {
+			forces[s.system.id] = append(forces[s.system.id], force{id, s.polity})
+		}
+	}
+	for id, c := range cb.st.colonies {
+		if c.system != nil && c.polity != nil {
+			forces[c.system.id] = append(forces[c.system.id], force{id, c.polity})
+		}
+	}
+	systems := make([]string, 0, len(forces))
+	for id := range forces {
+		systems = append(systems, id)
+	}
+	sort.Strings(systems)
+	for _, system := range systems {
+		list := forces[system]
+		sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+		for _, f := range list {
+			for _, other := range list {
+				if hostile(f.polity, other.polity) {
+					c := cb.combatant(f.id)
+					c.returnFire, c.closeProximity = 1, 1
+					c.logf("engaging hostile forces of %s", other.polity.name)
+					break
+				}
+			}
+		}
+	}
+}
+
+// actor returns the combatant for a ship or colony that must accept
+// orders from the polity.
+func (cb *combat) actor(issuedBy *Polity, id string) (*combatant, error) {
+	if colony := cb.st.Colony(id); colony != nil {
+		if !colony.acceptsOrdersFrom(issuedBy) {
+			return nil, fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN)
+		}
+	} else if ship := cb.st.Ship(id); ship != nil {
+		if !ship.acceptsOrdersFrom(issuedBy) {
+			return nil, fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN)
+		}
+	} else {
+		return nil, fmt.Errorf("invalid source %q: %w", id, ERRBADREQUEST)
+	}
+	return cb.combatant(id), nil
+}
+
+// opponent returns the combatant for the target. The target must be
+// in the same system as the actor.
+func (cb *combat) opponent(actor *combatant, id string) (*combatant, error) {
+	target := cb.combatant(id)
+	if target == nil {
+		return nil, fmt.Errorf("invalid target %q: %w", id, ERRBADREQUEST)
+	} else if target == actor {
+		return nil, fmt.Errorf("target %q is the actor: %w", id, ERRBADREQUEST)
+	} else if actor.system() == nil || actor.system() != target.system() {
+		return nil, fmt.Errorf("target %q is not in the same system: %w", id, ERRBADREQUEST)
+	}
+	return target, nil
+}
+
+// percentage converts an order's percentage to a fraction.
+func percentage(pct float64) (float64, error) {
+	if pct < 0 || pct > 100 {
+		return 0, fmt.Errorf("invalid percentage %g: %w", pct, ERRBADREQUEST)
+	}
+	return pct / 100, nil
+}
+
+// Dodge sets the fraction of a ship's speed that is used to avoid hostile fire.
+// Setting the percentage to zero disables dodging.
+func (cb *combat) Dodge(issuedBy *Polity, shipID string, pct float64) error {
+	ship := cb.st.Ship(shipID)
+	if ship == nil {
+		return fieldError("ship_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
+	}
+	c, err := cb.actor(issuedBy, shipID)
+	if err != nil {
+		return fieldError("ship_id", err)
+	} else if c.dodge, err = percentage(pct); err != nil {
+		return fieldError("percentage", err)
+	}
+	c.logf("dodging with %s of speed", utils.Percentage(c.dodge))
+	return nil
+}
+
+// AutoReturnFire sets the fraction of weapons that return fire on anyone
+// that fires on the ship or colony.
+func (cb *combat) AutoReturnFire(issuedBy *Polity, sourceID string, pct float64) error {
+	c, err := cb.actor(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	} else if c.returnFire, err = percentage(pct); err != nil {
+		return fieldError("percentage", err)
+	}
+	return nil
+}
+
+// CloseProximityTargeting sets the fraction of weapons that fire on any
+// hostile that comes within close range after maneuvering. Only weapons
+// that weren't fired by the post-maneuver fire orders are used.
+func (cb *combat) CloseProximityTargeting(issuedBy *Polity, sourceID string, pct float64) error {
+	c, err := cb.actor(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	} else if c.closeProximity, err = percentage(pct); err != nil {
+		return fieldError("percentage", err)
+	}
+	return nil
+}
+
+// Fire fires a percentage of one class of weapons at a target.
+//
+// 1. Source must accept orders from the polity issuing the order.
+// 2. Target must be hostile and in the same system as the source.
+// 3. TargetCategory may only be given when the target is a colony. It
+// limits damage to units of that kind.
+// 4. If MaximumTacticalDistance is set and the target is farther away,
+// the weapons are not fired.
+// 5. Only weapons that can reach the target are fired, and no more than
+// the weapons left unfired in this segment.
+func (cb *combat) Fire(issuedBy *Polity, sourceID, targetID string, energy bool, pct float64, category string, maxDistance int) error {
+	attacker, err := cb.actor(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	target, err := cb.opponent(attacker, targetID)
+	if err != nil {
+		return fieldError("target_id", err)
+	} else if !hostile(attacker.polity, target.polity) {
+		return fieldError("target_id", fmt.Errorf("target %q is not hostile: %w", targetID, ERRFORBIDDEN))
+	}
+	fraction, err := percentage(pct)
+	if err != nil {
+		return fieldError("percentage", err)
+	}
+	if category != "" {
+		if target.colony == nil {
+			return fieldError("target_category", fmt.Errorf("target category is only allowed for colonies: %w", ERRBADREQUEST))
+		} else if kind, ok := unitKindFromString(category); !ok {
+			return fieldError("target_category", fmt.Errorf("invalid target category %q: %w", category, ERRBADREQUEST))
+		} else {
+			category = kind.String()
+		}
+	}
+	if attacker.ran {
+		attacker.logf("ran from combat and could not fire on %s", target.id)
+		return nil
+	} else if distance := attacker.distance(target); maxDistance > 0 && distance > maxDistance {
+		attacker.logf("held fire on %s (distance %d exceeds %d)", target.id, distance, maxDistance)
+		return nil
+	}
+	cb.shoot(attacker, target, energy, fraction, category)
+	return nil
+}
+
+// shoot fires a fraction of one class of the attacker's weapons at the target.
+func (cb *combat) shoot(attacker, target *combatant, energy bool, fraction float64, category string) {
+	fraction = math.Min(fraction, 1-attacker.fired[energy])
+	if fraction <= 0 {
+		attacker.logf("has no %s left to fire at %s", weaponClass(energy), target.id)
+		return
+	}
+	distance := attacker.distance(target)
+	var damage, power float64
+	for _, u := range *attacker.depot.units {
+		if w, ok := weaponOf(u); ok && w.energy == energy && w.reach >= distance {
+			damage += fraction * float64(u.Quantity*w.damage)
+			power += fraction * float64(u.Quantity*w.power)
+		}
+	}
+	if damage <= 0 {
+		attacker.logf("has no %s in range of %s (distance %d)", weaponClass(energy), target.id, distance)
+		return
+	}
+
+	// weapons that draw power only fire if there is power for them
+	if power > 0 {
+		available := float64(attacker.depot.power())
+		if available <= 0 {
+			attacker.logf("has no power to fire %s at %s", weaponClass(energy), target.id)
+			return
+		} else if available < power {
+			damage, fraction = damage*available/power, fraction*available/power
+			power = available
+		}
+		attacker.depot.batteries.used += int(math.Ceil(power))
+	}
+	attacker.fired[energy] += fraction
+	if !energy {
+		for _, u := range *attacker.depot.units {
+			if w, ok := weaponOf(u); ok && !w.energy && w.reach >= distance {
+				attacker.launched[u.TechLevel] += fraction * float64(u.Quantity)
+			}
+		}
+	}
+
+	hit := damage * (1 - target.evasion())
+	if !energy {
+		// anti-missiles stop missiles until they are saturated
+		stopped := math.Min(hit, interception(*target.depot.units)-target.intercepted)
+		if stopped > 0 {
+			target.intercepted += stopped
+			hit -= stopped
+			target.logf("anti-missiles intercepted %s damage from %s", utils.Commas(int(stopped)), attacker.id)
+		}
+	}
+	target.damage[category] += hit
+	target.attackers = append(target.attackers, attacker)
+	attacker.logf("fired %s of %s at %s (distance %d): %s damage, %s hit", utils.Percentage(fraction), weaponClass(energy), target.id, distance, utils.Commas(int(damage)), utils.Commas(int(hit)))
+	target.logf("fired on by %s with %s: %s damage", attacker.id, weaponClass(energy), utils.Commas(int(hit)))
+}
+
+// returnFire has every combatant that was fired on and is set to
+// return fire shoot back at the first combatant that fired on it.
+func (cb *combat) returnFire() {
+	for _, c := range cb.order {
+		if c.returned || c.returnFire <= 0 || c.ran || len(c.attackers) == 0 {
+			continue
+		}
+		c.returned = true
+		attacker := c.attackers[0]
+		c.logf("returning fire on %s", attacker.id)
+		for _, energy := range []bool{true, false} {
+			cb.shoot(c, attacker, energy, c.returnFire, "")
+		}
+	}
+}
+
+// closeProximityFire has every combatant with weapons held for close
+// proximity targeting fire on the nearest hostile combatant within range.
+func (cb *combat) closeProximityFire() {
+	for _, c := range cb.order {
+		if c.closeProximity <= 0 || c.ran {
+			continue
+		}
+		var target *combatant
+		for _, t := range cb.order {
+			if t == c || t.system() != c.system() || !hostile(c.polity, t.polity) {
+				continue
+			} else if d := c.distance(t); d <= closeProximityDistance && (target == nil || d < c.distance(target)) {
+				target = t
+			}
+		}
+		if target == nil {
+			continue
+		}
+		c.logf("close proximity targeting %s", target.id)
+		for _, energy := range []bool{true, false} {
+			cb.shoot(c, target, energy, c.closeProximity, "")
+		}
+	}
+}
+
+// allocateDamage destroys units on every combatant that took damage in
+// the segment and resets the combatants for the next segment. Missiles
+// launched in the segment are used up first. Damage is spread over units
+// by their share of the mass, and a unit is destroyed for every multiple
+// of its mass in damage that it takes.
+func (cb *combat) allocateDamage() {
+	cb.returnFire()
+	for _, c := range cb.order {
+		c.expendMissiles()
+	}
+	for _, c := range cb.order {
+		categories := make([]string, 0, len(c.damage))
+		for category := range c.damage {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			damage := c.damage[category]
+			var units []Unit
+			var mass float64
+			for _, u := range *c.depot.units {
+				if category == "" || u.Kind.String() == category {
+					units = append(units, u)
+					mass += u.Mass()
+				}
+			}
+			if mass <= 0 {
+				continue
+			}
+			var losses []string
+			for _, u := range units {
+				one := Unit{Kind: u.Kind, TechLevel: u.TechLevel, Quantity: 1}
+				if one.Mass() <= 0 {
+					continue
+				}
+				destroyed := int(damage * (u.Mass() / mass) / one.Mass())
+				if destroyed = c.depot.removeUnit(u.Kind, u.TechLevel, u.Assembled, destroyed); destroyed != 0 {
+					losses = append(losses, fmt.Sprintf("%s %s", utils.Commas(destroyed), u))
+				}
+			}
+			if len(losses) == 0 {
+				c.logf("took %s damage with no losses", utils.Commas(int(damage)))
+			} else {
+				c.logf("took %s damage, losing %s", utils.Commas(int(damage)), strings.Join(losses, ", "))
+			}
+		}
+		c.fired, c.launched, c.intercepted = make(map[bool]float64), make(map[int]float64), 0
+		c.damage = make(map[string]float64)
+		c.attackers = nil
+	}
+}
+
+// expendMissiles removes the missiles launched in the segment from the
+// combatant's units. Any part of a missile that was fired uses it up.
+func (c *combatant) expendMissiles() {
+	techLevels := make([]int, 0, len(c.launched))
+	for techLevel := range c.launched {
+		techLevels = append(techLevels, techLevel)
+	}
+	sort.Ints(techLevels)
+	for _, techLevel := range techLevels {
+		// the epsilon keeps float error from costing a whole missile
+		quantity := int(math.Ceil(c.launched[techLevel] - 1e-9))
+		if quantity = c.depot.removeUnit(MISSILE, techLevel, true, quantity); quantity != 0 {
+			c.logf("expended %s %s", utils.Commas(quantity), Unit{Kind: MISSILE, TechLevel: techLevel})
+		}
+	}
+}
+
+// maneuver moves a ship toward a tactical offset, limited by its speed.
+func (c *combatant) maneuver(offset int) {
+	if offset < 0 {
+		offset = 0
+	}
+	delta := offset - c.ship.offset
+	if delta < 0 {
+		delta = -delta
+	}
+	if speed := c.speed(); delta > speed {
+		delta = speed
+	}
+	if offset < c.ship.offset {
+		c.ship.offset -= delta
+	} else {
+		c.ship.offset += delta
+	}
+	c.moved += delta
+}
+
+// maneuverer returns the combatant for a ship that must accept orders from the polity.
+func (cb *combat) maneuverer(issuedBy *Polity, shipID string) (*combatant, error) {
+	if cb.st.Ship(shipID) == nil {
+		return nil, fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST)
+	}
+	return cb.actor(issuedBy, shipID)
+}
+
+// Run moves a ship away from a target as fast as it can. A ship that
+// runs may not fire after maneuvering.
+func (cb *combat) Run(issuedBy *Polity, shipID, targetID string) error {
+	c, err := cb.maneuverer(issuedBy, shipID)
+	if err != nil {
+		return fieldError("ship_id", err)
+	}
+	target, err := cb.opponent(c, targetID)
+	if err != nil {
+		return fieldError("target_id", err)
+	}
+	from := c.distance(target)
+	c.maneuver(c.ship.offset + c.speed())
+	c.ran = true
+	c.logf("ran from %s (distance %d to %d)", target.id, from, c.distance(target))
+	return nil
+}
+
+// Close moves a ship toward a target, stopping at the standoff distance.
+// The target must be in the same orbit as the ship.
+func (cb *combat) Close(issuedBy *Polity, shipID, targetID string, standoff int) error {
+	c, err := cb.maneuverer(issuedBy, shipID)
+	if err != nil {
+		return fieldError("ship_id", err)
+	}
+	target, err := cb.opponent(c, targetID)
+	if err != nil {
+		return fieldError("target_id", err)
+	} else if standoff < 0 {
+		return fieldError("standoff_distance", fmt.Errorf("invalid standoff distance %d: %w", standoff, ERRBADREQUEST))
+	}
+	ring, offset := target.position()
+	if ring != c.ship.ring() {
+		return fieldError("target_id", fmt.Errorf("target %q is not in the same orbit: %w", targetID, ERRBADREQUEST))
+	}
+	from := c.distance(target)
+	if c.ship.offset < offset {
+		c.maneuver(offset - standoff)
+	} else {
+		c.maneuver(offset + standoff)
+	}
+	c.logf("closed on %s (distance %d to %d)", target.id, from, c.distance(target))
+	return nil
+}
+
+// TacticalManeuver moves a ship toward a tactical position. Positions are
+// relative to the ship's orbit, and only the distance from the orbit is kept.
+func (cb *combat) TacticalManeuver(issuedBy *Polity, shipID string, to Coords) error {
+	c, err := cb.maneuverer(issuedBy, shipID)
+	if err != nil {
+		return fieldError("ship_id", err)
+	}
+	from := c.ship.offset
+	c.maneuver(int(math.Round(to.distance(Coords{}))))
+	c.logf("maneuvered from offset %d to %d", from, c.ship.offset)
+	return nil
+}
+
+// report adds the combat log of every combatant to its polity's report.
+func (cb *combat) report() {
+	ids := make([]string, 0, len(cb.order))
+	for _, c := range cb.order {
+		ids = append(ids, c.id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c := cb.combatants[id]
+		for _, line := range c.log {
+			c.polity.logf("%s: combat: %s", c.id, line)
+		}
+	}
+}
+
+// spaceCombat resolves the prefire, pre-maneuver fire, maneuver, and
+// post-maneuver fire segments of the combat orders stage. Forces in a
+// system with hostile forces are engaged before any orders are resolved.
+func (st *State) spaceCombat(stageName string, debug bool) []error {
+	var errs []error
+	cb := st.newCombat()
+	cb.engage()
+
+	// prefire segment
+	for i, order := range st.orders {
+		var err error
+		switch {
+		case order.Dodge != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d dodge %v\n", stageName, i, *order.Dodge)
+			}
+			if err = st.record(order, cb.Dodge(st.Polity(order.issuedBy), order.Dodge.ShipID, order.Dodge.Percentage)); err != nil {
+				err = fmt.Errorf("Dodge: %w", err)
+			}
+		case order.AutoReturnFire != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d autoReturnFire %v\n", stageName, i, *order.AutoReturnFire)
+			}
+			if err = st.record(order, cb.AutoReturnFire(st.Polity(order.issuedBy), order.AutoReturnFire.SourceID, order.AutoReturnFire.Percentage)); err != nil {
+				err = fmt.Errorf("AutoReturnFire: %w", err)
+			}
+		case order.CloseProximityTargeting != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d closeProximityTargeting %v\n", stageName, i, *order.CloseProximityTargeting)
+			}
+			if err = st.record(order, cb.CloseProximityTargeting(st.Polity(order.issuedBy), order.CloseProximityTargeting.SourceID, order.CloseProximityTargeting.Percentage)); err != nil {
+				err = fmt.Errorf("CloseProximityTargeting: %w", err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// pre-maneuver fire segment
+	for i, order := range st.orders {
+		var err error
+		switch {
+		case order.PreManeuverEnergyWeaponFire != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d preManeuverEnergyWeaponFire %v\n", stageName, i, *order.PreManeuverEnergyWeaponFire)
+			}
+			o := order.PreManeuverEnergyWeaponFire
+			if err = st.record(order, cb.Fire(st.Polity(order.issuedBy), o.SourceID, o.TargetID, true, o.Percentage, o.TargetCategory, o.MaximumTacticalDistance)); err != nil {
+				err = fmt.Errorf("PreManeuverEnergyWeaponFire: %w", err)
+			}
+		case order.PreManeuverMissileFire != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d preManeuverMissileFire %v\n", stageName, i, *order.PreManeuverMissileFire)
+			}
+			o := order.PreManeuverMissileFire
+			if err = st.record(order, cb.Fire(st.Polity(order.issuedBy), o.SourceID, o.TargetID, false, o.Percentage, o.TargetCategory, o.MaximumTacticalDistance)); err != nil {
+				err = fmt.Errorf("PreManeuverMissileFire: %w", err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	cb.allocateDamage()
+
+	// maneuver segment
+	for i, order := range st.orders {
+		var err error
+		switch {
+		case order.Run != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d run %v\n", stageName, i, *order.Run)
+			}
+			if err = st.record(order, cb.Run(st.Polity(order.issuedBy), order.Run.ShipID, order.Run.TargetID)); err != nil {
+				err = fmt.Errorf("Run: %w", err)
+			}
+		case order.TacticalManeuver != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d tacticalManeuver %v\n", stageName, i, *order.TacticalManeuver)
+			}
+			if err = st.record(order, cb.TacticalManeuver(st.Polity(order.issuedBy), order.TacticalManeuver.ShipID, order.TacticalManeuver.To)); err != nil {
+				err = fmt.Errorf("TacticalManeuver: %w", err)
+			}
+		case order.Close != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d close %v\n", stageName, i, *order.Close)
+			}
+			if err = st.record(order, cb.Close(st.Polity(order.issuedBy), order.Close.ShipID, order.Close.TargetID, order.Close.StandoffDistance)); err != nil {
+				err = fmt.Errorf("Close: %w", err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// post-maneuver fire segment
+	for i, order := range st.orders {
+		var err error
+		switch {
+		case order.AfterManeuverEnergyWeaponFire != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d afterManeuverEnergyWeaponFire %v\n", stageName, i, *order.AfterManeuverEnergyWeaponFire)
+			}
+			o := order.AfterManeuverEnergyWeaponFire
+			if err = st.record(order, cb.Fire(st.Polity(order.issuedBy), o.SourceID, o.TargetID, true, o.Percentage, o.TargetCategory, o.MaximumTacticalDistance)); err != nil {
+				err = fmt.Errorf("AfterManeuverEnergyWeaponFire: %w", err)
+			}
+		case order.AfterManeuverMissileFire != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d afterManeuverMissileFire %v\n", stageName, i, *order.AfterManeuverMissileFire)
+			}
+			o := order.AfterManeuverMissileFire
+			if err = st.record(order, cb.Fire(st.Polity(order.issuedBy), o.SourceID, o.TargetID, false, o.Percentage, o.TargetCategory, o.MaximumTacticalDistance)); err != nil {
+				err = fmt.Errorf("AfterManeuverMissileFire: %w", err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	cb.closeProximityFire()
+	cb.allocateDamage()
+
+	cb.report()
+	return errs
+}
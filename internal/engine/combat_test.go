@@ -0,0 +1,285 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_CombatFireMissiles(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		pct         float64
+		maxDistance int
+		offset      int // tactical offset of K1
+		missiles    int // missiles left on S1
+		structure   int // structural units left on K1
+	}{
+		{"holds fire", 0, 0, 0, 10, 100},
+		{"fires half", 50, 0, 0, 5, 90},
+		{"fires some", 30, 0, 0, 7, 94},
+		{"fires all", 100, 0, 0, 0, 80},
+		{"target beyond maximum distance", 100, 10, 20, 10, 100},
+		{"target beyond reach", 100, 0, 60, 10, 100},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa := st.Colony("tosa")
+			s1 := mktestship(st, st.Polity("usagi"), tosa, "S1",
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true},
+				Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+			k1 := mktestship(st, mkrival(st, "kuma"), tosa, "K1",
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+			k1.offset = tc.offset
+			cb := st.newCombat()
+			is.NoErr(cb.Fire(st.Polity("usagi"), "S1", "K1", false, tc.pct, "", tc.maxDistance))
+			cb.allocateDamage()
+			is.Equal(s1.depot().countUnit(MISSILE, 1, true), tc.missiles)     // missiles are used up when fired
+			is.Equal(k1.depot().countUnit(STRUCTURAL, 1, true), tc.structure) // damage taken
+		})
+	}
+}
+
+func Test_CombatFireSegment(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa := st.Colony("tosa")
+	s1 := mktestship(st, st.Polity("usagi"), tosa, "S1",
+		Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true},
+		Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+	mktestship(st, mkrival(st, "kuma"), tosa, "K1",
+		Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+	cb := st.newCombat()
+	usagi := st.Polity("usagi")
+	is.NoErr(cb.Fire(usagi, "S1", "K1", false, 60, "", 0))
+	is.NoErr(cb.Fire(usagi, "S1", "K1", false, 60, "", 0)) // only the unfired 40% is left
+	cb.allocateDamage()
+	is.Equal(s1.depot().countUnit(MISSILE, 1, true), 0)
+}
+
+func Test_CombatFireErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		issuedBy string
+		sourceID string
+		targetID string
+		pct      float64
+		category string
+		err      error
+	}{
+		{"unknown source", "usagi", "S2", "K1", 50, "", ERRBADREQUEST},
+		{"source refuses", "kuma", "S1", "K1", 50, "", ERRFORBIDDEN},
+		{"unknown target", "usagi", "S1", "K2", 50, "", ERRBADREQUEST},
+		{"target is source", "usagi", "S1", "S1", 50, "", ERRBADREQUEST},
+		{"invalid percentage", "usagi", "S1", "K1", 150, "", ERRBADREQUEST},
+		{"category on a ship", "usagi", "S1", "K1", 50, "SU", ERRBADREQUEST},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa := st.Colony("tosa")
+			mktestship(st, st.Polity("usagi"), tosa, "S1",
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true},
+				Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+			mktestship(st, mkrival(st, "kuma"), tosa, "K1",
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+			err := st.newCombat().Fire(st.Polity(tc.issuedBy), tc.sourceID, tc.targetID, false, tc.pct, tc.category, 0)
+			is.True(errors.Is(err, tc.err))
+		})
+	}
+}
+
+func Test_CombatAllocateDamageOrder(t *testing.T) {
+	is := is.New(t)
+	var first []string
+	for i := 0; i < 20; i++ {
+		st, _ := Make()
+		cb := st.newCombat()
+		tosa := cb.combatant("tosa")
+		tosa.depot.addUnit(Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+		tosa.depot.addUnit(Unit{Kind: ENGINE, TechLevel: 1, Quantity: 100, Assembled: true})
+		tosa.damage["SU"], tosa.damage["ENGINE"], tosa.damage[""] = 50, 50, 50
+		cb.allocateDamage()
+		if first == nil {
+			first = tosa.log
+			is.Equal(len(first), 3)
+		}
+		is.Equal(tosa.log, first) // damage is applied in the same order every time
+	}
+}
+
+func Test_SpaceCombatRecordsOrders(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa := st.Colony("tosa")
+	mktestship(st, st.Polity("usagi"), tosa, "S1",
+		Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true},
+		Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+	mktestship(st, mkrival(st, "kuma"), tosa, "K1",
+		Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+	st.orders = Orders{
+		{issuedBy: "usagi", ID: "1", Dodge: &Dodge{ShipID: "S1", Percentage: 50}},
+		{issuedBy: "usagi", ID: "2", PreManeuverMissileFire: &PreManeuverMissileFire{SourceID: "S1", TargetID: "K1", Percentage: 50}},
+		{issuedBy: "usagi", ID: "3", Run: &Run{ShipID: "S9", TargetID: "K1"}},
+		{issuedBy: "kuma", ID: "4", Close: &Close{ShipID: "S1", TargetID: "K1"}},
+		{issuedBy: "kuma", ID: "5", AutoReturnFire: &AutoReturnFire{SourceID: "K1", Percentage: 200}},
+	}
+	errs := st.spaceCombat("combat", false)
+	is.Equal(len(errs), 3)
+	for i, want := range []OrderStatus{EXECUTED, EXECUTED, REJECTED, REJECTED, REJECTED} {
+		is.Equal(st.orders[i].status, want) // every combat order is recorded
+	}
+}
+
+func Test_SpaceCombatEngagesHostiles(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		setup      func(st *State, s1, k1 *Ship)
+		orders     Orders
+		s1Missiles int // missiles left on S1
+		k1Missiles int // missiles left on K1
+		structure  int // structural units left on K1
+	}{
+		{"hostiles in close range fire without orders", func(st *State, s1, k1 *Ship) {}, nil, 0, 0, 80},
+		{"hostiles out of close range hold fire", func(st *State, s1, k1 *Ship) { k1.offset = 20 }, nil, 10, 0, 100},
+		{"friends do not engage", func(st *State, s1, k1 *Ship) {
+			st.Polity("usagi").diplomacy["kuma"], st.Polity("kuma").diplomacy["usagi"] = FRIEND, FRIEND
+		}, nil, 10, 0, 100},
+		{"orders hold weapons back", func(st *State, s1, k1 *Ship) {}, Orders{
+			{issuedBy: "usagi", ID: "1", CloseProximityTargeting: &CloseProximityTargeting{SourceID: "S1"}},
+		}, 10, 0, 100},
+		{"fire orders are resolved before close proximity targeting", func(st *State, s1, k1 *Ship) {}, Orders{
+			{issuedBy: "usagi", ID: "1", AfterManeuverMissileFire: &AfterManeuverMissileFire{SourceID: "S1", TargetID: "K1", Percentage: 30}},
+		}, 0, 0, 80},
+		{"fire is returned without orders", func(st *State, s1, k1 *Ship) {
+			k1.offset = 20
+			k1.units = append(k1.units, Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+		}, Orders{
+			{issuedBy: "usagi", ID: "1", PreManeuverMissileFire: &PreManeuverMissileFire{SourceID: "S1", TargetID: "K1", Percentage: 50}},
+		}, 0, 0, 80}, // and S1 returns K1's fire with the rest of its missiles
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa := st.Colony("tosa")
+			s1 := mktestship(st, st.Polity("usagi"), tosa, "S1",
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true},
+				Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+			k1 := mktestship(st, mkrival(st, "kuma"), tosa, "K1",
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+			tc.setup(st, s1, k1)
+			st.orders = tc.orders
+			is.Equal(len(st.spaceCombat("combat", false)), 0)
+			is.Equal(s1.depot().countUnit(MISSILE, 1, true), tc.s1Missiles)
+			is.Equal(k1.depot().countUnit(MISSILE, 1, true), tc.k1Missiles)
+			is.Equal(k1.depot().countUnit(STRUCTURAL, 1, true), tc.structure)
+		})
+	}
+}
+
+func Test_WeaponOf(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		unit   Unit
+		ok     bool
+		energy bool
+		damage int
+		reach  int
+		power  int
+	}{
+		{"energy weapon", Unit{Kind: ENERGYWEAPON, TechLevel: 2, Assembled: true}, true, true, 10, 20, 2},
+		{"missile", Unit{Kind: MISSILE, TechLevel: 2, Assembled: true}, true, false, 20, 100, 0},
+		{"unassembled missile", Unit{Kind: MISSILE, TechLevel: 2}, false, false, 0, 0, 0},
+		{"anti-missile", Unit{Kind: ANTIMISSILE, TechLevel: 2, Assembled: true}, false, false, 0, 0, 0},
+		{"engine", Unit{Kind: ENGINE, TechLevel: 2, Assembled: true}, false, false, 0, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			w, ok := weaponOf(tc.unit)
+			is.Equal(ok, tc.ok)
+			is.Equal(w, weapon{energy: tc.energy, damage: tc.damage, reach: tc.reach, power: tc.power})
+		})
+	}
+}
+
+func Test_CombatEnergyWeaponsNeedPower(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		power     int // assembled POWER-1 on S1
+		structure int // structural units left on K1
+	}{
+		{"full power", 10, 90},
+		{"half power", 5, 95},
+		{"no power", 0, 100},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa := st.Colony("tosa")
+			s1 := mktestship(st, st.Polity("usagi"), tosa, "S1",
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true},
+				Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+			k1 := mktestship(st, mkrival(st, "kuma"), tosa, "K1",
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+			s1.units = []Unit{
+				{Kind: ENERGYWEAPON, TechLevel: 1, Quantity: 10, Assembled: true},
+				{Kind: POWER, TechLevel: 1, Quantity: tc.power, Assembled: true},
+			}
+			cb := st.newCombat()
+			is.NoErr(cb.Fire(st.Polity("usagi"), "S1", "K1", true, 100, "", 0))
+			is.Equal(s1.batteries.used, tc.power) // one unit of power per weapon fired
+			cb.allocateDamage()
+			is.Equal(k1.depot().countUnit(STRUCTURAL, 1, true), tc.structure)
+			is.Equal(s1.depot().countUnit(ENERGYWEAPON, 1, true), 10) // energy weapons are not used up
+		})
+	}
+}
+
+func Test_CombatAntiMissiles(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		antiMissiles int // assembled ANTIMISSILE-1 on K1
+		intercepted  float64
+		hit          float64
+	}{
+		{"no anti-missiles", 0, 0, 100},
+		{"some intercepted", 4, 20, 80},
+		{"all intercepted", 40, 100, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa := st.Colony("tosa")
+			mktestship(st, st.Polity("usagi"), tosa, "S1",
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true},
+				Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+			k1 := mktestship(st, mkrival(st, "kuma"), tosa, "K1",
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+			k1.units = append(k1.units, Unit{Kind: ANTIMISSILE, TechLevel: 1, Quantity: tc.antiMissiles, Assembled: true})
+			cb := st.newCombat()
+			is.NoErr(cb.Fire(st.Polity("usagi"), "S1", "K1", false, 100, "", 0))
+			target := cb.combatant("K1")
+			is.Equal(target.intercepted, tc.intercepted)
+			is.Equal(target.damage[""], tc.hit)
+		})
+	}
+}
@@ -35,13 +35,13 @@ func (st *State) CreateAdmin(issuedBy, id string) []error {
 		return []error{fmt.Errorf("engine refused orders: %w", ERRFORBIDDEN)}
 	}
 	if id != strings.TrimSpace(sanitize(id)) {
-		return []error{fmt.Errorf("invalid characters in id: %w", ERRBADREQUEST)}
+		return []error{fieldError("id", fmt.Errorf("invalid characters in id: %w", ERRBADREQUEST))}
 	}
 	if id == "" {
 		id = uuid.New().String()
 	}
 	if _, ok := st.admins[id]; ok {
-		return []error{fmt.Errorf("duplicate id: %w", ERRBADREQUEST)}
+		return []error{fieldError("id", fmt.Errorf("duplicate id: %w", ERRBADREQUEST))}
 	}
 	st.admins[id] = true
 	return nil
@@ -35,8 +35,7 @@ func Test_CreateAdmin(t *testing.T) {
 	orders = append(orders, &order)
 	is.True(len(orders) == 1)
 
-	id, errs := st.CreateAdmin(admin, "mdhender")
+	errs := st.CreateAdmin(admin, "mdhender")
 	is.True(len(errs) == 0)
-	is.True(id == "mdhender")
-	is.True(st.admins[id])
+	is.True(st.admins["mdhender"])
 }
@@ -36,31 +36,30 @@ func (st *State) CreatePolity(issuedBy, id, name string) []error {
 		return []error{fmt.Errorf("engine refused orders: %w", ERRFORBIDDEN)}
 	}
 	if id != strings.TrimSpace(id) {
-		return []error{fmt.Errorf("invalid characters in id: %w", ERRBADREQUEST)}
+		return []error{fieldError("id", fmt.Errorf("invalid characters in id: %w", ERRBADREQUEST))}
 	}
 	if id == "" {
 		id = uuid.New().String()
 	}
 	if st.isDuplicateID(id) {
-		return []error{fmt.Errorf("duplicate id: %w", ERRBADREQUEST)}
+		return []error{fieldError("id", fmt.Errorf("duplicate id: %w", ERRBADREQUEST))}
 	}
 	if name == "" {
-		return []error{fmt.Errorf("missing name: %w", ERRBADREQUEST)}
+		return []error{fieldError("name", fmt.Errorf("missing name: %w", ERRBADREQUEST))}
 	} else if cleanName := strings.TrimSpace(sanitize(name)); name != cleanName {
-		return []error{fmt.Errorf("invalid characters in name: %w", ERRBADREQUEST)}
+		return []error{fieldError("name", fmt.Errorf("invalid characters in name: %w", ERRBADREQUEST))}
 	} else {
 		upperName := strings.ToUpper(name)
 		for _, p := range st.polities {
 			if name == p.name || strings.ToUpper(p.name) == upperName {
-				return []error{fmt.Errorf("duplicate name %q: %w", name, ERRBADREQUEST)}
+				return []error{fieldError("name", fmt.Errorf("duplicate name %q: %w", name, ERRBADREQUEST))}
 			}
 		}
 	}
 
-	st.polities[id] = &Polity{
-		id:   id,
-		name: name,
-	}
+	p := polity()
+	p.id, p.name, p.events = id, name, st.events
+	st.polities[id] = p
 
 	return nil
 }
@@ -27,9 +27,9 @@ func Test_CreatePolity(t *testing.T) {
 	is := is.New(t)
 
 	st, admin := Make()
-	id, errs := st.CreatePolity(admin, "")
+	id := "tomoe"
+	errs := st.CreatePolity(admin, id, "Tomoe")
 	is.True(len(errs) == 0)
-	is.True(id != "")
 	p := st.Polity(id)
 	is.True(p != nil)
 	is.True(p.id == id)
@@ -38,13 +38,13 @@ func (st *State) CreateSystem(issuedBy, id string, x, y, z int) []error {
 		return []error{fmt.Errorf("engine refused orders: %w", ERRFORBIDDEN)}
 	}
 	if id != strings.TrimSpace(id) {
-		return []error{fmt.Errorf("invalid characters in id: %w", ERRBADREQUEST)}
+		return []error{fieldError("id", fmt.Errorf("invalid characters in id: %w", ERRBADREQUEST))}
 	}
 	if id == "" {
 		id = uuid.New().String()
 	}
 	if st.isDuplicateID(id) {
-		return []error{fmt.Errorf("duplicate id: %w", ERRBADREQUEST)}
+		return []error{fieldError("id", fmt.Errorf("duplicate id: %w", ERRBADREQUEST))}
 	}
 
 	system := &System{id: id}
@@ -0,0 +1,172 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import "fmt"
+
+// depot is a view of the people, units, and storage at a colony or on a ship.
+// It lets the stages work with either one without caring which they have.
+type depot struct {
+	id         string
+	colony     *Colony
+	ship       *Ship
+	population *Population
+	passengers *Population // nil for colonies, which have no cargo hold
+	units      *[]Unit
+	storage    *storage
+	batteries  *batteries
+	transports *transports
+	factories  *[]*FactoryGroup
+	// construction workers that are busy for the rest of the turn
+	constructionUsed *int
+}
+
+func (c *Colony) depot() depot {
+	return depot{
+		id:         c.id,
+		colony:     c,
+		population: &c.population,
+		units:      &c.units,
+		storage:    &c.storage,
+		batteries:  &c.batteries,
+		transports: &c.transports,
+		factories:  &c.factories,
+
+		constructionUsed: &c.constructionUsed,
+	}
+}
+
+func (s *Ship) depot() depot {
+	return depot{
+		id:         s.id,
+		ship:       s,
+		population: &s.population,
+		passengers: &s.passengers,
+		units:      &s.units,
+		storage:    &s.storage,
+		batteries:  &s.batteries,
+		transports: &s.transports,
+		factories:  &s.factories,
+
+		constructionUsed: &s.constructionUsed,
+	}
+}
+
+// findDepot returns the depot for the colony or ship with the given id.
+// The depot must accept orders from the polity.
+func (st *State) findDepot(issuedBy *Polity, id string) (depot, error) {
+	if colony := st.Colony(id); colony != nil {
+		if !colony.acceptsOrdersFrom(issuedBy) {
+			return depot{}, fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN)
+		}
+		return colony.depot(), nil
+	} else if ship := st.Ship(id); ship != nil {
+		if !ship.acceptsOrdersFrom(issuedBy) {
+			return depot{}, fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN)
+		}
+		return ship.depot(), nil
+	}
+	return depot{}, fmt.Errorf("invalid source %q: %w", id, ERRBADREQUEST)
+}
+
+// findTarget returns the depot for the colony or ship with the given id.
+// The depot does not need to accept orders from anyone.
+func (st *State) findTarget(id string) (depot, error) {
+	if colony := st.Colony(id); colony != nil {
+		return colony.depot(), nil
+	} else if ship := st.Ship(id); ship != nil {
+		return ship.depot(), nil
+	}
+	return depot{}, fmt.Errorf("invalid target %q: %w", id, ERRBADREQUEST)
+}
+
+// polity returns the polity that controls the depot.
+func (d depot) polity() *Polity {
+	if d.colony != nil {
+		return d.colony.polity
+	}
+	return d.ship.polity
+}
+
+// addUnit adds the unit to the depot, merging it with any existing
+// units of the same kind, tech level, and assembly status.
+func (d depot) addUnit(u Unit) {
+	if u.Quantity <= 0 {
+		return
+	}
+	for i, unit := range *d.units {
+		if unit.Kind == u.Kind && unit.TechLevel == u.TechLevel && unit.Assembled == u.Assembled {
+			(*d.units)[i].Quantity += u.Quantity
+			return
+		}
+	}
+	*d.units = append(*d.units, u)
+}
+
+// removeUnit takes up to quantity units of the given kind, tech level,
+// and assembly status from the depot. It returns the number removed.
+func (d depot) removeUnit(kind UnitKind, techLevel int, assembled bool, quantity int) int {
+	if quantity <= 0 {
+		return 0
+	}
+	for i, unit := range *d.units {
+		if unit.Kind == kind && unit.TechLevel == techLevel && unit.Assembled == assembled {
+			if unit.Quantity < quantity {
+				quantity = unit.Quantity
+			}
+			(*d.units)[i].Quantity -= quantity
+			if (*d.units)[i].Quantity == 0 {
+				*d.units = append((*d.units)[:i], (*d.units)[i+1:]...)
+			}
+			return quantity
+		}
+	}
+	return 0
+}
+
+// countUnit returns the number of units of the given kind, tech level,
+// and assembly status in the depot.
+func (d depot) countUnit(kind UnitKind, techLevel int, assembled bool) int {
+	for _, unit := range *d.units {
+		if unit.Kind == kind && unit.TechLevel == techLevel && unit.Assembled == assembled {
+			return unit.Quantity
+		}
+	}
+	return 0
+}
+
+// store adds items to the depot. Raw resources go into storage and
+// everything else is stored as unassembled units.
+func (d depot) store(u Unit) {
+	switch u.Kind {
+	case FOOD:
+		d.storage.food += u.Quantity
+	case FUEL:
+		d.storage.fuel += u.Quantity
+	case GOLD:
+		d.storage.gold += u.Quantity
+	case METAL:
+		d.storage.metal += u.Quantity
+	case NONMETAL:
+		d.storage.nonmetal += u.Quantity
+	default:
+		u.Assembled = false
+		d.addUnit(u)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"log"
+)
+
+// Diplomacy order sets the stance that the polity takes toward another polity.
+type Diplomacy struct {
+	PolityID string `json:"polity_id"` // id of polity the stance is taken toward
+	Status   string `json:"status"`    // one of "unknown", "acquaintance", "friend", or "ally"
+}
+
+// Diplomacy sets the stance that the polity issuing the order takes toward another polity.
+//
+// 1. Polity identified by PolityID must not be the polity issuing the order.
+// 2. Status must be "unknown", "acquaintance", "friend", or "ally".
+// 3. The target is sent a notice of the new stance.
+// 4. What the two polities may do together depends on the lower of their stances.
+func (st *State) Diplomacy(issuedByID, polityID, status string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Diplomacy: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	target := st.Polity(polityID)
+	if target == nil || target == issuedBy {
+		return fieldError("polity_id", fmt.Errorf("invalid polity %q: %w", polityID, ERRBADREQUEST))
+	}
+	ds, ok := diplomaticStatusFromString(status)
+	if !ok {
+		return fieldError("status", fmt.Errorf("invalid status %q: %w", status, ERRBADREQUEST))
+	} else if issuedBy.diplomaticStatus(target) == ds {
+		return nil // nothing to do
+	}
+	issuedBy.diplomacy[target.id] = ds
+	issuedBy.logf("diplomacy: now %s toward %s (standing %s)", ds, target.name, issuedBy.standing(target))
+	st.notify(issuedBy, target, fmt.Sprintf("%s now regards you as %s.", issuedBy.name, ds))
+	return nil
+}
+
+// shareIntel copies observations between every pair of polities that
+// stand as allies.
+func (st *State) shareIntel() {
+	for _, p := range st.polities {
+		for _, t := range st.polities {
+			if p != t && p.isAlliedTo(t) {
+				p.intel.share(t.intel)
+			}
+		}
+	}
+}
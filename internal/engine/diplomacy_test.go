@@ -0,0 +1,121 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_Diplomacy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		polity  string
+		status  string
+		err     error
+		want    DiplomaticStatus
+		notices int
+	}{
+		{"friend", "kuma", "friend", nil, FRIEND, 1},
+		{"ally", "kuma", " ALLY ", nil, ALLY, 1},
+		{"unchanged", "kuma", "acquaintance", nil, ACQUAINTANCE, 0},
+		{"unknown status", "kuma", "enemy", ERRBADREQUEST, ACQUAINTANCE, 0},
+		{"self", "usagi", "ally", ERRBADREQUEST, ACQUAINTANCE, 0},
+		{"unknown polity", "tora", "ally", ERRBADREQUEST, ACQUAINTANCE, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			usagi.diplomacy["kuma"] = ACQUAINTANCE
+			err := st.Diplomacy("usagi", tc.polity, tc.status)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(usagi.diplomaticStatus(kuma), tc.want)
+			is.Equal(kuma.diplomaticStatus(usagi), UNKNOWN) // the other side's stance is its own
+			is.Equal(len(st.mail), tc.notices)              // the target is told of the new stance
+		})
+	}
+	is := is.New(t)
+	st, _ := Make()
+	mkrival(st, "kuma")
+	is.Equal(st.Diplomacy("nobody", "kuma", "ally"), ERRBUG)
+}
+
+func Test_Standing(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		stance   DiplomaticStatus // usagi toward kuma
+		response DiplomaticStatus // kuma toward usagi
+		viceroy  bool             // kuma is usagi's viceroy
+		want     DiplomaticStatus
+		hostile  bool
+	}{
+		{"strangers", UNKNOWN, UNKNOWN, false, UNKNOWN, true},
+		{"one sided", ALLY, UNKNOWN, false, UNKNOWN, true},
+		{"acquaintances", ACQUAINTANCE, FRIEND, false, ACQUAINTANCE, true},
+		{"friends", FRIEND, ALLY, false, FRIEND, false},
+		{"allies", ALLY, ALLY, false, ALLY, false},
+		{"viceroy", UNKNOWN, UNKNOWN, true, ALLY, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = tc.stance, tc.response
+			if tc.viceroy {
+				kuma.viceroyOf = usagi
+			}
+			is.Equal(usagi.standing(kuma), tc.want) // lower of the two stances
+			is.Equal(kuma.standing(usagi), tc.want) // standing is shared
+			is.Equal(hostile(usagi, kuma), tc.hostile)
+			is.Equal(usagi.isAlliedTo(kuma), tc.want == ALLY)
+		})
+	}
+	is := is.New(t)
+	st, _ := Make()
+	usagi := st.Polity("usagi")
+	is.Equal(usagi.standing(usagi), ALLY) // polities never fight themselves
+	is.Equal(usagi.standing(nil), UNKNOWN)
+}
+
+func Test_ShareIntel(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status DiplomaticStatus
+		shared bool
+	}{
+		{"allies", ALLY, true},
+		{"friends", FRIEND, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = tc.status, tc.status
+			usagi.intel.observeSystem(st.turn, st.System("mizugame"))
+			kuma.intel.surveyPlanet(st.turn, st.Planet("suisei"))
+			st.shareIntel()
+			_, ok := kuma.intel.systems["mizugame"]
+			is.Equal(ok, tc.shared) // allies share what they have seen
+			_, ok = usagi.intel.planets["suisei"]
+			is.Equal(ok, tc.shared) // in both directions
+		})
+	}
+}
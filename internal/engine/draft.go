@@ -0,0 +1,193 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+)
+
+// draftCost is the consumer goods paid to each person drafted and the
+// number of turns that they spend in training before taking up the job.
+type draftCost struct {
+	goods int
+	turns int
+}
+
+// draftCosts are the jobs that unskilled people can be drafted into.
+var draftCosts = map[PopulationKind]draftCost{
+	SOLDIERS: {goods: 1, turns: 1},
+	SPIES:    {goods: 2, turns: 2},
+	TRAINEES: {goods: 1, turns: 1},
+}
+
+// training is a group of people that have been drafted and are learning
+// a new job. They are still counted in the colony's total population.
+type training struct {
+	kind     PopulationKind
+	quantity int
+	turns    int // turns left before they take up the job
+}
+
+// goods returns the consumer goods in the depot's storage.
+func (d depot) goods() (quantity int) {
+	for _, u := range *d.units {
+		if u.Kind == CONSUMERGOOD && !u.Assembled {
+			quantity += u.Quantity
+		}
+	}
+	return quantity
+}
+
+// payGoods removes consumer goods of any tech level from the depot's storage.
+func (d depot) payGoods(quantity int) {
+	for i := 0; quantity > 0 && i < len(*d.units); {
+		u := (*d.units)[i]
+		if u.Kind == CONSUMERGOOD && !u.Assembled {
+			quantity -= d.removeUnit(CONSUMERGOOD, u.TechLevel, false, quantity)
+			continue
+		}
+		i++
+	}
+}
+
+// inTraining returns the number of people in training at the depot.
+// Only colonies train people.
+func (d depot) inTraining() (n int) {
+	if d.colony != nil {
+		for _, t := range d.colony.training {
+			n += t.quantity
+		}
+	}
+	return n
+}
+
+// graduate moves people that have finished training into their new jobs.
+func (c *Colony) graduate() {
+	var still []*training
+	for _, t := range c.training {
+		if t.turns--; t.turns > 0 {
+			still = append(still, t)
+			continue
+		}
+		*c.population.count(t.kind) += t.quantity
+		c.polity.logf("%s: %s %s finished training", c.id, utils.Commas(t.quantity), t.kind)
+	}
+	c.training = still
+}
+
+// draftColony returns the colony for a draft or disband order and the
+// kind of population that it names.
+func (st *State) draftColony(issuedBy *Polity, sourceID, populationType string) (*Colony, PopulationKind, error) {
+	colony := st.Colony(sourceID)
+	if colony == nil {
+		if st.Ship(sourceID) != nil {
+			return nil, OTHERS, fieldError("source_id", fmt.Errorf("only colonies may draft or disband: %w", ERRBADREQUEST))
+		}
+		return nil, OTHERS, fieldError("source_id", fmt.Errorf("invalid colony %q: %w", sourceID, ERRBADREQUEST))
+	} else if !colony.acceptsOrdersFrom(issuedBy) {
+		return nil, OTHERS, fieldError("source_id", fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN))
+	}
+	kind, ok := populationKindFromString(populationType)
+	if _, draftable := draftCosts[kind]; !ok || !draftable {
+		return nil, OTHERS, fieldError("population_type", fmt.Errorf("invalid population type %q: %w", populationType, ERRBADREQUEST))
+	}
+	return colony, kind, nil
+}
+
+// Draft converts unskilled people at a colony into soldiers, spies, or trainees.
+//
+// 1. Colony identified by SourceID must accept orders from the polity issuing the order.
+// 2. PopulationType must be soldiers, spies, or trainees. Polities have a
+// single race, so RaceID is not checked.
+// 3. Quantity must be greater than zero.
+// 4. Each person drafted is paid consumer goods from the colony's storage:
+// one for soldiers and trainees, two for spies.
+// 5. Drafted people train before taking up the job: one turn for soldiers
+// and trainees, two for spies.
+// 6. Quantity may exceed the unskilled people or the consumer goods
+// available; the overage is ignored and the order is partially executed.
+// The order is rejected if no one can be drafted.
+func (st *State) Draft(issuedByID, sourceID, populationType string, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Draft: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	colony, kind, err := st.draftColony(issuedBy, sourceID, populationType)
+	if err != nil {
+		return err
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+
+	d, cost := colony.depot(), draftCosts[kind]
+	drafted := minInt(quantity, minInt(colony.population.unskilled, d.goods()/cost.goods))
+	if drafted == 0 {
+		return fmt.Errorf("not enough unskilled or consumer goods to draft %s: %w", kind, ERRBADREQUEST)
+	}
+	d.payGoods(drafted * cost.goods)
+	colony.population.unskilled -= drafted
+	colony.training = append(colony.training, &training{kind: kind, quantity: drafted, turns: cost.turns})
+	if drafted < quantity {
+		colony.polity.logf("%s: drafted %s of %s %s (not enough unskilled or consumer goods)", sourceID, utils.Commas(drafted), utils.Commas(quantity), kind)
+		return fmt.Errorf("drafted %d of %d: %w", drafted, quantity, ERRPARTIAL)
+	}
+	colony.polity.logf("%s: drafted %s %s", sourceID, utils.Commas(drafted), kind)
+	return nil
+}
+
+// Disband releases soldiers, spies, or trainees at a colony back into the
+// unskilled population.
+//
+// 1. Colony identified by SourceID must accept orders from the polity issuing the order.
+// 2. PopulationType must be soldiers, spies, or trainees. People still in
+// training can't be disbanded.
+// 3. Quantity must be greater than zero.
+// 4. Quantity may exceed the people available; the overage is ignored and
+// the order is partially executed. The order is rejected if there is no one
+// to disband.
+func (st *State) Disband(issuedByID, sourceID, populationType string, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Disband: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	colony, kind, err := st.draftColony(issuedBy, sourceID, populationType)
+	if err != nil {
+		return err
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+
+	count := colony.population.count(kind)
+	disbanded := minInt(quantity, *count)
+	if disbanded == 0 {
+		return fmt.Errorf("no %s to disband: %w", kind, ERRBADREQUEST)
+	}
+	*count -= disbanded
+	colony.population.unskilled += disbanded
+	if disbanded < quantity {
+		colony.polity.logf("%s: disbanded %s of %s %s (not enough %s)", sourceID, utils.Commas(disbanded), utils.Commas(quantity), kind, kind)
+		return fmt.Errorf("disbanded %d of %d: %w", disbanded, quantity, ERRPARTIAL)
+	}
+	colony.polity.logf("%s: disbanded %s %s", sourceID, utils.Commas(disbanded), kind)
+	return nil
+}
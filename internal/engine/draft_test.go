@@ -0,0 +1,129 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_Draft(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		unskilled int
+		goods     int
+		kind      string
+		quantity  int
+		err       error
+		drafted   int
+		turns     int
+	}{
+		{"soldiers", 100, 100, "soldiers", 50, nil, 50, 1},
+		{"spies cost more", 100, 100, "spies", 50, nil, 50, 2},
+		{"trainees", 100, 100, "trainees", 50, nil, 50, 1},
+		{"limited by unskilled", 40, 100, "soldiers", 50, ERRPARTIAL, 40, 1},
+		{"limited by goods", 100, 30, "spies", 50, ERRPARTIAL, 15, 2},
+		{"no unskilled", 0, 100, "soldiers", 50, ERRBADREQUEST, 0, 0},
+		{"no goods", 100, 1, "spies", 50, ERRBADREQUEST, 0, 0},
+		{"zero quantity", 100, 100, "soldiers", 0, ERRBADREQUEST, 0, 0},
+		{"not draftable", 100, 100, "professionals", 50, ERRBADREQUEST, 0, 0},
+		{"unknown type", 100, 100, "pirates", 50, ERRBADREQUEST, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			c.population = Population{unskilled: tc.unskilled, total: tc.unskilled}
+			c.units = append(c.units, Unit{Kind: CONSUMERGOOD, TechLevel: 1, Quantity: tc.goods})
+			err := st.Draft("usagi", "tosa", tc.kind, tc.quantity)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(c.population.unskilled, tc.unskilled-tc.drafted)
+			is.Equal(c.population.total, tc.unskilled) // people in training are still counted
+			if tc.drafted == 0 {
+				is.Equal(len(c.training), 0)
+				return
+			}
+			is.Equal(len(c.training), 1)
+			is.Equal(*c.training[0], training{kind: c.training[0].kind, quantity: tc.drafted, turns: tc.turns})
+			is.Equal(c.depot().goods(), tc.goods-tc.drafted*draftCosts[c.training[0].kind].goods) // drafted people are paid
+		})
+	}
+}
+
+func Test_DraftSource(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	c := st.Colony("tosa")
+	c.population = Population{unskilled: 100, total: 100}
+	c.units = append(c.units, Unit{Kind: CONSUMERGOOD, TechLevel: 1, Quantity: 100})
+	mkrival(st, "kuma")
+	mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1")
+	is.True(errors.Is(st.Draft("usagi", "S1", "soldiers", 10), ERRBADREQUEST))      // ships don't draft
+	is.True(errors.Is(st.Draft("usagi", "nowhere", "soldiers", 10), ERRBADREQUEST)) // unknown colony
+	is.True(errors.Is(st.Draft("kuma", "tosa", "soldiers", 10), ERRFORBIDDEN))      // colony refuses a rival
+	is.Equal(st.Draft("nobody", "tosa", "soldiers", 10), ERRBUG)
+}
+
+func Test_Graduate(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	c := st.Colony("tosa")
+	c.population = Population{unskilled: 100, total: 100}
+	c.units = append(c.units, Unit{Kind: CONSUMERGOOD, TechLevel: 1, Quantity: 100})
+	is.NoErr(st.Draft("usagi", "tosa", "soldiers", 10))
+	is.NoErr(st.Draft("usagi", "tosa", "spies", 10))
+	c.graduate()
+	is.Equal(c.population.soldiers, 10) // soldiers train for one turn
+	is.Equal(c.population.spies, 0)
+	is.Equal(len(c.training), 1)
+	c.graduate()
+	is.Equal(c.population.spies, 10) // spies train for two
+	is.Equal(len(c.training), 0)
+	is.Equal(c.population.total, 100)
+}
+
+func Test_Disband(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		kind      string
+		quantity  int
+		err       error
+		disbanded int
+	}{
+		{"soldiers", "soldiers", 10, nil, 10},
+		{"more than there are", "soldiers", 30, ERRPARTIAL, 20},
+		{"none to disband", "spies", 10, ERRBADREQUEST, 0},
+		{"zero quantity", "soldiers", 0, ERRBADREQUEST, 0},
+		{"not disbandable", "construction", 10, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			c.population = Population{unskilled: 100, total: 100}
+			c.population.soldiers, c.population.total = 20, 120
+			err := st.Disband("usagi", "tosa", tc.kind, tc.quantity)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(c.population.soldiers, 20-tc.disbanded)
+			is.Equal(c.population.unskilled, 100+tc.disbanded)
+			is.Equal(c.population.total, 120)
+		})
+	}
+}
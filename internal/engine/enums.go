@@ -18,7 +18,10 @@
 
 package engine
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // enums
 type PlanetKind int
@@ -29,6 +32,19 @@ const (
 	TERRESTRIAL
 )
 
+// String implements the stringer interface
+func (k PlanetKind) String() string {
+	switch k {
+	case ASTEROIDBELT:
+		return "asteroid-belt"
+	case GASGIANT:
+		return "gas-giant"
+	case TERRESTRIAL:
+		return "terrestrial"
+	}
+	return fmt.Sprintf("PlanetKind(%d)", int(k))
+}
+
 // ColonyKind is TODO
 type ColonyKind int
 
@@ -49,10 +65,24 @@ func (k ColonyKind) String() string {
 	case ORBITING:
 		return "orbiting"
 	}
-	return fmt.Sprint("ColonyKind(%d)", k)
+	return fmt.Sprintf("ColonyKind(%d)", int(k))
 }
 
-// DiplomaticStatus is TODO
+// colonyKindFromString returns the ColonyKind with the given name.
+// The name is not case sensitive.
+func colonyKindFromString(name string) (ColonyKind, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for k := OPEN; k <= ORBITING; k++ {
+		if k.String() == name {
+			return k, true
+		}
+	}
+	return OPEN, false
+}
+
+// DiplomaticStatus is the stance that a polity takes toward another.
+// Each level unlocks everything that the levels below it do. What two
+// polities may do together is limited by the lower of their stances.
 type DiplomaticStatus int
 
 // enums for DiplomaticStatus. order is important.
@@ -60,10 +90,37 @@ type DiplomaticStatus int
 const (
 	UNKNOWN      DiplomaticStatus = iota
 	ACQUAINTANCE                  // allows messages to be sent
-	FRIEND                        // allows assets to be transferred
-	ALLY
+	FRIEND                        // allows cargo to be transferred and forces hold their fire
+	ALLY                          // allows assets to be given, colonies to be founded, and vision to be shared
 )
 
+// String implements the stringer interface
+func (ds DiplomaticStatus) String() string {
+	switch ds {
+	case UNKNOWN:
+		return "unknown"
+	case ACQUAINTANCE:
+		return "acquaintance"
+	case FRIEND:
+		return "friend"
+	case ALLY:
+		return "ally"
+	}
+	return fmt.Sprintf("DiplomaticStatus(%d)", int(ds))
+}
+
+// diplomaticStatusFromString returns the DiplomaticStatus with the given name.
+// The name is not case sensitive.
+func diplomaticStatusFromString(name string) (DiplomaticStatus, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for ds := UNKNOWN; ds <= ALLY; ds++ {
+		if ds.String() == name {
+			return ds, true
+		}
+	}
+	return UNKNOWN, false
+}
+
 // PopulationKind is the type of population unit.
 // It controls what actions the unit may perform.
 type PopulationKind int
@@ -98,6 +155,18 @@ func (k PopulationKind) String() string {
 	}
 }
 
+// populationKindFromString returns the PopulationKind with the given name.
+// The name is not case sensitive.
+func populationKindFromString(name string) (PopulationKind, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for k := OTHERS; k <= UNSKILLED; k++ {
+		if k.String() == name {
+			return k, true
+		}
+	}
+	return OTHERS, false
+}
+
 // ResourceKind is TODO
 type ResourceKind int
 
@@ -131,7 +200,11 @@ type UnitKind int
 // enums for UnitKind
 const (
 	NOOP UnitKind = iota
+	ANTIMISSILE
 	CONSUMERGOOD
+	ENERGYWEAPON
+	ENGINE
+	FACTORY
 	FARM
 	FOOD
 	FUEL
@@ -140,17 +213,27 @@ const (
 	METAL
 	MINE
 	MINEGROUP
+	MISSILE
 	NONMETAL
 	POPULATION
 	POWER
 	STRUCTURAL
+	TRANSPORT
 )
 
 // String implements the stringer interface
 func (k UnitKind) String() string {
 	switch k {
+	case ANTIMISSILE:
+		return "ANTIMISSILE"
 	case CONSUMERGOOD:
 		return "GOODS"
+	case ENERGYWEAPON:
+		return "ENERGYWEAPON"
+	case ENGINE:
+		return "ENGINE"
+	case FACTORY:
+		return "FACTORY"
 	case FARM:
 		return "FARM"
 	case FOOD:
@@ -167,6 +250,8 @@ func (k UnitKind) String() string {
 		return "MINE"
 	case MINEGROUP:
 		return "MINEGROUP"
+	case MISSILE:
+		return "MISSILE"
 	case NONMETAL:
 		return "NONMETAL"
 	case NOOP:
@@ -177,7 +262,21 @@ func (k UnitKind) String() string {
 		return "POWER"
 	case STRUCTURAL:
 		return "SU"
+	case TRANSPORT:
+		return "TRANSPORT"
 	default:
 		return fmt.Sprintf("UNIT(%d)", k)
 	}
 }
+
+// unitKindFromString returns the UnitKind with the given name.
+// The name is not case sensitive.
+func unitKindFromString(name string) (UnitKind, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for k := NOOP; k <= TRANSPORT; k++ {
+		if k.String() == name {
+			return k, true
+		}
+	}
+	return NOOP, false
+}
@@ -18,10 +18,112 @@ package engine
 
 import (
 	"errors"
+	"fmt"
 )
 
 var ERRBADREQUEST = errors.New("invalid request")
 var ERRBUG = errors.New("bug")
 var ERRFORBIDDEN = errors.New("forbidden")
+var ERRNOTFOUND = errors.New("not found")
 var ERRNOTIMPLEMENTED = errors.New("not implemented")
+var ERRPARTIAL = errors.New("partially executed")
 var ERRUNAUTHORIZED = errors.New("unauthorized")
+
+// codes maps the error sentinels to the codes reported to clients.
+// The first sentinel that matches is used.
+var codes = []struct {
+	err  error
+	code string
+}{
+	{ERRBUG, "bug"},
+	{ERRUNAUTHORIZED, "unauthorized"},
+	{ERRFORBIDDEN, "forbidden"},
+	{ERRNOTFOUND, "not_found"},
+	{ERRNOTIMPLEMENTED, "not_implemented"},
+	{ERRPARTIAL, "partial"},
+	{ERRBADREQUEST, "bad_request"},
+}
+
+// Error is an error with enough structure for a client to tell what
+// went wrong and, for errors from orders, which order and field caused it.
+// It unwraps to its Code, so errors.Is works with the sentinels.
+type Error struct {
+	Code    error  // one of the ERR sentinels
+	Order   int    // index of the order in the submitted list, or -1 if the error is not from an order
+	Field   string // path within the order to the field that caused the error, for example "target_id" or "items/2"
+	Pointer string // JSON pointer to the order or field, for example "/orders/3/give/target_id"
+	Text    string // description of the error
+}
+
+// AsError returns the Error in the chain, or creates one from the error.
+// Errors that do not wrap a sentinel are treated as bugs.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	e = &Error{Code: ERRBUG, Order: -1, Text: err.Error()}
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			e.Code = c.err
+			break
+		}
+	}
+	return e
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Text
+}
+
+// Unwrap returns the sentinel for the error.
+func (e *Error) Unwrap() error {
+	return e.Code
+}
+
+// ErrorCode returns the code for the error, for example "bad_request".
+func (e *Error) ErrorCode() string {
+	for _, c := range codes {
+		if e.Code == c.err {
+			return c.code
+		}
+	}
+	return "bug"
+}
+
+// ErrorTitle returns a short summary of the error that is the same for every error with the same code.
+func (e *Error) ErrorTitle() string {
+	return e.Code.Error()
+}
+
+// ErrorPointer returns the JSON pointer to the source of the error.
+func (e *Error) ErrorPointer() string {
+	return e.Pointer
+}
+
+// fieldError returns an Error that names the field of the order that
+// caused the error, or nil if err is nil. Order handlers use it so that
+// the pointer reported to the client ends at that field.
+func fieldError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := AsError(err)
+	return &Error{Code: e.Code, Order: e.Order, Field: field, Text: e.Text}
+}
+
+// orderError returns an Error that points at the order that failed and,
+// if the handler named one, at the field that caused it.
+func orderError(order *Order, err error) *Error {
+	e := AsError(err)
+	if e.Order != -1 {
+		return e
+	}
+	e = &Error{Code: e.Code, Order: order.index, Field: e.Field, Text: e.Text}
+	e.Pointer = fmt.Sprintf("/orders/%d/%s", order.index, order.kind())
+	if e.Field != "" {
+		e.Pointer += "/" + e.Field
+	}
+	return e
+}
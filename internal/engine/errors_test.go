@@ -0,0 +1,69 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_FieldError(t *testing.T) {
+	is := is.New(t)
+	err := fmt.Errorf("Give: %w", fieldError("target_id", fmt.Errorf("invalid target %q: %w", "tora", ERRBADREQUEST)))
+	is.True(errors.Is(err, ERRBADREQUEST)) // the sentinel is kept
+	e := AsError(err)
+	is.Equal(e.Field, "target_id")
+	is.Equal(e.Text, `invalid target "tora": invalid request`)
+	is.Equal(e.ErrorCode(), "bad_request")
+	is.NoErr(fieldError("target_id", nil)) // nil stays nil
+}
+
+func Test_OrderError(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		order   *Order
+		pointer string
+	}{
+		{"unknown target", &Order{Give: &Give{AssetID: "tosa", TargetID: "tora"}}, "/orders/1/give/target_id"},
+		{"unknown asset", &Order{Give: &Give{AssetID: "tora", TargetID: "tora"}}, "/orders/1/give/asset_id"},
+		{"target not quoted", &Order{Give: &Give{AssetID: "tosa", TargetID: "kuma"}}, "/orders/1/give/target_id"},
+		{"same value in two fields", &Order{ProbeOrbit: &ProbeOrbit{SourceID: "sanuki", TargetID: "sanuki", Orbit: 1}}, "/orders/1/probe_orbit/target_id"},
+		{"bad text", &Order{Note: &Note{TargetID: "tosa", Text: "\xff"}}, "/orders/1/note/text"},
+		{"no field to blame", &Order{LoadCargo: &LoadCargo{ColonyID: "tosa", ToID: "S1", Item: "METAL", TechLevel: 1, Quantity: 10}}, "/orders/1/load_cargo"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			mkrival(st, "kuma")
+			mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1")
+			st.ExecuteOrders(Orders{
+				(&Order{Debug: &Debug{}}).Stamp("usagi"),
+				tc.order.Stamp("usagi"),
+			}, false)
+			results, err := st.OrderResults("usagi")
+			is.NoErr(err)
+			is.Equal(len(results), 2)
+			is.Equal(results[1].Status, REJECTED)
+			is.Equal(results[1].Pointer, tc.pointer) // pointer
+			is.Equal(results[0].Pointer, "")         // orders that succeed have no pointer
+		})
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EventKind is the type of thing that happened during a turn.
+type EventKind int
+
+const (
+	NOTICE           EventKind = iota // anything else worth reporting to the polity
+	ORDERREJECTED                     // an order failed its checks
+	FOODPRODUCED                      // farms at a colony or on a ship produced food
+	STARVATION                        // a colony did not have enough food
+	SHIPJUMPED                        // a ship jumped between systems
+	SHIPMOVED                         // a ship moved between orbits
+	ASSETTRANSFERRED                  // a colony or ship changed hands
+	MESSAGEDELIVERED                  // a message arrived in a polity's inbox
+)
+
+// String implements the stringer interface
+func (k EventKind) String() string {
+	switch k {
+	case NOTICE:
+		return "notice"
+	case ORDERREJECTED:
+		return "order-rejected"
+	case FOODPRODUCED:
+		return "food-produced"
+	case STARVATION:
+		return "starvation"
+	case SHIPJUMPED:
+		return "ship-jumped"
+	case SHIPMOVED:
+		return "ship-moved"
+	case ASSETTRANSFERRED:
+		return "asset-transferred"
+	case MESSAGEDELIVERED:
+		return "message-delivered"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (k EventKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Event is something that happened during a turn. Reports, notifications,
+// and the API are all built from the events.
+type Event struct {
+	Turn     int       `json:"turn"`
+	Stage    string    `json:"stage"`
+	Kind     EventKind `json:"kind"`
+	Actor    string    `json:"actor"`              // id of the polity the event happened to
+	Entities []string  `json:"entities,omitempty"` // ids of the entities affected
+	Detail   string    `json:"detail"`
+}
+
+// eventLog holds the events for the current turn. The state and all of
+// its polities share a single log.
+type eventLog struct {
+	turn   int
+	stage  string // name of the stage that is running
+	events []Event
+}
+
+func mkeventLog() *eventLog {
+	return &eventLog{}
+}
+
+// emit adds an event for the current turn and stage.
+func (el *eventLog) emit(actor string, kind EventKind, entities []string, detail string) {
+	if el == nil {
+		return
+	}
+	el.events = append(el.events, Event{Turn: el.turn, Stage: el.stage, Kind: kind, Actor: actor, Entities: entities, Detail: detail})
+}
+
+// reset drops the events from the prior turn.
+func (el *eventLog) reset(turn int) {
+	el.turn, el.events = turn, nil
+}
+
+// eventf adds an event for the polity.
+func (p *Polity) eventf(kind EventKind, entities []string, format string, args ...interface{}) {
+	if p == nil {
+		return
+	}
+	p.events.emit(p.id, kind, entities, fmt.Sprintf(format, args...))
+}
+
+// journal returns the details of the polity's events for the current turn.
+func (p *Polity) journal() []string {
+	if p == nil || p.events == nil {
+		return nil
+	}
+	var lines []string
+	for _, e := range p.events.events {
+		if e.Actor == p.id {
+			lines = append(lines, e.Detail)
+		}
+	}
+	return lines
+}
+
+// reject marks the order as rejected, or skipped if it is not implemented,
+// and records an event for the polity that issued it. It returns the error
+// as an Error that points at the order so that it can still be collected
+// by the stage.
+func (st *State) reject(order *Order, err error) error {
+	if errors.Is(err, ERRNOTIMPLEMENTED) {
+		order.status, order.reason = SKIPPED, err.Error()
+	} else {
+		order.status, order.reason = REJECTED, err.Error()
+	}
+	st.events.emit(order.issuedBy, ORDERREJECTED, []string{order.ID}, fmt.Sprintf("order %s: %v", order.ID, err))
+	e := orderError(order, err)
+	order.pointer = e.Pointer
+	return e
+}
+
+// Events returns the events of the current turn for a polity.
+func (st *State) Events(polityID string) ([]Event, error) {
+	if st.Polity(polityID) == nil {
+		return nil, fmt.Errorf("invalid polity %q: %w", polityID, ERRNOTFOUND)
+	}
+	events := []Event{}
+	for _, e := range st.events.events {
+		if e.Actor == polityID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_Eventf(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	st.events.reset(4)
+	st.events.stage = "production"
+	usagi.eventf(FOODPRODUCED, []string{"sanuki"}, "%s: farms produced %d food", "C1", 10)
+	kuma.eventf(NOTICE, nil, "hello")
+	(*Polity)(nil).eventf(NOTICE, nil, "independents have no events")
+
+	events, err := st.Events("usagi")
+	is.NoErr(err)
+	is.Equal(events, []Event{{Turn: 4, Stage: "production", Kind: FOODPRODUCED, Actor: "usagi", Entities: []string{"sanuki"}, Detail: "C1: farms produced 10 food"}})
+	is.Equal(usagi.journal(), []string{"C1: farms produced 10 food"}) // journals are built from the events
+	is.Equal(kuma.journal(), []string{"hello"})
+
+	st.events.reset(5)
+	events, err = st.Events("usagi")
+	is.NoErr(err)
+	is.Equal(len(events), 0) // events are dropped at the start of a turn
+	is.Equal(len(usagi.journal()), 0)
+
+	_, err = st.Events("nobody")
+	is.True(errors.Is(err, ERRNOTFOUND))
+}
+
+func Test_EventKind(t *testing.T) {
+	for _, tc := range []struct {
+		kind EventKind
+		want string
+	}{
+		{NOTICE, "notice"},
+		{ORDERREJECTED, "order-rejected"},
+		{SHIPJUMPED, "ship-jumped"},
+		{MESSAGEDELIVERED, "message-delivered"},
+		{EventKind(99), "EventKind(99)"},
+	} {
+		is := is.New(t)
+		is.Equal(tc.kind.String(), tc.want)
+		text, err := tc.kind.MarshalText()
+		is.NoErr(err)
+		is.Equal(string(text), tc.want)
+	}
+}
+
+func Test_StageEvents(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	st.ExecuteOrders(nil, false)
+	events, err := st.Events("usagi")
+	is.NoErr(err)
+	stages := make(map[string]bool)
+	for _, e := range events {
+		is.Equal(e.Turn, st.turn)
+		stages[e.Stage] = true
+	}
+	is.True(stages["production"]) // farms at sanuki produce food
+	is.True(!stages[""])          // every event names its stage
+}
+
+func Test_StagesDoNotWriteToStdout(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	r, w, err := os.Pipe()
+	is.NoErr(err)
+	stdout := os.Stdout
+	os.Stdout = w
+	st.ExecuteOrders(nil, false)
+	os.Stdout = stdout
+	is.NoErr(w.Close())
+	out, err := ioutil.ReadAll(r)
+	is.NoErr(err)
+	is.Equal(string(out), "") // progress is reported with events
+}
@@ -26,70 +26,55 @@ import (
 )
 
 func (st *State) ExecuteOrders(orders Orders, debug bool) []error {
+	st.stampIDs(orders)
+	st.submitted = append(Orders{}, orders...)
 	orders.Prioritize()
 	sort.Stable(orders)
+	st.resolveNames(orders)
+	st.orders = orders
 
-	var errs []error
-	for _, err := range st.gameDataCleanupStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.adminStage(orders, debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.combatOrdersStage(debug) {
-		errs = append(errs, err)
+	stages := []struct {
+		name string
+		run  func(debug bool) []error
+	}{
+		{"gameDataCleanup", st.gameDataCleanupStage},
+		{"admin", func(debug bool) []error { return st.adminStage(orders, debug) }},
+		{"combatOrders", st.combatOrdersStage},
+		{"permissionOrders", st.permissionOrdersStage},
+		{"disassembly", st.disassemblyStage},
+		{"setup", st.setupStage},
+		{"transfer", st.transferStage},
+		{"draftOrders", st.draftOrdersStage},
+		{"assembly", st.assemblyStage},
+		{"buildChange", st.buildChangeStage},
+		{"surveysAndProbes", st.surveysAndProbesStage},
+		{"payChange", st.payChangeStage},
+		{"namingOrders", st.namingOrdersStage},
+		{"shipTravel", st.shipTravelStage},
+		{"probe", st.probeStage},
+		{"give", st.giveStage},
+		{"production", st.productionStage},
+		{"produceOutput", st.produceOutputStage},
+		{"sendOutput", st.sendOutputStage},
+		{"reset", st.resetStage},
 	}
-	for _, err := range st.permissionOrdersStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.disassemblyStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.setupStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.transferStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.draftOrdersStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.assemblyStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.buildChangeStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.surveysAndProbesStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.payChangeStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.namingOrdersStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.shipTravelStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.probeStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.giveStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.productionStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.produceOutputStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.sendOutputStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.resetStage(debug) {
-		errs = append(errs, err)
+
+	var errs []error
+	for _, stage := range stages {
+		st.events.stage = stage.name
+		for _, err := range stage.run(debug) {
+			errs = append(errs, err)
+		}
+		// in debug mode, check the state after each stage so that a broken
+		// invariant is reported against the stage that broke it.
+		if debug {
+			for _, err := range st.Verify() {
+				errs = append(errs, fmt.Errorf("%s: %w", stage.name, err))
+			}
+		}
 	}
+	st.events.stage = ""
+	st.settleOrders()
 	return errs
 }
 
@@ -109,22 +94,25 @@ func (st *State) adminStage(orders Orders, debug bool) []error {
 				log.Printf("[stage:%s] %4d createAdmin %q %q\n", stageName, i, order.issuedBy, order.CreateAdmin.ID)
 			}
 			for _, err := range st.CreateAdmin(order.issuedBy, order.CreateAdmin.ID) {
-				errs = append(errs, fmt.Errorf("CreateAdmin: %w", err))
+				errs = append(errs, st.reject(order, fmt.Errorf("CreateAdmin: %w", err)))
 			}
+			st.executed(order)
 		case order.CreatePolity != nil:
 			if debug {
 				log.Printf("[stage:%s] %4d createPolity %q %q\n", stageName, i, order.issuedBy, order.CreatePolity.Name)
 			}
 			for _, err := range st.CreatePolity(order.issuedBy, order.CreatePolity.ID, order.CreatePolity.Name) {
-				errs = append(errs, fmt.Errorf("CreatePolity: %w", err))
+				errs = append(errs, st.reject(order, fmt.Errorf("CreatePolity: %w", err)))
 			}
+			st.executed(order)
 		case order.CreateSystem != nil:
 			if debug {
 				log.Printf("[stage:%s] %4d createSystem %q %02d-%02d-%02d\n", stageName, i, order.issuedBy, order.CreateSystem.X, order.CreateSystem.Y, order.CreateSystem.Z)
 			}
 			for _, err := range st.CreateSystem(order.issuedBy, order.CreateSystem.ID, order.CreateSystem.X, order.CreateSystem.Y, order.CreateSystem.Z) {
-				errs = append(errs, fmt.Errorf("CreateSystem: %w", err))
+				errs = append(errs, st.reject(order, fmt.Errorf("CreateSystem: %w", err)))
 			}
+			st.executed(order)
 		}
 	}
 	return errs
@@ -133,6 +121,8 @@ func (st *State) adminStage(orders Orders, debug bool) []error {
 // Assembly Stage
 // = Order Processing Segment
 // == Assemble
+// == Design Ship
+// == Commission Ship
 // == Expend (Research Points only)
 // === Expending Research Points to Advance Technology Level (TL)
 // == Expend (using Prototypes)
@@ -154,9 +144,89 @@ func (st *State) assemblyStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.AssembleItem != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d assembleItem %v\n", stageName, i, *order.AssembleItem)
+			}
+			o := order.AssembleItem
+			if err := st.record(order, st.AssembleItem(order.issuedBy, o.SourceID, o.Quantity, o.Item, o.TechLevel)); err != nil {
+				errs = append(errs, fmt.Errorf("AssembleItem: %w", err))
+			}
+		case order.AssembleFactory != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d assembleFactory %v\n", stageName, i, *order.AssembleFactory)
+			}
+			o := order.AssembleFactory
+			if err := st.record(order, st.AssembleFactory(order.issuedBy, o.SourceID, o.Quantity, o.Item, o.TechLevel)); err != nil {
+				errs = append(errs, fmt.Errorf("AssembleFactory: %w", err))
+			}
+		case order.AssembleFactoryGroup != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d assembleFactoryGroup %v\n", stageName, i, *order.AssembleFactoryGroup)
+			}
+			o := order.AssembleFactoryGroup
+			if err := st.record(order, st.AssembleFactoryGroup(order.issuedBy, o.SourceID, o.Quantity, o.GroupID)); err != nil {
+				errs = append(errs, fmt.Errorf("AssembleFactoryGroup: %w", err))
+			}
+		case order.DesignShip != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d designShip %v\n", stageName, i, *order.DesignShip)
+			}
+			o := order.DesignShip
+			if err := st.record(order, st.DesignShip(order.issuedBy, o.Name, o.Parts, o.CargoHold)); err != nil {
+				errs = append(errs, fmt.Errorf("DesignShip: %w", err))
+			}
+		case order.CommissionShip != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d commissionShip %v\n", stageName, i, *order.CommissionShip)
+			}
+			o := order.CommissionShip
+			if err := st.record(order, st.CommissionShip(order.issuedBy, o.ColonyID, o.Design, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("CommissionShip: %w", err))
+			}
+		case order.ExpendResearchPointsOnly != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d expendResearchPointsOnly %v\n", stageName, i, *order.ExpendResearchPointsOnly)
+			}
+			o := order.ExpendResearchPointsOnly
+			if err := st.record(order, st.ExpendResearchPointsOnly(order.issuedBy, o.ColonyID, o.Quantity, o.Item)); err != nil {
+				errs = append(errs, fmt.Errorf("ExpendResearchPointsOnly: %w", err))
+			}
+		case order.ExpendPrototype != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d expendPrototype %v\n", stageName, i, *order.ExpendPrototype)
+			}
+			o := order.ExpendPrototype
+			if err := st.record(order, st.ExpendPrototype(order.issuedBy, o.ColonyID, o.Quantity, o.Item, o.TechLevel)); err != nil {
+				errs = append(errs, fmt.Errorf("ExpendPrototype: %w", err))
+			}
+		case order.FactoryGroupChange != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d factoryGroupChange %v\n", stageName, i, *order.FactoryGroupChange)
+			}
+			o := order.FactoryGroupChange
+			if err := st.record(order, st.FactoryGroupChange(order.issuedBy, o.ColonyID, o.FromID, o.ToID, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("FactoryGroupChange: %w", err))
+			}
+		case order.BuildChange != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d buildChange %v\n", stageName, i, *order.BuildChange)
+			}
+			o := order.BuildChange
+			if err := st.record(order, st.BuildChange(order.issuedBy, o.SourceID, o.GroupID, o.Item, o.TechLevel)); err != nil {
+				errs = append(errs, fmt.Errorf("BuildChange: %w", err))
+			}
+		case order.ExpendCommittedBufferResearchPoints != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d expendCommittedBufferResearchPoints %v\n", stageName, i, *order.ExpendCommittedBufferResearchPoints)
+			}
+			o := order.ExpendCommittedBufferResearchPoints
+			if err := st.record(order, st.ExpendCommittedBufferResearchPoints(order.issuedBy, o.ColonyID, o.Quantity, o.Item)); err != nil {
+				errs = append(errs, fmt.Errorf("ExpendCommittedBufferResearchPoints: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Build Change Stage
@@ -195,19 +265,34 @@ func (st *State) buildChangeStage(debug bool) []error {
 func (st *State) colonyProductionStage(debug bool) []error {
 	stageName := "colonyProduction"
 	var errs []error
-	for id, c := range st.colonies {
-		fmt.Printf("[stage:%s] colony %s %q\n", stageName, id, c.name)
-
-		// production
-		c.batteries.charged, c.batteries.used = 0, 0
+	// colonies may change hands during rebel actions, so use a fixed order to keep the results repeatable
+	var ids []string
+	for id := range st.colonies {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c := st.colonies[id]
+		// production, reduced by any unrest at the colony
+		modifier := c.productionModifier()
+		c.batteries.charged = 0
+		var food int
 		for _, unit := range c.units {
 			switch unit.Kind {
 			case FARM:
-				c.storage.food += unit.Produce().Quantity
+				food += int(float64(unit.Produce().Quantity) * modifier)
 			case POWER:
-				c.batteries.charged += unit.Produce().Quantity
+				c.batteries.charged += int(float64(unit.Produce().Quantity) * modifier)
 			}
 		}
+		if food != 0 {
+			c.polity.eventf(FOODPRODUCED, []string{c.id}, "%s: farms produced %s food", c.number, utils.Commas(food))
+		}
+
+		// laboratory production
+		if c.polity != nil {
+			c.polity.research.points += c.research()
+		}
 
 		// calculate food needed
 		minNeeded, maxNeeded := c.population.FoodNeededPerTurn()
@@ -217,7 +302,7 @@ func (st *State) colonyProductionStage(debug bool) []error {
 		}
 
 		// consume from production before taking from storage
-		var unitsProduced, unitsStored int
+		unitsProduced, unitsStored, unitsWanted := food, c.storage.food, unitsRationed
 		if unitsProduced >= unitsRationed {
 			unitsProduced, unitsRationed = unitsProduced-unitsRationed, 0
 		} else {
@@ -229,12 +314,25 @@ func (st *State) colonyProductionStage(debug bool) []error {
 			}
 		}
 
+		c.storage.food = unitsStored
 		if unitsRationed != 0 { // calculate deaths due to starvation
 		}
 		if unitsProduced != 0 { // move to storage, any excess is wasted.
+			c.storage.food += unitsProduced
+		}
+		// people starve when they eat less than the minimum they need
+		starving := unitsWanted-unitsRationed < minNeeded
+		if starving {
+			c.polity.eventf(STARVATION, []string{c.id}, "%s: not enough food for %s people", c.number, utils.Commas(c.population.total))
+		}
+
+		// rebel actions
+		c.unrest(starving)
+		if err := st.rebelActions(c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: colony %s: %w", stageName, c.id, err))
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Combat Orders Stage
@@ -296,7 +394,13 @@ func (st *State) combatOrdersStage(debug bool) []error {
 			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	for _, err := range st.spaceCombat(stageName, debug) {
+		errs = append(errs, err)
+	}
+	for _, err := range st.groundCombat(stageName, debug) {
+		errs = append(errs, err)
+	}
+	return errs
 }
 
 func (st *State) combineFactoryGroupStage(debug bool) []error {
@@ -309,9 +413,17 @@ func (st *State) combineFactoryGroupStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.CombineFactoryGroup != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d combineFactoryGroup %v\n", stageName, i, *order.CombineFactoryGroup)
+			}
+			o := order.CombineFactoryGroup
+			if err := st.record(order, st.CombineFactoryGroup(order.issuedBy, o.SourceID, o.FromGroupID, o.ToGroupID, o.WIPOnly, o.WIPQuarters)); err != nil {
+				errs = append(errs, fmt.Errorf("CombineFactoryGroup: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) disassembleStage(debug bool) []error {
@@ -324,9 +436,17 @@ func (st *State) disassembleStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Disassemble != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d disassemble %v\n", stageName, i, *order.Disassemble)
+			}
+			o := order.Disassemble
+			if err := st.record(order, st.Disassemble(order.issuedBy, o.SourceID, o.Item, o.TechLevel, o.GroupID, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("Disassemble: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Disassembly Stage
@@ -361,9 +481,17 @@ func (st *State) disbandStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Disband != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d disband %v\n", stageName, i, *order.Disband)
+			}
+			o := order.Disband
+			if err := st.record(order, st.Disband(order.issuedBy, o.SourceID, o.PopulationType, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("Disband: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) draftStage(debug bool) []error {
@@ -376,22 +504,37 @@ func (st *State) draftStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Draft != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d draft %v\n", stageName, i, *order.Draft)
+			}
+			o := order.Draft
+			if err := st.record(order, st.Draft(order.issuedBy, o.SourceID, o.PopulationType, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("Draft: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Draft Orders Stage
 func (st *State) draftOrdersStage(debug bool) []error {
 	stageName := "draftOrders"
 	var errs []error
+	// people drafted on earlier turns finish their training before new drafts
+	for _, colony := range st.colonies {
+		colony.graduate()
+		if debug {
+			log.Printf("[stage:%s] colony %s training %d\n", stageName, colony.id, len(colony.training))
+		}
+	}
 	for _, err := range st.draftStage(debug) {
 		errs = append(errs, err)
 	}
 	for _, err := range st.disbandStage(debug) {
 		errs = append(errs, err)
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Game Data Cleanup Stage
@@ -407,13 +550,20 @@ func (st *State) gameDataCleanupStage(debug bool) []error {
 			}
 		}
 	}
+	// reset the events for the new turn
+	st.events.reset(st.turn)
 	// reset colonies
 	for _, colony := range st.colonies {
-		fmt.Printf("[stage:%s] colony %s %q\n", stageName, colony.id, colony.name)
+		colony.constructionUsed = 0
+		colony.batteries = batteries{}
+		colony.transports = transports{}
 	}
 	// reset ships
 	for _, ship := range st.ships {
-		fmt.Printf("[stage:%s] ship %s %q\n", stageName, ship.id, ship.name)
+		ship.constructionUsed = 0
+		ship.batteries = batteries{}
+		ship.transports = transports{}
+		ship.colonize = nil
 	}
 	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
 }
@@ -433,17 +583,18 @@ func (st *State) giveStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d give %v\n", stageName, i, *order.Give)
 			}
-			if err := st.Give(order.issuedBy, order.Give.AssetID, order.Give.TargetID); err != nil {
-				errs = append(errs, err)
+			if err := st.record(order, st.Give(order.issuedBy, order.Give.AssetID, order.Give.TargetID)); err != nil {
+				errs = append(errs, fmt.Errorf("Give: %w", err))
 			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) jumpStage(debug bool) []error {
 	stageName := "jump"
 	var errs []error
+	jumped := make(map[string]bool) // ships may only jump once per turn
 	for i, order := range st.orders {
 		switch {
 		case order.Debug != nil:
@@ -451,9 +602,21 @@ func (st *State) jumpStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Jump != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d jump %v\n", stageName, i, *order.Jump)
+			}
+			o := order.Jump
+			if jumped[o.ShipID] {
+				errs = append(errs, fmt.Errorf("Jump: %w", st.reject(order, fieldError("ship_id", fmt.Errorf("ship %q has already jumped: %w", o.ShipID, ERRBADREQUEST)))))
+			} else if err := st.record(order, st.Jump(order.issuedBy, o.ShipID, o.Coords, o.Offset)); err != nil {
+				errs = append(errs, fmt.Errorf("Jump: %w", err))
+			} else {
+				jumped[o.ShipID] = true
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) junkStage(debug bool) []error {
@@ -474,7 +637,24 @@ func (st *State) junkStage(debug bool) []error {
 func (st *State) loadCargoStage(debug bool) []error {
 	stageName := "loadCargo"
 	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	for i, order := range st.orders {
+		switch {
+		case order.Debug != nil:
+			debug = order.Debug.On
+			if debug {
+				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
+			}
+		case order.LoadCargo != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d loadCargo %v\n", stageName, i, *order.LoadCargo)
+			}
+			o := order.LoadCargo
+			if err := st.record(order, st.LoadCargo(order.issuedBy, o.ColonyID, o.ToID, o.Item, o.TechLevel, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("LoadCargo: %w", err))
+			}
+		}
+	}
+	return errs
 }
 
 func (st *State) mergeStage(debug bool) []error {
@@ -495,6 +675,7 @@ func (st *State) mergeStage(debug bool) []error {
 func (st *State) moveStage(debug bool) []error {
 	stageName := "move"
 	var errs []error
+	moved := make(map[string]bool) // ships may only be given one move per turn
 	for i, order := range st.orders {
 		switch {
 		case order.Debug != nil:
@@ -502,9 +683,35 @@ func (st *State) moveStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Move != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d move %v\n", stageName, i, *order.Move)
+			}
+			o := order.Move
+			if moved[o.ShipID] {
+				errs = append(errs, fmt.Errorf("Move: %w", st.reject(order, fieldError("ship_id", fmt.Errorf("ship %q has already moved: %w", o.ShipID, ERRBADREQUEST)))))
+				continue
+			}
+			moved[o.ShipID] = true
+			if err := st.record(order, st.Move(order.issuedBy, o.ShipID, o.Orbit, o.Offset)); err != nil {
+				errs = append(errs, fmt.Errorf("Move: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	// continue moves from earlier turns, in a fixed order so that events are repeatable
+	var ids []string
+	for id, ship := range st.ships {
+		if ship.moving != nil && !moved[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := st.continueMove(st.ships[id]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", stageName, err))
+		}
+	}
+	return errs
 }
 
 // Naming Orders Stage
@@ -523,9 +730,45 @@ func (st *State) namingOrdersStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Name != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d name %v\n", stageName, i, *order.Name)
+			}
+			o := order.Name
+			if err := st.record(order, st.Name(order.issuedBy, o.EntityID, o.Type, o.Name)); err != nil {
+				errs = append(errs, fmt.Errorf("Name: %w", err))
+			}
+		case order.Note != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d note %v\n", stageName, i, *order.Note)
+			}
+			o := order.Note
+			if text, err := NewText(o.Text); err != nil {
+				errs = append(errs, fmt.Errorf("Note: %w", st.reject(order, fieldError("text", err))))
+			} else if err := st.record(order, st.Note(order.issuedBy, o.TargetID, text)); err != nil {
+				errs = append(errs, fmt.Errorf("Note: %w", err))
+			}
+		case order.ShareNames != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d shareNames %v\n", stageName, i, *order.ShareNames)
+			}
+			o := order.ShareNames
+			if err := st.record(order, st.ShareNames(order.issuedBy, o.PolityID)); err != nil {
+				errs = append(errs, fmt.Errorf("ShareNames: %w", err))
+			}
+		case order.Message != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d message %v\n", stageName, i, *order.Message)
+			}
+			o := order.Message
+			if text, err := NewText(o.Text); err != nil {
+				errs = append(errs, fmt.Errorf("Message: %w", st.reject(order, fieldError("text", err))))
+			} else if err := st.record(order, st.Message(order.issuedBy, o.SourceID, o.TargetID, text)); err != nil {
+				errs = append(errs, fmt.Errorf("Message: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) payStage(debug bool) []error {
@@ -560,6 +803,8 @@ func (st *State) payChangeStage(debug bool) []error {
 // = Permission to Colonize
 // = Home Port Change
 // = Diplomacy
+// = Appoint Viceroy
+// = Dismiss Viceroy
 func (st *State) permissionOrdersStage(debug bool) []error {
 	stageName := "permissionOrders"
 	var errs []error
@@ -570,15 +815,80 @@ func (st *State) permissionOrdersStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.PermissionToColonize != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d permissionToColonize %v\n", stageName, i, *order.PermissionToColonize)
+			}
+			o := order.PermissionToColonize
+			if err := st.record(order, st.PermissionToColonize(order.issuedBy, o.PlanetID, o.ShipID)); err != nil {
+				errs = append(errs, fmt.Errorf("PermissionToColonize: %w", err))
+			}
+		case order.HomePortChange != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d homePortChange %v\n", stageName, i, *order.HomePortChange)
+			}
+			o := order.HomePortChange
+			if err := st.record(order, st.HomePortChange(order.issuedBy, o.ShipID, o.ColonyID)); err != nil {
+				errs = append(errs, fmt.Errorf("HomePortChange: %w", err))
+			}
+		case order.Diplomacy != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d diplomacy %v\n", stageName, i, *order.Diplomacy)
+			}
+			o := order.Diplomacy
+			if err := st.record(order, st.Diplomacy(order.issuedBy, o.PolityID, o.Status)); err != nil {
+				errs = append(errs, fmt.Errorf("Diplomacy: %w", err))
+			}
+		case order.AppointViceroy != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d appointViceroy %v\n", stageName, i, *order.AppointViceroy)
+			}
+			o := order.AppointViceroy
+			if err := st.record(order, st.AppointViceroy(order.issuedBy, o.PolityID)); err != nil {
+				errs = append(errs, fmt.Errorf("AppointViceroy: %w", err))
+			}
+		case order.DismissViceroy != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d dismissViceroy %v\n", stageName, i, *order.DismissViceroy)
+			}
+			o := order.DismissViceroy
+			if err := st.record(order, st.DismissViceroy(order.issuedBy, o.PolityID)); err != nil {
+				errs = append(errs, fmt.Errorf("DismissViceroy: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) pickupStage(debug bool) []error {
 	stageName := "pickup"
 	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	for i, order := range st.orders {
+		switch {
+		case order.Debug != nil:
+			debug = order.Debug.On
+			if debug {
+				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
+			}
+		case order.PickUpItem != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d pickUpItem %v\n", stageName, i, *order.PickUpItem)
+			}
+			o := order.PickUpItem
+			if err := st.record(order, st.PickUpItem(order.issuedBy, o.SourceID, o.ToID, o.Item, o.TechLevel, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("PickUpItem: %w", err))
+			}
+		case order.PickUpPopulation != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d pickUpPopulation %v\n", stageName, i, *order.PickUpPopulation)
+			}
+			o := order.PickUpPopulation
+			if err := st.record(order, st.PickUpPopulation(order.issuedBy, o.SourceID, o.ToID, o.PopulationType, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("PickUpPopulation: %w", err))
+			}
+		}
+	}
+	return errs
 }
 
 // Probe Stage
@@ -596,12 +906,31 @@ func (st *State) probeStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.ProbeOrbit != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d probeOrbit %v\n", stageName, i, *order.ProbeOrbit)
+			}
+			o := order.ProbeOrbit
+			if err := st.record(order, st.ProbeOrbit(order.issuedBy, o.SourceID, o.TargetID, o.Orbit)); err != nil {
+				errs = append(errs, fmt.Errorf("ProbeOrbit: %w", err))
+			}
+		case order.ProbeSystem != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d probeSystem %v\n", stageName, i, *order.ProbeSystem)
+			}
+			o := order.ProbeSystem
+			if err := st.record(order, st.ProbeSystem(order.issuedBy, o.SourceID, o.TargetID, o.Magnitude)); err != nil {
+				errs = append(errs, fmt.Errorf("ProbeSystem: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Produce Output Stage
+// Runs the factory groups at every colony and ship.
+// Finished items leave the work-in-progress pipeline and are delivered
+// to storage, then new work is started with the inputs that remain.
 func (st *State) produceOutputStage(debug bool) []error {
 	stageName := "produceOutput"
 	var errs []error
@@ -614,12 +943,23 @@ func (st *State) produceOutputStage(debug bool) []error {
 			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	for _, c := range st.colonies {
+		if debug {
+			log.Printf("[stage:%s] colony %s %q\n", stageName, c.id, c.name)
+		}
+		c.depot().runFactories()
+	}
+	for _, s := range st.ships {
+		if debug {
+			log.Printf("[stage:%s] ship %s %q\n", stageName, s.id, s.name)
+		}
+		s.depot().runFactories()
+	}
+	return errs
 }
 
 // Production Stage
 func (st *State) productionStage(debug bool) []error {
-	stageName := "production"
 	var errs []error
 	for _, err := range st.colonyProductionStage(debug) {
 		errs = append(errs, err)
@@ -627,7 +967,7 @@ func (st *State) productionStage(debug bool) []error {
 	for _, err := range st.shipProductionStage(debug) {
 		errs = append(errs, err)
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) rationStage(debug bool) []error {
@@ -649,17 +989,6 @@ func (st *State) rationStage(debug bool) []error {
 func (st *State) resetStage(debug bool) []error {
 	stageName := "resetCleanup"
 	var errs []error
-	// reset colonies
-	for _, colony := range st.colonies {
-		fmt.Printf("[stage:%s] colony %s %q\n", stageName, colony.id, colony.name)
-		if colony.batteries.used != 0 {
-			fmt.Printf("  > (reset (batteries %s))\n", utils.Commas(colony.batteries.charged-colony.batteries.used))
-		}
-	}
-	// reset ships
-	for _, ship := range st.ships {
-		fmt.Printf("[stage:%s] ship %s %q\n", stageName, ship.id, ship.name)
-	}
 	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
 }
 
@@ -673,9 +1002,17 @@ func (st *State) scrapStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Scrap != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d scrap %v\n", stageName, i, *order.Scrap)
+			}
+			o := order.Scrap
+			if err := st.record(order, st.Scrap(order.issuedBy, o.ActorID, o.Item, o.TechLevel, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("Scrap: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Send Output Stage
@@ -691,7 +1028,9 @@ func (st *State) sendOutputStage(debug bool) []error {
 			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	st.shareIntel()
+	st.deliverMessages()
+	return errs
 }
 
 // Setup Stage
@@ -727,9 +1066,33 @@ func (st *State) setupStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.DefineCargoHold != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d defineCargoHold %v\n", stageName, i, *order.DefineCargoHold)
+			}
+			o := order.DefineCargoHold
+			if err := st.record(order, st.DefineCargoHold(order.issuedBy, o.ShipID, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("DefineCargoHold: %w", err))
+			}
+		case order.SetUp != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d setUp %v\n", stageName, i, *order.SetUp)
+			}
+			o := order.SetUp
+			if err := st.record(order, st.SetUp(order.issuedBy, o.SourceID, o.TypeOfColony, o.Quantity, o.Items)); err != nil {
+				errs = append(errs, fmt.Errorf("SetUp: %w", err))
+			}
+		case order.AddOn != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d addOn %v\n", stageName, i, *order.AddOn)
+			}
+			o := order.AddOn
+			if err := st.record(order, st.AddOn(order.issuedBy, o.SourceID, o.TargetID, o.Item, o.TechLevel, o.Quantity, o.DoNotAssemble)); err != nil {
+				errs = append(errs, fmt.Errorf("AddOn: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // shipProductionStage calculates production and consumption for all ships.
@@ -746,12 +1109,24 @@ func (st *State) shipProductionStage(debug bool) []error {
 	stageName := "shipProduction"
 	var errs []error
 	for _, ship := range st.ships {
-		log.Printf("[stage:%s] ship %q\n", stageName, ship.name)
+		if debug {
+			log.Printf("[stage:%s] ship %q\n", stageName, ship.name)
+		}
 
-		// farm production
+		// power and farm production
 		var unitsProduced, unitsStored int
-		for _, farm := range ship.units.farms {
-			unitsProduced += farm.Produce()
+		ship.batteries.charged = 0
+		for _, unit := range ship.units {
+			switch unit.Kind {
+			case FARM:
+				unitsProduced += unit.Produce().Quantity
+			case POWER:
+				ship.batteries.charged += unit.Produce().Quantity
+			}
+		}
+
+		if unitsProduced != 0 {
+			ship.polity.eventf(FOODPRODUCED, []string{ship.id}, "%s: farms produced %s food", ship.number, utils.Commas(unitsProduced))
 		}
 
 		// calculate food needed
@@ -781,7 +1156,7 @@ func (st *State) shipProductionStage(debug bool) []error {
 		// rebel actions
 		// population changes
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func farmProductionStep() {
@@ -790,7 +1165,6 @@ func farmProductionStep() {
 
 // Ship Travel Stage
 func (st *State) shipTravelStage(debug bool) []error {
-	stageName := "shipTravel"
 	var errs []error
 	for _, err := range st.jumpStage(debug) {
 		errs = append(errs, err)
@@ -798,13 +1172,7 @@ func (st *State) shipTravelStage(debug bool) []error {
 	for _, err := range st.moveStage(debug) {
 		errs = append(errs, err)
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
-}
-
-func (st *State) shortagesStage(debug bool) []error {
-	stageName := "shortages"
-	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Surveys and Probes Stage
@@ -826,9 +1194,33 @@ func (st *State) surveysAndProbesStage(debug bool) []error {
 			if debug {
 				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
 			}
+		case order.Probe != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d probe %v\n", stageName, i, *order.Probe)
+			}
+			o := order.Probe
+			if err := st.record(order, st.Probe(order.issuedBy, o.SourceID, o.TargetID)); err != nil {
+				errs = append(errs, fmt.Errorf("Probe: %w", err))
+			}
+		case order.Survey != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d survey %v\n", stageName, i, *order.Survey)
+			}
+			o := order.Survey
+			if err := st.record(order, st.Survey(order.issuedBy, o.SourceID, o.PlanetID)); err != nil {
+				errs = append(errs, fmt.Errorf("Survey: %w", err))
+			}
+		case order.LaunchRobotProbe != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d launchRobotProbe %v\n", stageName, i, *order.LaunchRobotProbe)
+			}
+			o := order.LaunchRobotProbe
+			if err := st.record(order, st.LaunchRobotProbe(order.issuedBy, o.SourceID, o.Type, o.Coords, o.StarLetter, o.Orbit)); err != nil {
+				errs = append(errs, fmt.Errorf("LaunchRobotProbe: %w", err))
+			}
 		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 // Transfer Stage
@@ -842,8 +1234,10 @@ func (st *State) surveysAndProbesStage(debug bool) []error {
 // = Pick Up
 // = Load Cargo
 func (st *State) transferStage(debug bool) []error {
-	stageName := "transfer"
 	var errs []error
+	for _, err := range st.transportCapacityStage(debug) {
+		errs = append(errs, err)
+	}
 	for _, err := range st.unloadCargoStage(debug) {
 		errs = append(errs, err)
 	}
@@ -856,56 +1250,69 @@ func (st *State) transferStage(debug bool) []error {
 	for _, err := range st.loadCargoStage(debug) {
 		errs = append(errs, err)
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
-}
-
-func (st *State) transferAndPickupStage(debug bool) []error {
-	stageName := "transferAndPickup"
-	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) transferUnitsStage(debug bool) []error {
 	stageName := "transferUnits"
 	var errs []error
-	for _, err := range st.transferAndPickupStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.transportsStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.transportCapacityStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.transferOrPickupTransportsStage(debug) {
-		errs = append(errs, err)
-	}
-	for _, err := range st.shortagesStage(debug) {
-		errs = append(errs, err)
+	for i, order := range st.orders {
+		switch {
+		case order.Debug != nil:
+			debug = order.Debug.On
+			if debug {
+				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
+			}
+		case order.Transfer != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d transfer %v\n", stageName, i, *order.Transfer)
+			}
+			o := order.Transfer
+			if err := st.record(order, st.Transfer(order.issuedBy, o.SourceID, o.ToID, o.Item, o.TechLevel, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("Transfer: %w", err))
+			}
+		}
 	}
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
-}
-
-func (st *State) transferOrPickupTransportsStage(debug bool) []error {
-	stageName := "transferOrPickupTransports"
-	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	return errs
 }
 
 func (st *State) transportCapacityStage(debug bool) []error {
 	stageName := "transportCapacity"
 	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
-}
-
-func (st *State) transportsStage(debug bool) []error {
-	stageName := "transports"
-	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	for _, colony := range st.colonies {
+		colony.transports = transports{capacity: colony.depot().transportCapacity()}
+		if debug {
+			log.Printf("[stage:%s] colony %s %v\n", stageName, colony.id, colony.transports)
+		}
+	}
+	for _, ship := range st.ships {
+		ship.transports = transports{capacity: ship.depot().transportCapacity()}
+		if debug {
+			log.Printf("[stage:%s] ship %s %v\n", stageName, ship.id, ship.transports)
+		}
+	}
+	return errs
 }
 
 func (st *State) unloadCargoStage(debug bool) []error {
 	stageName := "unloadCargo"
 	var errs []error
-	return append(errs, fmt.Errorf("%s: %w", stageName, ERRNOTIMPLEMENTED))
+	for i, order := range st.orders {
+		switch {
+		case order.Debug != nil:
+			debug = order.Debug.On
+			if debug {
+				log.Printf("[stage:%s] %4d debug %v\n", stageName, i, *order.Debug)
+			}
+		case order.UnloadCargo != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d unloadCargo %v\n", stageName, i, *order.UnloadCargo)
+			}
+			o := order.UnloadCargo
+			if err := st.record(order, st.UnloadCargo(order.issuedBy, o.ColonyID, o.ShipID, o.Item, o.TechLevel, o.Quantity)); err != nil {
+				errs = append(errs, fmt.Errorf("UnloadCargo: %w", err))
+			}
+		}
+	}
+	return errs
 }
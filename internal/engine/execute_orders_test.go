@@ -0,0 +1,50 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func Test_ImplementedStages(t *testing.T) {
+	st, _ := Make()
+	errs := st.ExecuteOrders(nil, false)
+	for _, stage := range []string{
+		"assembly",
+		"colonyProduction",
+		"combatOrders",
+		"give",
+		"namingOrders",
+		"shipProduction",
+		"shipTravel",
+		"production",
+	} {
+		t.Run(stage, func(t *testing.T) {
+			is := is.New(t)
+			for _, err := range errs {
+				if errors.Is(err, ERRNOTIMPLEMENTED) {
+					is.True(!strings.HasPrefix(err.Error(), stage+":")) // stage is implemented
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+	"strings"
+)
+
+// factoryQuarters is the number of turns an item spends in the
+// work-in-progress pipeline before it is delivered to storage.
+const factoryQuarters = 3
+
+// FactoryGroup is a set of factory units that manufacture a single item.
+// Items move through a pipeline of work-in-progress (WIP), one quarter
+// per turn, and are delivered to storage when they leave the pipeline.
+type FactoryGroup struct {
+	id     string // group number, unique within the colony or ship
+	units  Unit   // the assembled factory units in the group
+	builds Unit   // kind and tech level of the item being manufactured
+	wip    [factoryQuarters]int
+}
+
+// capacity returns the amount of metals and non-metals that the group can
+// process in a single turn.
+func (g *FactoryGroup) capacity() int {
+	return 10 * g.units.TechLevel * g.units.Quantity
+}
+
+// inputs returns the power and professionals needed to run the group for a turn.
+// Each factory unit needs power equal to its tech level and every
+// hundred factory units needs one unit of professionals.
+func (g *FactoryGroup) inputs() (power, professionals int) {
+	return g.units.TechLevel * g.units.Quantity, (g.units.Quantity + 99) / 100
+}
+
+// isIdle returns true if the group has no factories and no work in progress.
+func (g *FactoryGroup) isIdle() bool {
+	if g.units.Quantity != 0 {
+		return false
+	}
+	for _, qty := range g.wip {
+		if qty != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Sexpr implements the sexpr interface
+func (g *FactoryGroup) Sexpr() string {
+	var wip []string
+	for _, qty := range g.wip {
+		wip = append(wip, utils.Commas(qty))
+	}
+	return fmt.Sprintf("(factory-group (id %q) (tl %d) (qty %s) (builds %s) (wip %s))", g.id, g.units.TechLevel, utils.Commas(g.units.Quantity), g.builds.String(), strings.Join(wip, " "))
+}
+
+// factoryGroup returns the group with the given id, or nil if there is no such group.
+func (d depot) factoryGroup(id string) *FactoryGroup {
+	for _, g := range *d.factories {
+		if g.id == id {
+			return g
+		}
+	}
+	return nil
+}
+
+// removeIdleFactoryGroup removes the group from the depot if it has
+// no factories and no work in progress.
+func (d depot) removeIdleFactoryGroup(group *FactoryGroup) {
+	if !group.isIdle() {
+		return
+	}
+	for i, g := range *d.factories {
+		if g == group {
+			*d.factories = append((*d.factories)[:i], (*d.factories)[i+1:]...)
+			return
+		}
+	}
+}
+
+// nextFactoryGroupID returns an id that isn't used by any group in the depot.
+func (d depot) nextFactoryGroupID() string {
+	for n := len(*d.factories) + 1; ; n++ {
+		if id := fmt.Sprintf("F%d", n); d.factoryGroup(id) == nil {
+			return id
+		}
+	}
+}
+
+// manufacturable returns the item if factories are able to build it.
+// Raw resources are mined, not manufactured.
+func manufacturable(item string, techLevel int) (Unit, error) {
+	kind, ok := unitKindFromString(item)
+	if !ok {
+		return Unit{}, fieldError("item", fmt.Errorf("invalid item %q: %w", item, ERRBADREQUEST))
+	} else if techLevel < 1 {
+		return Unit{}, fieldError("tech_level", fmt.Errorf("invalid tech level %d: %w", techLevel, ERRBADREQUEST))
+	}
+	u := Unit{Kind: kind, TechLevel: techLevel, Quantity: 1}
+	if metals, nonMetals := u.Materials(); metals+nonMetals <= 0 {
+		return Unit{}, fieldError("item", fmt.Errorf("item %q can not be manufactured: %w", item, ERRBADREQUEST))
+	}
+	u.Quantity = 0
+	return u, nil
+}
+
+// AssembleFactory creates a new factory group from unassembled factory
+// units in storage and sets it to manufacture the item.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. Quantity must be greater than zero.
+// 3. Item must be something that factories can manufacture.
+// 4. Factory units are taken from storage, highest tech level first,
+// skipping any above the polity's tech level for factories.
+// 5. Assembly needs construction workers and power (see AssembleItem).
+// 6. Quantity may exceed the number in storage or the labor and power
+// available; the overage is ignored and the order is partially executed.
+// The order is rejected if no factories can be assembled.
+func (st *State) AssembleFactory(issuedByID, sourceID string, quantity int, item string, techLevel int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.AssembleFactory: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	builds, err := manufacturable(item, techLevel)
+	if err != nil {
+		return err
+	} else if err = d.polity().canManufacture(builds); err != nil {
+		return fieldError("tech_level", err)
+	}
+
+	// factories in a group must share a tech level, so use the highest available
+	var factoryTechLevel int
+	for _, u := range *d.units {
+		if u.Kind == FACTORY && !u.Assembled && u.Quantity != 0 && u.TechLevel > factoryTechLevel && d.polity().canAssemble(u) == nil {
+			factoryTechLevel = u.TechLevel
+		}
+	}
+	if factoryTechLevel == 0 {
+		return fieldError("quantity", fmt.Errorf("no factory units in storage: %w", ERRBADREQUEST))
+	}
+
+	units := Unit{Kind: FACTORY, TechLevel: factoryTechLevel}
+	units.Quantity = d.assemblyLimit(units, minInt(quantity, d.countUnit(FACTORY, factoryTechLevel, false)))
+	if units.Quantity == 0 {
+		return fmt.Errorf("not enough labor or power to assemble factories: %w", ERRBADREQUEST)
+	}
+	d.useAssembly(units)
+
+	group := &FactoryGroup{
+		id:     d.nextFactoryGroupID(),
+		units:  Unit{Kind: FACTORY, TechLevel: factoryTechLevel, Assembled: true},
+		builds: builds,
+	}
+	group.units.Quantity = d.removeUnit(FACTORY, factoryTechLevel, false, units.Quantity)
+	*d.factories = append(*d.factories, group)
+	d.polity().logf("%s: assembled factory group %s (%s units) to build %s", sourceID, group.id, utils.Commas(group.units.Quantity), builds)
+	if group.units.Quantity < quantity {
+		return fmt.Errorf("assembled %d of %d: %w", group.units.Quantity, quantity, ERRPARTIAL)
+	}
+	return nil
+}
+
+// AssembleFactoryGroup adds unassembled factory units from storage to an existing group.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. The group must exist at the source.
+// 3. Factory units must be the same tech level as the group.
+// 4. Assembly needs construction workers and power (see AssembleItem).
+// 5. Quantity may exceed the number in storage or the labor and power
+// available; the overage is ignored and reported.
+func (st *State) AssembleFactoryGroup(issuedByID, sourceID string, quantity int, groupID string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.AssembleFactoryGroup: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	group := d.factoryGroup(groupID)
+	if group == nil {
+		return fieldError("group_id", fmt.Errorf("invalid group %q: %w", groupID, ERRBADREQUEST))
+	}
+	units := Unit{Kind: FACTORY, TechLevel: group.units.TechLevel}
+	if err := d.polity().canAssemble(units); err != nil {
+		return fieldError("group_id", err)
+	}
+	units.Quantity = minInt(quantity, d.countUnit(FACTORY, group.units.TechLevel, false))
+	if units.Quantity == 0 {
+		return fieldError("quantity", fmt.Errorf("no %s units in storage: %w", group.units, ERRBADREQUEST))
+	}
+	if units.Quantity = d.assemblyLimit(units, units.Quantity); units.Quantity == 0 {
+		return fmt.Errorf("not enough labor or power to assemble %s for group %s: %w", units, group.id, ERRBADREQUEST)
+	}
+	d.useAssembly(units)
+	group.units.Quantity += d.removeUnit(FACTORY, group.units.TechLevel, false, units.Quantity)
+	if units.Quantity < quantity {
+		d.polity().logf("%s: assembled %s of %s %s for group %s (not enough units, labor, or power)", sourceID, utils.Commas(units.Quantity), utils.Commas(quantity), units, group.id)
+		return fmt.Errorf("assembled %d of %d: %w", units.Quantity, quantity, ERRPARTIAL)
+	}
+	d.polity().logf("%s: assembled %s %s for group %s", sourceID, utils.Commas(units.Quantity), units, group.id)
+	return nil
+}
+
+// BuildChange retools a factory group to manufacture a different item.
+// Retooling scraps all of the group's work in progress.
+func (st *State) BuildChange(issuedByID, sourceID, groupID, item string, techLevel int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.BuildChange: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	group := d.factoryGroup(groupID)
+	if group == nil {
+		return fieldError("group_id", fmt.Errorf("invalid group %q: %w", groupID, ERRBADREQUEST))
+	}
+	builds, err := manufacturable(item, techLevel)
+	if err != nil {
+		return err
+	} else if err = d.polity().canManufacture(builds); err != nil {
+		return fieldError("tech_level", err)
+	}
+	if builds.Kind == group.builds.Kind && builds.TechLevel == group.builds.TechLevel {
+		return nil // nothing to change
+	}
+	var lost int
+	for i, qty := range group.wip {
+		lost, group.wip[i] = lost+qty, 0
+	}
+	group.builds = builds
+	d.polity().logf("%s: factory group %s retooled to build %s (%s items of work in progress lost)", sourceID, group.id, builds, utils.Commas(lost))
+	return nil
+}
+
+// CombineFactoryGroup moves factories and work in progress from one group to another.
+//
+// 1. Both groups must exist at the source and must be building the same item.
+// 2. If WIPOnly is set, only work in progress is moved.
+// 3. If quarters are given, only work in progress from those quarters (1 is the newest) is moved.
+// 4. Factories can only be moved between groups of the same tech level.
+// 5. The from group is removed once it has no factories and no work in progress.
+func (st *State) CombineFactoryGroup(issuedByID, sourceID, fromID, toID string, wipOnly bool, quarters []int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.CombineFactoryGroup: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	from, to := d.factoryGroup(fromID), d.factoryGroup(toID)
+	if from == nil {
+		return fieldError("from_group_id", fmt.Errorf("invalid group %q: %w", fromID, ERRBADREQUEST))
+	} else if to == nil {
+		return fieldError("to_group_id", fmt.Errorf("invalid group %q: %w", toID, ERRBADREQUEST))
+	} else if from == to {
+		return fieldError("to_group_id", fmt.Errorf("can't combine group with itself: %w", ERRBADREQUEST))
+	} else if from.builds.Kind != to.builds.Kind || from.builds.TechLevel != to.builds.TechLevel {
+		return fieldError("to_group_id", fmt.Errorf("groups must build the same item: %w", ERRBADREQUEST))
+	} else if !wipOnly && from.units.TechLevel != to.units.TechLevel {
+		return fieldError("to_group_id", fmt.Errorf("groups must be the same tech level: %w", ERRBADREQUEST))
+	}
+
+	if len(quarters) == 0 {
+		for q := 1; q <= factoryQuarters; q++ {
+			quarters = append(quarters, q)
+		}
+	}
+	for _, q := range quarters {
+		if q < 1 || q > factoryQuarters {
+			return fieldError("wip_quarters", fmt.Errorf("invalid quarter %d: %w", q, ERRBADREQUEST))
+		}
+	}
+	for _, q := range quarters {
+		to.wip[q-1], from.wip[q-1] = to.wip[q-1]+from.wip[q-1], 0
+	}
+	if !wipOnly {
+		to.units.Quantity, from.units.Quantity = to.units.Quantity+from.units.Quantity, 0
+	}
+
+	d.removeIdleFactoryGroup(from)
+	return nil
+}
+
+// FactoryGroupChange moves factory units from one group to another.
+//
+// 1. Colony identified by ColonyID must accept orders from the polity issuing the order.
+// 2. Both groups must exist at the colony and must not be the same group.
+// 3. Factories can only be moved between groups of the same tech level.
+// 4. Work in progress stays with the group that started it.
+// 5. Quantity may exceed the factories in the from group; the overage is
+// ignored and reported.
+func (st *State) FactoryGroupChange(issuedByID, colonyID, fromID, toID string, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.FactoryGroupChange: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, colonyID)
+	if err != nil {
+		return fieldError("colony_id", err)
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	from, to := d.factoryGroup(fromID), d.factoryGroup(toID)
+	if from == nil {
+		return fieldError("from_id", fmt.Errorf("invalid group %q: %w", fromID, ERRBADREQUEST))
+	} else if to == nil {
+		return fieldError("to_id", fmt.Errorf("invalid group %q: %w", toID, ERRBADREQUEST))
+	} else if from == to {
+		return fieldError("to_id", fmt.Errorf("can't move factories to the same group: %w", ERRBADREQUEST))
+	} else if from.units.TechLevel != to.units.TechLevel {
+		return fieldError("to_id", fmt.Errorf("groups must be the same tech level: %w", ERRBADREQUEST))
+	} else if from.units.Quantity == 0 {
+		return fieldError("from_id", fmt.Errorf("group %q has no factories: %w", fromID, ERRBADREQUEST))
+	}
+	moved := minInt(quantity, from.units.Quantity)
+	from.units.Quantity, to.units.Quantity = from.units.Quantity-moved, to.units.Quantity+moved
+	if moved < quantity {
+		d.polity().logf("%s: moved %s of %s factories from group %s to %s (not enough factories)", colonyID, utils.Commas(moved), utils.Commas(quantity), from.id, to.id)
+		return fmt.Errorf("moved %d of %d: %w", moved, quantity, ERRPARTIAL)
+	}
+	d.polity().logf("%s: moved %s factories from group %s to %s", colonyID, utils.Commas(moved), from.id, to.id)
+	return nil
+}
+
+// runFactories advances the work in progress for every factory group
+// in the depot, delivers finished items to storage, and starts new work.
+//
+// New work is limited by the capacity of the group and by the metals,
+// non-metals, power, and professionals available at the depot. Groups
+// are run in order, so the first groups get first call on the inputs.
+// At a colony, unrest reduces the capacity of every group.
+func (d depot) runFactories() {
+	professionals := d.population.professionals
+	modifier := 1.0
+	if d.colony != nil {
+		modifier = d.colony.productionModifier()
+	}
+	for _, g := range *d.factories {
+		// deliver the oldest quarter and shift the pipeline
+		if delivered := g.wip[factoryQuarters-1]; delivered != 0 {
+			d.store(Unit{Kind: g.builds.Kind, TechLevel: g.builds.TechLevel, Quantity: delivered})
+			d.polity().logf("%s: factory group %s delivered %s %s", d.id, g.id, utils.Commas(delivered), g.builds)
+		}
+		for q := factoryQuarters - 1; q > 0; q-- {
+			g.wip[q] = g.wip[q-1]
+		}
+		g.wip[0] = 0
+
+		if g.units.Quantity == 0 {
+			continue
+		}
+		if modifier == 0 {
+			d.polity().logf("%s: factory group %s is idle (workers on strike)", d.id, g.id)
+			continue
+		}
+
+		// fraction of the group that can run given the power and professionals available
+		run := 1.0
+		power, workers := g.inputs()
+		if available := d.batteries.available(); available < power {
+			run = math.Min(run, float64(available)/float64(power))
+		}
+		if professionals < workers {
+			run = math.Min(run, float64(professionals)/float64(workers))
+		}
+		if run <= 0 {
+			d.polity().logf("%s: factory group %s is idle (no power or professionals)", d.id, g.id)
+			continue
+		}
+
+		// number of items that can be started given capacity and materials
+		metals, nonMetals := g.builds.Materials()
+		items := int(run * modifier * float64(g.capacity()) / (metals + nonMetals))
+		if metals > 0 {
+			items = minInt(items, int(float64(d.storage.metal)/metals))
+		}
+		if nonMetals > 0 {
+			items = minInt(items, int(float64(d.storage.nonmetal)/nonMetals))
+		}
+		if items <= 0 {
+			d.polity().logf("%s: factory group %s is idle (no materials)", d.id, g.id)
+			continue
+		}
+
+		d.storage.metal -= int(math.Ceil(float64(items) * metals))
+		d.storage.nonmetal -= int(math.Ceil(float64(items) * nonMetals))
+		d.batteries.used += int(math.Ceil(run * float64(power)))
+		professionals -= int(math.Ceil(run * float64(workers)))
+		g.wip[0] = items
+	}
+}
+
+// minInt returns the smaller of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,215 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_RunFactories(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		rebels  float64
+		started int
+	}{
+		{"no unrest", 0, 1666},
+		{"minor unrest", rebelsReduceProduction, 1666},
+		{"unrest", 0.16, 1333},
+		{"strike", rebelsStrike, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			c.population = Population{professionals: 10_000, total: 10_000}
+			c.rebels.professionals = tc.rebels
+			c.batteries.charged = 1_000_000
+			c.storage.metal, c.storage.nonmetal = 1_000_000, 1_000_000
+			g := &FactoryGroup{
+				id:     "F1",
+				units:  Unit{Kind: FACTORY, TechLevel: 1, Quantity: 100, Assembled: true},
+				builds: Unit{Kind: CONSUMERGOOD, TechLevel: 1},
+			}
+			c.factories = append(c.factories, g)
+			d := c.depot()
+			d.runFactories()
+			is.Equal(g.wip[0], tc.started) // items started this turn
+		})
+	}
+}
+
+func Test_RunFactoriesPipeline(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	c := st.Colony("tosa")
+	c.population = Population{professionals: 10_000, total: 10_000}
+	c.batteries.charged = 1_000_000
+	c.storage.metal, c.storage.nonmetal = 1_000_000, 1_000_000
+	g := &FactoryGroup{
+		id:     "F1",
+		units:  Unit{Kind: FACTORY, TechLevel: 1, Quantity: 100, Assembled: true},
+		builds: Unit{Kind: CONSUMERGOOD, TechLevel: 1},
+	}
+	c.factories = append(c.factories, g)
+	d := c.depot()
+	for turn := 1; turn <= factoryQuarters; turn++ {
+		d.runFactories()
+		d.batteries.used = 0
+		is.Equal(d.countUnit(CONSUMERGOOD, 1, false), 0) // nothing delivered before the pipeline fills
+	}
+	d.runFactories()
+	is.Equal(d.countUnit(CONSUMERGOOD, 1, false), 1666) // first batch delivered
+	is.Equal(g.wip[factoryQuarters-1], 1666)            // next batch is ready for next turn
+}
+
+func Test_RunFactoriesLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		power         int
+		professionals int
+		metal         int
+		started       int
+	}{
+		{"plenty", 1_000_000, 10_000, 1_000_000, 1666},
+		{"half power", 50, 10_000, 1_000_000, 833},
+		{"no professionals", 1_000_000, 0, 1_000_000, 0},
+		{"short of metal", 1_000_000, 10_000, 100, 500},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			c.population = Population{professionals: tc.professionals, total: tc.professionals}
+			c.batteries.charged = tc.power
+			c.storage.metal, c.storage.nonmetal = tc.metal, 1_000_000
+			g := &FactoryGroup{
+				id:     "F1",
+				units:  Unit{Kind: FACTORY, TechLevel: 1, Quantity: 100, Assembled: true},
+				builds: Unit{Kind: CONSUMERGOOD, TechLevel: 1},
+			}
+			c.factories = append(c.factories, g)
+			d := c.depot()
+			d.runFactories()
+			is.Equal(g.wip[0], tc.started)
+		})
+	}
+}
+
+func Test_AssembleFactory(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		constructors int
+		stored       int // unassembled FACTORY-1 in storage
+		quantity     int
+		err          error
+		assembled    int // units in the new group
+	}{
+		{"assembles all", 100, 10, 10, nil, 10},
+		{"limited by storage", 100, 10, 20, ERRPARTIAL, 10},
+		{"limited by labor", 1, 100, 100, ERRPARTIAL, 7},
+		{"no labor", 0, 10, 10, ERRBADREQUEST, 0},
+		{"nothing in storage", 100, 0, 10, ERRBADREQUEST, 0},
+		{"zero quantity", 100, 10, 0, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			mktestcrew(c, tc.constructors, 1_000, Unit{Kind: FACTORY, TechLevel: 1, Quantity: tc.stored})
+			err := st.AssembleFactory("usagi", "tosa", tc.quantity, "ENGINE", 1)
+			is.True(errors.Is(err, tc.err)) // error
+			if tc.assembled == 0 {
+				is.Equal(len(c.factories), 0) // no group is created
+				return
+			}
+			is.Equal(len(c.factories), 1)
+			is.Equal(c.factories[0].units.Quantity, tc.assembled)
+			is.Equal(c.depot().countUnit(FACTORY, 1, false), tc.stored-tc.assembled) // left in storage
+		})
+	}
+}
+
+func Test_AssembleFactoryGroup(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		constructors int
+		stored       int // unassembled FACTORY-1 in storage
+		quantity     int
+		err          error
+		assembled    int // units added to the group
+	}{
+		{"assembles all", 100, 10, 10, nil, 10},
+		{"limited by storage", 100, 10, 20, ERRPARTIAL, 10},
+		{"limited by labor", 1, 100, 100, ERRPARTIAL, 7},
+		{"no labor", 0, 10, 10, ERRBADREQUEST, 0},
+		{"nothing in storage", 100, 0, 10, ERRBADREQUEST, 0},
+		{"zero quantity", 100, 10, 0, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			mktestcrew(c, tc.constructors, 1_000, Unit{Kind: FACTORY, TechLevel: 1, Quantity: tc.stored})
+			g := &FactoryGroup{id: "F1", units: Unit{Kind: FACTORY, TechLevel: 1, Quantity: 10, Assembled: true}, builds: Unit{Kind: ENGINE, TechLevel: 1}}
+			c.factories = append(c.factories, g)
+			err := st.AssembleFactoryGroup("usagi", "tosa", tc.quantity, "F1")
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(g.units.Quantity, 10+tc.assembled)
+			is.Equal(c.depot().countUnit(FACTORY, 1, false), tc.stored-tc.assembled) // left in storage
+		})
+	}
+}
+
+func Test_FactoryGroupChange(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		fromID   string
+		toID     string
+		quantity int
+		err      error
+		moved    int
+	}{
+		{"moves factories", "F1", "F2", 5, nil, 5},
+		{"quantity exceeds group", "F1", "F2", 20, ERRPARTIAL, 10},
+		{"same group", "F1", "F1", 5, ERRBADREQUEST, 0},
+		{"different tech level", "F1", "F3", 5, ERRBADREQUEST, 0},
+		{"empty group", "F2", "F1", 5, ERRBADREQUEST, 0},
+		{"unknown group", "F1", "F9", 5, ERRBADREQUEST, 0},
+		{"zero quantity", "F1", "F2", 0, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			for _, g := range []*FactoryGroup{
+				{id: "F1", units: Unit{Kind: FACTORY, TechLevel: 1, Quantity: 10, Assembled: true}, builds: Unit{Kind: ENGINE, TechLevel: 1}},
+				{id: "F2", units: Unit{Kind: FACTORY, TechLevel: 1, Assembled: true}, builds: Unit{Kind: ENGINE, TechLevel: 1}},
+				{id: "F3", units: Unit{Kind: FACTORY, TechLevel: 2, Quantity: 10, Assembled: true}, builds: Unit{Kind: ENGINE, TechLevel: 1}},
+			} {
+				c.factories = append(c.factories, g)
+			}
+			err := st.FactoryGroupChange("usagi", "tosa", tc.fromID, tc.toID, tc.quantity)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(c.factories[0].units.Quantity, 10-tc.moved)
+			is.Equal(c.factories[1].units.Quantity, tc.moved)
+		})
+	}
+}
@@ -57,12 +57,12 @@ func (st *State) Give(issuedByID, assetID, targetID string) error {
 	} else if asset.ship = st.Ship(assetID); asset.ship != nil {
 		asset.polity, asset.system = asset.ship.polity, asset.ship.system
 	} else {
-		return fmt.Errorf("invalid asset %q: %w", assetID, ERRBADREQUEST)
+		return fieldError("asset_id", fmt.Errorf("invalid asset %q: %w", assetID, ERRBADREQUEST))
 	}
 
 	// the asset must be owned by the polity issuing the order
 	if asset.polity != issuedBy {
-		return fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN)
+		return fieldError("asset_id", fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN))
 	}
 
 	// target must be a polity, colony, or ship.
@@ -74,23 +74,23 @@ func (st *State) Give(issuedByID, assetID, targetID string) error {
 		} else if target.ship = st.Ship(targetID); target.ship != nil {
 			target.polity, target.system = target.ship.polity, target.ship.system
 		} else {
-			return fmt.Errorf("invalid target %q: %w", targetID, ERRBADREQUEST)
+			return fieldError("target_id", fmt.Errorf("invalid target %q: %w", targetID, ERRBADREQUEST))
 		}
 	}
 
 	// the target must belong to an ally
 	if !issuedBy.isAlliedTo(target.polity) {
-		return fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN)
+		return fieldError("target_id", fmt.Errorf("target is not an ally: %w", ERRFORBIDDEN))
 	}
 
 	// if the target is a colony or ship, it must be in the same system as the asset.
 	if target.system != nil && asset.system != target.system {
-		return fmt.Errorf("asset not in target's system: %w", ERRFORBIDDEN)
+		return fieldError("target_id", fmt.Errorf("asset not in target's system: %w", ERRFORBIDDEN))
 	}
 
 	// if the asset is a home colony, the target must be the colony's original polity
 	if asset.colony.isHomeColony() && target.polity != asset.colony.originalPolity {
-		return fmt.Errorf("asset refuses order: %w", ERRFORBIDDEN)
+		return fieldError("target_id", fmt.Errorf("home colony may only be given to its original polity: %w", ERRFORBIDDEN))
 	}
 
 	// all checks have passed, so transfer the colony or ship
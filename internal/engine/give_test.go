@@ -19,12 +19,61 @@
 package engine
 
 import (
+	"errors"
 	"github.com/matryer/is"
 	"testing"
 )
 
 func Test_Give(t *testing.T) {
-	is := is.New(t)
+	for _, tc := range []struct {
+		name   string
+		asset  string
+		target string
+		stance DiplomaticStatus // between usagi and kuma
+		home   bool             // tosa is usagi's home colony
+		err    error
+		polity string // polity controlling the asset afterwards
+	}{
+		{"ship to polity", "S1", "kuma", ALLY, false, nil, "kuma"},
+		{"ship to colony", "S1", "K-near", ALLY, false, nil, "kuma"},
+		{"colony to polity", "tosa", "kuma", ALLY, false, nil, "kuma"},
+		{"colony to ship", "tosa", "K1", ALLY, false, nil, "kuma"},
+		{"not allied", "S1", "kuma", FRIEND, false, ERRFORBIDDEN, "usagi"},
+		{"not in system", "S1", "K-away", ALLY, false, ERRFORBIDDEN, "usagi"},
+		{"home colony", "tosa", "kuma", ALLY, true, ERRFORBIDDEN, "usagi"},
+		{"not controlled", "K1", "usagi", ALLY, false, ERRFORBIDDEN, "kuma"},
+		{"unknown asset", "S9", "kuma", ALLY, false, ERRBADREQUEST, ""},
+		{"unknown target", "S1", "tora", ALLY, false, ERRBADREQUEST, "usagi"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = tc.stance, tc.stance
+			tosa := st.Colony("tosa")
+			if tc.home {
+				tosa.originalPolity = usagi
+			}
+			mktestship(st, usagi, st.Colony("sanuki"), "S1")
+			near := mktestcolony(st, kuma, st.Planet("suisei").orbit, "K-near")
+			mktestship(st, kuma, near, "K1")
+			mktestcolony(st, kuma, mkorbit(mktestsystem(st, "hoshi", 1, 1, 4).stars[0], 0), "K-away")
 
-	is.NoErr(ERRNOTIMPLEMENTED)
+			err := st.Give("usagi", tc.asset, tc.target)
+			is.True(errors.Is(err, tc.err)) // error
+			if tc.polity != "" {
+				var p *Polity
+				if c := st.Colony(tc.asset); c != nil {
+					p = c.polity
+				} else {
+					p = st.Ship(tc.asset).polity
+				}
+				is.Equal(p.id, tc.polity) // controlling polity
+			}
+			is.Equal(len(st.checkOwnership()), 0)
+		})
+	}
+	is := is.New(t)
+	st, _ := Make()
+	is.Equal(st.Give("nobody", "tosa", "usagi"), ERRBUG)
 }
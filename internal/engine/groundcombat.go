@@ -0,0 +1,453 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// groundCombatRounds is the most rounds of ground combat fought at a
+// colony in a single turn. Fighting that is not settled carries over.
+const groundCombatRounds = 10
+
+// groundCasualtyRate is the fraction of the enemy's combat factor that
+// a side loses in each round.
+const groundCasualtyRate = 0.10
+
+// militiaFactor is the combat factor of one unskilled worker called up
+// to defend a colony. A soldier has a factor of one.
+const militiaFactor = 0.10
+
+// surrenderRatio is the advantage that one side needs over the other to
+// force a surrender.
+const surrenderRatio = 3
+
+// invasion is a force that has landed on a colony.
+type invasion struct {
+	polity   *Polity
+	ship     *Ship // survivors return to the ship when they withdraw
+	soldiers int
+	units    []Unit
+}
+
+// factor returns the combat factor of the invading force.
+func (inv *invasion) factor() float64 {
+	return float64(inv.soldiers) + unitsFactor(inv.units)
+}
+
+// unitsFactor returns the combat factor of military units.
+func unitsFactor(units []Unit) float64 {
+	var factor float64
+	for _, u := range units {
+		if w, ok := weaponOf(u); ok {
+			factor += float64(w.damage * u.Quantity)
+		}
+	}
+	return factor
+}
+
+// loseUnits removes a fraction of every unit and returns the losses.
+func loseUnits(units []Unit, fraction float64) (kept, lost []Unit) {
+	for _, u := range units {
+		n := int(math.Ceil(float64(u.Quantity) * fraction))
+		if n > u.Quantity {
+			n = u.Quantity
+		}
+		if n != 0 {
+			lost = append(lost, Unit{Kind: u.Kind, TechLevel: u.TechLevel, Quantity: n, Assembled: u.Assembled})
+		}
+		if u.Quantity -= n; u.Quantity != 0 {
+			kept = append(kept, u)
+		}
+	}
+	return kept, lost
+}
+
+// groundItems is the list of items given in ground combat orders.
+type groundItems []struct {
+	Item      string `json:"item"`
+	TechLevel int    `json:"tech_level"`
+	Quantity  int    `json:"quantity"`
+}
+
+// forces returns the soldiers and military units listed in the items
+// that are available at the depot. Quantities may exceed what the depot
+// has; the overage is ignored.
+func (d depot) forces(items groundItems) (soldiers int, units []Unit, err error) {
+	for i, item := range items {
+		if item.Quantity <= 0 {
+			return 0, nil, fieldError(fmt.Sprintf("items/%d/quantity", i), fmt.Errorf("invalid quantity %d: %w", item.Quantity, ERRBADREQUEST))
+		} else if strings.ToLower(strings.TrimSpace(item.Item)) == SOLDIERS.String() {
+			soldiers = minInt(soldiers+item.Quantity, d.population.soldiers)
+			continue
+		}
+		kind, ok := unitKindFromString(item.Item)
+		if !ok {
+			return 0, nil, fieldError(fmt.Sprintf("items/%d/item", i), fmt.Errorf("invalid item %q: %w", item.Item, ERRBADREQUEST))
+		}
+		u := Unit{Kind: kind, TechLevel: item.TechLevel, Assembled: true}
+		if _, ok := weaponOf(u); !ok {
+			return 0, nil, fieldError(fmt.Sprintf("items/%d/item", i), fmt.Errorf("item %q is not a military unit: %w", item.Item, ERRBADREQUEST))
+		}
+		if u.Quantity = minInt(item.Quantity, d.countUnit(kind, item.TechLevel, true)); u.Quantity != 0 {
+			units = append(units, u)
+		}
+	}
+	return soldiers, units, nil
+}
+
+// takeForces removes soldiers and military units from the depot.
+func (d depot) takeForces(soldiers int, units []Unit) {
+	d.population.soldiers -= soldiers
+	d.population.total -= soldiers
+	for i, u := range units {
+		units[i].Quantity = d.removeUnit(u.Kind, u.TechLevel, true, u.Quantity)
+	}
+}
+
+// groundCombat holds the support given to each colony this turn.
+type groundCombat struct {
+	st        *State
+	offensive map[*Colony]float64
+	defensive map[*Colony]float64
+	committed map[string][]Unit // units supporting ground combat this turn, by source
+}
+
+// uncommitted limits the units to those at the depot that have not been
+// committed to supporting ground combat this turn.
+func (gc *groundCombat) uncommitted(d depot, units []Unit) []Unit {
+	var free []Unit
+	for _, u := range units {
+		available := d.countUnit(u.Kind, u.TechLevel, true)
+		for _, c := range gc.committed[d.id] {
+			if c.Kind == u.Kind && c.TechLevel == u.TechLevel {
+				available -= c.Quantity
+			}
+		}
+		if u.Quantity = minInt(u.Quantity, available); u.Quantity > 0 {
+			free = append(free, u)
+		}
+	}
+	return free
+}
+
+// commit reserves the units at the depot for the rest of the turn.
+// Support units fire from where they are, so they stay in the depot,
+// but they can't support another colony or land in an invasion.
+func (gc *groundCombat) commit(d depot, units []Unit) {
+	gc.committed[d.id] = append(gc.committed[d.id], units...)
+}
+
+// landingShip returns the ship, which must accept orders from the polity
+// and be in orbit around the target colony.
+func (gc *groundCombat) landingShip(issuedBy *Polity, shipID, colonyID string) (*Ship, *Colony, error) {
+	ship, colony := gc.st.Ship(shipID), gc.st.Colony(colonyID)
+	if ship == nil {
+		return nil, nil, fieldError("source_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
+	} else if !ship.acceptsOrdersFrom(issuedBy) {
+		return nil, nil, fieldError("source_id", fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN))
+	} else if colony == nil {
+		return nil, nil, fieldError("target_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	} else if ship.orbit == nil || ship.orbit != colony.orbitOf() {
+		return nil, nil, fieldError("target_id", fmt.Errorf("ship is not in orbit around %q: %w", colonyID, ERRBADREQUEST))
+	}
+	return ship, colony, nil
+}
+
+// orbitOf returns the orbit that the colony is in or whose planet it is on.
+func (c *Colony) orbitOf() *Orbit {
+	if c.planet != nil {
+		return c.planet.orbit
+	}
+	return c.orbit
+}
+
+// support returns the depot of the source, which must accept orders from
+// the polity and be in the same system as the target colony.
+func (gc *groundCombat) support(issuedBy *Polity, sourceID, colonyID string) (depot, *Colony, error) {
+	d, err := gc.st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return depot{}, nil, fieldError("source_id", err)
+	}
+	colony := gc.st.Colony(colonyID)
+	if colony == nil {
+		return depot{}, nil, fieldError("target_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	}
+	system := colony.system
+	if d.ship != nil && d.ship.system != system || d.colony != nil && d.colony.system != system {
+		return depot{}, nil, fieldError("target_id", fmt.Errorf("source is not in the same system as %q: %w", colonyID, ERRBADREQUEST))
+	}
+	return d, colony, nil
+}
+
+// Withdraw pulls the forces that a ship landed on a colony back to the ship.
+func (gc *groundCombat) Withdraw(issuedBy *Polity, shipID, colonyID string) error {
+	ship, colony, err := gc.landingShip(issuedBy, shipID, colonyID)
+	if err != nil {
+		return err
+	}
+	var invaders []*invasion
+	for _, inv := range colony.invaders {
+		if inv.ship != ship {
+			invaders = append(invaders, inv)
+			continue
+		}
+		d := ship.depot()
+		d.population.soldiers += inv.soldiers
+		d.population.total += inv.soldiers
+		for _, u := range inv.units {
+			d.addUnit(u)
+		}
+		ship.polity.logf("%s: withdrew %s soldiers and %d military units from %s", shipID, utils.Commas(inv.soldiers), len(inv.units), colonyID)
+	}
+	colony.invaders = invaders
+	return nil
+}
+
+// DefensiveSupport adds the weapons of the source to the defense of a
+// colony. Soldiers listed in the items land on the colony and join its
+// garrison. The colony must not be hostile to the source. Units that
+// support a colony are committed to it for the rest of the turn.
+func (gc *groundCombat) DefensiveSupport(issuedBy *Polity, sourceID, colonyID string, items groundItems) error {
+	d, colony, err := gc.support(issuedBy, sourceID, colonyID)
+	if err != nil {
+		return err
+	} else if hostile(d.polity(), colony.polity) {
+		return fieldError("target_id", fmt.Errorf("colony %q is hostile: %w", colonyID, ERRFORBIDDEN))
+	}
+	soldiers, units, err := d.forces(items)
+	if err != nil {
+		return err
+	} else if units = gc.uncommitted(d, units); soldiers == 0 && len(units) == 0 {
+		return fmt.Errorf("no forces to support with: %w", ERRBADREQUEST)
+	}
+	d.takeForces(soldiers, nil)
+	colony.population.soldiers += soldiers
+	colony.population.total += soldiers
+	gc.commit(d, units)
+	gc.defensive[colony] += unitsFactor(units)
+	d.polity().logf("%s: supporting the defense of %s with %s soldiers and a factor of %.0f", sourceID, colonyID, utils.Commas(soldiers), unitsFactor(units))
+	return nil
+}
+
+// Invade lands soldiers and military units from a ship on a hostile colony.
+func (gc *groundCombat) Invade(issuedBy *Polity, shipID, colonyID string, items groundItems) error {
+	ship, colony, err := gc.landingShip(issuedBy, shipID, colonyID)
+	if err != nil {
+		return err
+	} else if !hostile(ship.polity, colony.polity) {
+		return fieldError("target_id", fmt.Errorf("colony %q is not hostile: %w", colonyID, ERRFORBIDDEN))
+	}
+	soldiers, units, err := ship.depot().forces(items)
+	if err != nil {
+		return err
+	} else if units = gc.uncommitted(ship.depot(), units); soldiers == 0 && len(units) == 0 {
+		return fmt.Errorf("no forces to land: %w", ERRBADREQUEST)
+	}
+	ship.depot().takeForces(soldiers, units)
+	colony.invaders = append(colony.invaders, &invasion{polity: ship.polity, ship: ship, soldiers: soldiers, units: units})
+	ship.polity.logf("%s: landed %s soldiers and %d military units on %s", shipID, utils.Commas(soldiers), len(units), colonyID)
+	colony.polity.logf("%s: invaded by %s", colonyID, ship.polity.name)
+	return nil
+}
+
+// OffensiveSupport adds the weapons of the source to the attack on a colony.
+// Units that support an attack are committed to it for the rest of the turn.
+func (gc *groundCombat) OffensiveSupport(issuedBy *Polity, sourceID, colonyID string, items groundItems) error {
+	d, colony, err := gc.support(issuedBy, sourceID, colonyID)
+	if err != nil {
+		return err
+	} else if !hostile(d.polity(), colony.polity) {
+		return fieldError("target_id", fmt.Errorf("colony %q is not hostile: %w", colonyID, ERRFORBIDDEN))
+	}
+	// soldiers can't support an attack from where they are, so only units count
+	_, units, err := d.forces(items)
+	if err != nil {
+		return err
+	} else if units = gc.uncommitted(d, units); len(units) == 0 {
+		return fmt.Errorf("no forces to support with: %w", ERRBADREQUEST)
+	}
+	gc.commit(d, units)
+	gc.offensive[colony] += unitsFactor(units)
+	d.polity().logf("%s: supporting the attack on %s with a factor of %.0f", sourceID, colonyID, unitsFactor(units))
+	return nil
+}
+
+// fight cycles rounds of ground combat at the colony until one side
+// surrenders or the rounds for the turn run out.
+func (gc *groundCombat) fight(colony *Colony) error {
+	for round := 1; round <= groundCombatRounds && len(colony.invaders) != 0; round++ {
+		var attack float64
+		for _, inv := range colony.invaders {
+			attack += inv.factor()
+		}
+		attack += gc.offensive[colony]
+		garrison := float64(colony.population.soldiers) + unitsFactor(colony.units)
+		militia := militiaFactor * float64(colony.population.unskilled)
+		defense := garrison + militia + gc.defensive[colony]
+
+		// surrender check
+		if defense*surrenderRatio <= attack {
+			return gc.surrender(colony, round)
+		} else if attack*surrenderRatio <= defense {
+			for _, inv := range colony.invaders {
+				inv.polity.logf("%s: round %d: invasion force of %s soldiers surrendered", colony.id, round, utils.Commas(inv.soldiers))
+			}
+			colony.polity.logf("%s: round %d: invaders surrendered", colony.id, round)
+			colony.invaders = nil
+			return nil
+		}
+
+		// invasion casualties
+		attackerLoss := math.Min(1, defense*groundCasualtyRate/attack)
+		for _, inv := range colony.invaders {
+			dead := int(math.Ceil(float64(inv.soldiers) * attackerLoss))
+			var lost []Unit
+			inv.soldiers -= dead
+			inv.units, lost = loseUnits(inv.units, attackerLoss)
+			inv.polity.logf("%s: round %d: attack %.0f, defense %.0f: lost %s soldiers and %d military units", colony.id, round, attack, defense, utils.Commas(dead), len(lost))
+		}
+		if own := garrison + militia; own > 0 {
+			defenderLoss := math.Min(1, attack*groundCasualtyRate/defense)
+			dead := int(math.Ceil(float64(colony.population.soldiers) * defenderLoss))
+			militiaDead := int(math.Ceil(float64(colony.population.unskilled) * defenderLoss))
+			colony.population.soldiers -= dead
+			colony.population.unskilled -= militiaDead
+			colony.population.total -= dead + militiaDead
+			var lost []Unit
+			var kept []Unit
+			for _, u := range colony.units {
+				if _, ok := weaponOf(u); !ok {
+					kept = append(kept, u)
+					continue
+				}
+				k, l := loseUnits([]Unit{u}, defenderLoss)
+				kept, lost = append(kept, k...), append(lost, l...)
+			}
+			colony.units = kept
+			colony.polity.logf("%s: round %d: attack %.0f, defense %.0f: lost %s soldiers, %s militia, and %d military units", colony.id, round, attack, defense, utils.Commas(dead), utils.Commas(militiaDead), len(lost))
+		}
+
+		// remove invaders that have been wiped out
+		var invaders []*invasion
+		for _, inv := range colony.invaders {
+			if inv.factor() > 0 {
+				invaders = append(invaders, inv)
+			}
+		}
+		colony.invaders = invaders
+	}
+	return nil
+}
+
+// surrender hands the colony to the strongest invader. Surviving
+// invaders join the garrison. Home colonies keep their original polity
+// so that it may liberate them later.
+func (gc *groundCombat) surrender(colony *Colony, round int) error {
+	var victor *invasion
+	for _, inv := range colony.invaders {
+		if victor == nil || inv.factor() > victor.factor() {
+			victor = inv
+		}
+	}
+	from, to := colony.polity, victor.polity
+	for _, inv := range colony.invaders {
+		if inv.polity != to {
+			continue
+		}
+		colony.population.soldiers += inv.soldiers
+		colony.population.total += inv.soldiers
+		for _, u := range inv.units {
+			colony.depot().addUnit(u)
+		}
+	}
+	colony.invaders = nil
+	if colony.isHomeColony() && to == colony.originalPolity {
+		to.logf("%s: round %d: colony surrendered and has been liberated", colony.id, round)
+	} else {
+		to.logf("%s: round %d: colony surrendered", colony.id, round)
+	}
+	from.logf("%s: round %d: colony surrendered to %s", colony.id, round, to.name)
+	return gc.st.transferColony(colony, from, to)
+}
+
+// groundCombat resolves the ground combat segment of the combat orders stage.
+func (st *State) groundCombat(stageName string, debug bool) []error {
+	var errs []error
+	gc := &groundCombat{st: st, offensive: make(map[*Colony]float64), defensive: make(map[*Colony]float64), committed: make(map[string][]Unit)}
+	for i, order := range st.orders {
+		var err error
+		switch {
+		case order.Withdraw != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d withdraw %v\n", stageName, i, *order.Withdraw)
+			}
+			if err = st.record(order, gc.Withdraw(st.Polity(order.issuedBy), order.Withdraw.SourceID, order.Withdraw.TargetID)); err != nil {
+				err = fmt.Errorf("Withdraw: %w", err)
+			}
+		case order.DefensiveSupport != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d defensiveSupport %v\n", stageName, i, *order.DefensiveSupport)
+			}
+			o := order.DefensiveSupport
+			if err = st.record(order, gc.DefensiveSupport(st.Polity(order.issuedBy), o.SourceID, o.TargetID, o.Items)); err != nil {
+				err = fmt.Errorf("DefensiveSupport: %w", err)
+			}
+		case order.Invade != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d invade %v\n", stageName, i, *order.Invade)
+			}
+			o := order.Invade
+			if err = st.record(order, gc.Invade(st.Polity(order.issuedBy), o.SourceID, o.TargetID, o.Items)); err != nil {
+				err = fmt.Errorf("Invade: %w", err)
+			}
+		case order.OffensiveSupport != nil:
+			if debug {
+				log.Printf("[stage:%s] %4d offensiveSupport %v\n", stageName, i, *order.OffensiveSupport)
+			}
+			o := order.OffensiveSupport
+			if err = st.record(order, gc.OffensiveSupport(st.Polity(order.issuedBy), o.SourceID, o.TargetID, o.Items)); err != nil {
+				err = fmt.Errorf("OffensiveSupport: %w", err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// cycle ground combat at every colony with invaders, in a fixed order
+	var ids []string
+	for id, colony := range st.colonies {
+		if len(colony.invaders) != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := gc.fight(st.colonies[id]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: colony %s: %w", stageName, id, err))
+		}
+	}
+	return errs
+}
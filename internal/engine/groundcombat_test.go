@@ -0,0 +1,122 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_OffensiveSupportCommitsUnits(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	kuma, tosa := mkrival(st, "kuma"), st.Colony("tosa")
+	k1 := mktestship(st, kuma, tosa, "K1", Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+	gc := &groundCombat{st: st, offensive: make(map[*Colony]float64), defensive: make(map[*Colony]float64), committed: make(map[string][]Unit)}
+	missiles := func(n int) groundItems {
+		return groundItems{{Item: "MISSILE", TechLevel: 1, Quantity: n}}
+	}
+	for _, tc := range []struct {
+		name     string
+		quantity int
+		err      error
+		factor   float64 // offensive support for tosa afterwards
+	}{
+		{"commits missiles", 6, nil, 60},
+		{"only uncommitted missiles", 6, nil, 100},
+		{"nothing left", 1, ERRBADREQUEST, 100},
+	} {
+		is.True(errors.Is(gc.OffensiveSupport(kuma, "K1", "tosa", missiles(tc.quantity)), tc.err)) // error
+		is.Equal(gc.offensive[tosa], tc.factor)                                                    // support
+	}
+	is.True(errors.Is(gc.Invade(kuma, "K1", "tosa", missiles(10)), ERRBADREQUEST)) // committed missiles can't land
+	is.Equal(k1.depot().countUnit(MISSILE, 1, true), 10)                           // support units fire from where they are
+}
+
+func Test_DefensiveSupport(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, tosa := st.Polity("usagi"), st.Colony("tosa")
+	u1 := mktestship(st, usagi, tosa, "U1", Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+	u1.population = Population{soldiers: 50, total: 50}
+	gc := &groundCombat{st: st, offensive: make(map[*Colony]float64), defensive: make(map[*Colony]float64), committed: make(map[string][]Unit)}
+	items := groundItems{{Item: "soldiers", Quantity: 20}, {Item: "MISSILE", TechLevel: 1, Quantity: 10}}
+
+	is.NoErr(gc.DefensiveSupport(usagi, "U1", "tosa", items))
+	is.Equal(tosa.population.soldiers, 20) // soldiers join the garrison
+	is.Equal(u1.population.soldiers, 30)
+	is.Equal(gc.defensive[tosa], 100.0)
+	is.NoErr(gc.DefensiveSupport(usagi, "U1", "tosa", items))
+	is.Equal(tosa.population.soldiers, 40)
+	is.Equal(gc.defensive[tosa], 100.0) // missiles are already committed
+	is.True(errors.Is(gc.DefensiveSupport(usagi, "U1", "tosa", groundItems{{Item: "MISSILE", TechLevel: 1, Quantity: 10}}), ERRBADREQUEST))
+
+	mkrival(st, "kuma")
+	is.True(errors.Is(gc.DefensiveSupport(usagi, "U1", "tosa", groundItems{{Item: "WIDGET", Quantity: 1}}), ERRBADREQUEST))
+	is.True(errors.Is(gc.DefensiveSupport(usagi, "U1", "tosa", groundItems{{Item: "ENGINE", TechLevel: 1, Quantity: 1}}), ERRBADREQUEST))
+	is.True(errors.Is(gc.DefensiveSupport(st.Polity("kuma"), "U1", "tosa", items), ERRFORBIDDEN))
+}
+
+func Test_GroundCombatFight(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		invaders int
+		garrison int
+		polity   string // polity controlling tosa afterwards
+		invaded  bool   // invasion continues next turn
+	}{
+		{"undefended colony surrenders", 100, 0, "kuma", false},
+		{"invaders surrender", 100, 1_000, "usagi", false},
+		{"fighting carries over", 100, 100, "usagi", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			tosa := st.Colony("tosa")
+			k1 := mktestship(st, mkrival(st, "kuma"), tosa, "K1")
+			k1.population = Population{soldiers: tc.invaders, total: tc.invaders}
+			gc := &groundCombat{st: st, offensive: make(map[*Colony]float64), defensive: make(map[*Colony]float64), committed: make(map[string][]Unit)}
+			tosa.population = Population{soldiers: tc.garrison, total: tc.garrison}
+			is.NoErr(gc.Invade(st.Polity("kuma"), "K1", "tosa", groundItems{{Item: "soldiers", Quantity: tc.invaders}}))
+			is.NoErr(gc.fight(tosa))
+			is.Equal(tosa.polity.id, tc.polity)
+			is.Equal(len(tosa.invaders) != 0, tc.invaded)
+		})
+	}
+}
+
+func Test_GroundCombatRecordsOrders(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	k1 := mktestship(st, mkrival(st, "kuma"), st.Colony("tosa"), "K1")
+	k1.population = Population{soldiers: 100, total: 100}
+	st.orders = Orders{
+		{issuedBy: "kuma", ID: "1", Invade: &Invade{SourceID: "K1", TargetID: "tosa"}},
+		{issuedBy: "usagi", ID: "2", Withdraw: &Withdraw{SourceID: "K1", TargetID: "tosa"}},
+		{issuedBy: "kuma", ID: "3", OffensiveSupport: &OffensiveSupport{SourceID: "K1", TargetID: "nowhere"}},
+	}
+	st.orders[0].Invade.Items = append(st.orders[0].Invade.Items, groundItems{{Item: "soldiers", Quantity: 100}}...)
+	errs := st.groundCombat("combat", false)
+	is.Equal(len(errs), 2)
+	for i, want := range []OrderStatus{EXECUTED, REJECTED, REJECTED} {
+		is.Equal(st.orders[i].status, want) // every ground combat order is recorded
+	}
+	is.Equal(st.Colony("tosa").polity.id, "kuma") // tosa surrendered
+}
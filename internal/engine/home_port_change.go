@@ -43,17 +43,17 @@ func (st *State) HomePortChange(issuedByID, shipID, colonyID string) error {
 	// ship must be a ship controlled by the polity issuing the order
 	ship := st.Ship(shipID)
 	if ship == nil {
-		return fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST)
+		return fieldError("ship_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
 	} else if ship.polity != issuedBy {
-		return fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN)
+		return fieldError("ship_id", fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN))
 	}
 
 	// colony must be a colony controlled by the polity issuing the order
 	colony := st.Colony(colonyID)
 	if colony == nil {
-		return fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST)
+		return fieldError("colony_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
 	} else if colony.polity != issuedBy {
-		return fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN)
+		return fieldError("colony_id", fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN))
 	}
 
 	// all checks have passed, so assign the ship to the colony
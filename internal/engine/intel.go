@@ -0,0 +1,286 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"io"
+	"sort"
+)
+
+// intel is what a polity knows about the cluster. Every observation
+// records the turn that it was made on. Observations from earlier turns
+// are stale; the polity only knows how things were, not how they are.
+type intel struct {
+	systems  map[string]*systemIntel
+	planets  map[string]*planetIntel
+	colonies map[string]*colonyIntel
+	ships    map[string]*shipIntel
+}
+
+func mkintel() *intel {
+	return &intel{
+		systems:  make(map[string]*systemIntel),
+		planets:  make(map[string]*planetIntel),
+		colonies: make(map[string]*colonyIntel),
+		ships:    make(map[string]*shipIntel),
+	}
+}
+
+type systemIntel struct {
+	turn   int
+	name   string
+	coords Coords
+	stars  int
+}
+
+type planetIntel struct {
+	turn         int
+	orbit        string // name of the orbit the planet is in
+	kind         PlanetKind
+	habitability int
+	surveyed     int // turn of the last survey, zero if never surveyed
+	deposits     []depositIntel
+}
+
+type depositIntel struct {
+	id        string
+	kind      ResourceKind
+	remaining int
+	yield     float64
+	unlimited bool
+}
+
+type colonyIntel struct {
+	turn       int
+	polity     string
+	number     string
+	kind       ColonyKind
+	orbit      string // name of the orbit the colony is in
+	population int
+	probed     int // turn of the last intensive probe, zero if never probed
+	units      []Unit
+}
+
+type shipIntel struct {
+	turn     int
+	polity   string
+	number   string
+	location string
+	mass     float64
+	probed   int // turn of the last intensive probe, zero if never probed
+	units    []Unit
+}
+
+// observeSystem records the location and stars of the system.
+func (in *intel) observeSystem(turn int, s *System) {
+	in.systems[s.id] = &systemIntel{turn: turn, name: s.name, coords: s.location(), stars: len(s.stars)}
+}
+
+// observePlanet records what can be seen of the planet from orbit.
+// Anything learned from an earlier survey is kept.
+func (in *intel) observePlanet(turn int, p *Planet) {
+	pi, ok := in.planets[p.id]
+	if !ok {
+		pi = &planetIntel{}
+		in.planets[p.id] = pi
+	}
+	pi.turn, pi.orbit, pi.kind, pi.habitability = turn, p.orbit.name, p.kind, p.habitability
+}
+
+// surveyPlanet records the planet along with its deposits and their yields.
+func (in *intel) surveyPlanet(turn int, p *Planet) {
+	in.observePlanet(turn, p)
+	pi := in.planets[p.id]
+	pi.surveyed, pi.deposits = turn, nil
+	for _, r := range p.deposits {
+		pi.deposits = append(pi.deposits, depositIntel{id: r.id, kind: r.kind, remaining: r.amountRemaining, yield: r.yieldPct, unlimited: r.unlimited})
+	}
+}
+
+// observeColony records the colony. An intensive probe also records its units.
+func (in *intel) observeColony(turn int, c *Colony, intensive bool) {
+	ci, ok := in.colonies[c.id]
+	if !ok {
+		ci = &colonyIntel{}
+		in.colonies[c.id] = ci
+	}
+	ci.turn, ci.polity, ci.number, ci.kind, ci.orbit, ci.population = turn, ownerID(c.polity), c.number, c.kind, c.orbitOf().name, c.population.total
+	if intensive {
+		ci.probed, ci.units = turn, append([]Unit{}, c.units...)
+	}
+}
+
+// observeShip records the ship. An intensive probe also records its units.
+func (in *intel) observeShip(turn int, s *Ship, intensive bool) {
+	si, ok := in.ships[s.id]
+	if !ok {
+		si = &shipIntel{}
+		in.ships[s.id] = si
+	}
+	si.turn, si.polity, si.number, si.location, si.mass = turn, ownerID(s.polity), s.number, s.locationName(), s.mass()
+	if intensive {
+		si.probed, si.units = turn, append([]Unit{}, s.units...)
+	}
+}
+
+// observeOrbit records the planet, colonies, and ships in the orbit.
+func (in *intel) observeOrbit(turn int, o *Orbit) {
+	if o.planet != nil {
+		in.observePlanet(turn, o.planet)
+		for _, c := range o.planet.colonies {
+			in.observeColony(turn, c, false)
+		}
+	}
+	for _, c := range o.colonies {
+		in.observeColony(turn, c, false)
+	}
+	for _, s := range o.ships {
+		in.observeShip(turn, s, false)
+	}
+}
+
+// share copies observations to the other intel where they are newer.
+func (in *intel) share(to *intel) {
+	for id, si := range in.systems {
+		if old, ok := to.systems[id]; !ok || old.turn < si.turn {
+			cp := *si
+			to.systems[id] = &cp
+		}
+	}
+	for id, pi := range in.planets {
+		old, ok := to.planets[id]
+		if !ok {
+			old = &planetIntel{}
+			to.planets[id] = old
+		}
+		if old.turn < pi.turn {
+			old.turn, old.orbit, old.kind, old.habitability = pi.turn, pi.orbit, pi.kind, pi.habitability
+		}
+		if old.surveyed < pi.surveyed {
+			old.surveyed, old.deposits = pi.surveyed, append([]depositIntel{}, pi.deposits...)
+		}
+	}
+	for id, ci := range in.colonies {
+		old, ok := to.colonies[id]
+		if !ok {
+			old = &colonyIntel{}
+			to.colonies[id] = old
+		}
+		if old.turn < ci.turn {
+			old.turn, old.polity, old.number, old.kind, old.orbit, old.population = ci.turn, ci.polity, ci.number, ci.kind, ci.orbit, ci.population
+		}
+		if old.probed < ci.probed {
+			old.probed, old.units = ci.probed, append([]Unit{}, ci.units...)
+		}
+	}
+	for id, si := range in.ships {
+		old, ok := to.ships[id]
+		if !ok {
+			old = &shipIntel{}
+			to.ships[id] = old
+		}
+		if old.turn < si.turn {
+			old.turn, old.polity, old.number, old.location, old.mass = si.turn, si.polity, si.number, si.location, si.mass
+		}
+		if old.probed < si.probed {
+			old.probed, old.units = si.probed, append([]Unit{}, si.units...)
+		}
+	}
+}
+
+// seen returns the turn an observation was made on, marked if it is stale.
+func seen(turn, now int) string {
+	if turn < now {
+		return fmt.Sprintf("(turn %d) (stale)", turn)
+	}
+	return fmt.Sprintf("(turn %d)", turn)
+}
+
+// report writes the polity's view of the cluster. Only what the polity
+// has observed is written, stale observations are marked, and entities
+// are shown by the names the polity has given them.
+func (in *intel) report(w io.Writer, now int, names *nameTable) {
+	if in == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "    (intel\n")
+	var systemIDs []string
+	for id := range in.systems {
+		systemIDs = append(systemIDs, id)
+	}
+	sort.Strings(systemIDs)
+	for _, id := range systemIDs {
+		si := in.systems[id]
+		_, _ = fmt.Fprintf(w, "      (system (id %q) (name %q) (coords %s) (stars %d) %s)\n", id, names.nameOf(id, si.name), si.coords, si.stars, seen(si.turn, now))
+	}
+	var planetIDs []string
+	for id := range in.planets {
+		planetIDs = append(planetIDs, id)
+	}
+	sort.Strings(planetIDs)
+	for _, id := range planetIDs {
+		pi := in.planets[id]
+		_, _ = fmt.Fprintf(w, "      (planet (id %q) (name %q) (orbit %q) (kind %s) (habitability %d) %s\n", id, names.nameOf(id, pi.orbit), pi.orbit, pi.kind, pi.habitability, seen(pi.turn, now))
+		if pi.surveyed != 0 {
+			_, _ = fmt.Fprintf(w, "        (survey %s\n", seen(pi.surveyed, now))
+			for _, d := range pi.deposits {
+				_, _ = fmt.Fprintf(w, "          (deposit (id %q) (kind %s) (remaining %s) (yield %s) (unlimited %v))\n", d.id, d.kind, utils.Commas(d.remaining), utils.Percentage(d.yield), d.unlimited)
+			}
+			_, _ = fmt.Fprintf(w, "        )\n")
+		}
+		_, _ = fmt.Fprintf(w, "      )\n")
+	}
+	var colonyIDs []string
+	for id := range in.colonies {
+		colonyIDs = append(colonyIDs, id)
+	}
+	sort.Strings(colonyIDs)
+	for _, id := range colonyIDs {
+		ci := in.colonies[id]
+		_, _ = fmt.Fprintf(w, "      (colony (id %q) (polity %q) (number %q) (kind %s) (orbit %q) (population %s) %s\n", id, ci.polity, ci.number, ci.kind, ci.orbit, utils.Commas(ci.population), seen(ci.turn, now))
+		if ci.probed != 0 {
+			_, _ = fmt.Fprintf(w, "        (probe %s\n", seen(ci.probed, now))
+			for _, u := range ci.units {
+				_, _ = fmt.Fprintf(w, "          %s\n", u.Sexpr())
+			}
+			_, _ = fmt.Fprintf(w, "        )\n")
+		}
+		_, _ = fmt.Fprintf(w, "      )\n")
+	}
+	var shipIDs []string
+	for id := range in.ships {
+		shipIDs = append(shipIDs, id)
+	}
+	sort.Strings(shipIDs)
+	for _, id := range shipIDs {
+		si := in.ships[id]
+		_, _ = fmt.Fprintf(w, "      (ship (id %q) (polity %q) (number %q) (location %q) (mass %.1f) %s\n", id, si.polity, si.number, si.location, si.mass, seen(si.turn, now))
+		if si.probed != 0 {
+			_, _ = fmt.Fprintf(w, "        (probe %s\n", seen(si.probed, now))
+			for _, u := range si.units {
+				_, _ = fmt.Fprintf(w, "          %s\n", u.Sexpr())
+			}
+			_, _ = fmt.Fprintf(w, "        )\n")
+		}
+		_, _ = fmt.Fprintf(w, "      )\n")
+	}
+	_, _ = fmt.Fprintf(w, "    ) ;; intel\n")
+}
@@ -0,0 +1,92 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"bytes"
+	"github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func Test_IntelShare(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	suisei := st.Planet("suisei")
+
+	st.turn = 1
+	usagi.intel.surveyPlanet(st.turn, suisei)
+	usagi.intel.observeColony(st.turn, st.Colony("sanuki"), true)
+	st.turn = 2
+	kuma.intel.observePlanet(st.turn, suisei)
+	kuma.intel.observeColony(st.turn, st.Colony("sanuki"), false)
+
+	usagi.intel.share(kuma.intel)
+	pi := kuma.intel.planets["suisei"]
+	is.Equal(pi.turn, 2)     // newer observation is kept
+	is.Equal(pi.surveyed, 1) // survey is copied
+	is.Equal(len(pi.deposits), len(suisei.deposits))
+	ci := kuma.intel.colonies["sanuki"]
+	is.Equal(ci.turn, 2)   // newer observation is kept
+	is.Equal(ci.probed, 1) // probe is copied
+	is.Equal(len(ci.units), len(st.Colony("sanuki").units))
+
+	kuma.intel.share(usagi.intel)
+	is.Equal(usagi.intel.planets["suisei"].turn, 2) // older observation is replaced
+	is.Equal(usagi.intel.planets["suisei"].surveyed, 1)
+
+	// shared observations are copies
+	ci.units[0].Quantity = 0
+	is.True(usagi.intel.colonies["sanuki"].units[0].Quantity != 0)
+}
+
+func Test_IntelReport(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi := st.Polity("usagi")
+	st.turn = 1
+	usagi.intel.observeSystem(st.turn, st.System("mizugame"))
+	st.turn = 2
+	usagi.intel.surveyPlanet(st.turn, st.Planet("suisei"))
+
+	var b bytes.Buffer
+	usagi.intel.report(&b, st.turn, usagi.names)
+	out := b.String()
+	is.True(strings.Contains(out, `(system (id "mizugame")`))
+	is.True(strings.Contains(out, "(turn 1) (stale)"))      // earlier observations are marked
+	is.True(strings.Contains(out, `(survey (turn 2)`+"\n")) // current observations are not
+	is.Equal(strings.Count(out, "(deposit "), 4)
+
+	b.Reset()
+	(*intel)(nil).report(&b, st.turn, usagi.names)
+	is.Equal(b.Len(), 0) // nothing to report
+}
+
+func Test_Seen(t *testing.T) {
+	for _, tc := range []struct {
+		turn, now int
+		want      string
+	}{
+		{3, 3, "(turn 3)"},
+		{2, 3, "(turn 2) (stale)"},
+	} {
+		is.New(t).Equal(seen(tc.turn, tc.now), tc.want)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+)
+
+// jumpRangeFactor scales engine thrust to light years of range per
+// unit of ship mass.
+const jumpRangeFactor = 1_000
+
+// jumpFuelFactor is the mass, in mass units, that one unit of fuel will
+// carry one light year with tech level 1 engines.
+const jumpFuelFactor = 100
+
+// String implements the stringer interface.
+func (c Coords) String() string {
+	return fmt.Sprintf("%02d-%02d-%02d", c.X, c.Y, c.Z)
+}
+
+// distance returns the distance, in light years, between two coordinates.
+func (c Coords) distance(to Coords) float64 {
+	dx, dy, dz := float64(to.X-c.X), float64(to.Y-c.Y), float64(to.Z-c.Z)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// location returns the stellar coordinates of the system.
+func (s *System) location() Coords {
+	return Coords{X: s.coords.x, Y: s.coords.y, Z: s.coords.z}
+}
+
+// systemAt returns the system at the coordinates, or nil if that
+// location is deep space.
+func (st *State) systemAt(c Coords) *System {
+	for _, s := range st.systems {
+		if s.location() == c {
+			return s
+		}
+	}
+	return nil
+}
+
+// location returns the stellar coordinates of the ship.
+func (s *Ship) location() Coords {
+	if s.system != nil {
+		return s.system.location()
+	}
+	return s.coords
+}
+
+// locationName returns the name of the system the ship is in, or
+// its coordinates if it is in deep space.
+func (s *Ship) locationName() string {
+	if s.system != nil {
+		return s.system.name
+	}
+	return "deep space " + s.coords.String()
+}
+
+// mass returns the total mass of the ship, including units, cargo,
+// population, and factories.
+func (s *Ship) mass() float64 {
+	mass := Unit{Kind: POPULATION, Quantity: s.population.total}.Mass()
+	for _, u := range s.units {
+		mass += u.Mass()
+	}
+	for _, g := range s.factories {
+		mass += g.units.Mass()
+	}
+	mass += Unit{Kind: FOOD, Quantity: s.storage.food}.Mass()
+	mass += Unit{Kind: FUEL, Quantity: s.storage.fuel}.Mass()
+	mass += Unit{Kind: GOLD, Quantity: s.storage.gold}.Mass()
+	mass += Unit{Kind: METAL, Quantity: s.storage.metal}.Mass()
+	mass += Unit{Kind: NONMETAL, Quantity: s.storage.nonmetal}.Mass()
+	return mass
+}
+
+// engines returns the thrust of the assembled engines on the ship
+// and their average tech level.
+func (s *Ship) engines() (thrust int, techLevel float64) {
+	var quantity int
+	for _, u := range s.units {
+		if u.Kind == ENGINE && u.Assembled {
+			thrust += u.TechLevel * u.Quantity
+			quantity += u.Quantity
+		}
+	}
+	if quantity == 0 {
+		return 0, 0
+	}
+	return thrust, float64(thrust) / float64(quantity)
+}
+
+// jumpRange returns the farthest distance, in light years, that the
+// ship can jump given its engines and mass.
+func (s *Ship) jumpRange() float64 {
+	thrust, _ := s.engines()
+	mass := s.mass()
+	if thrust == 0 || mass <= 0 {
+		return 0
+	}
+	return float64(thrust) * jumpRangeFactor / mass
+}
+
+// jumpFuel returns the fuel needed for the ship to jump the distance.
+// Better engines burn less fuel.
+func (s *Ship) jumpFuel(distance float64) int {
+	_, techLevel := s.engines()
+	if techLevel == 0 {
+		return 0
+	}
+	return int(math.Ceil(distance * s.mass() / (jumpFuelFactor * techLevel)))
+}
+
+// Jump moves a ship to another system or to a location in deep space.
+//
+// 1. Ship identified by ShipID must accept orders from the polity issuing the order.
+// 2. The ship must have assembled engines.
+// 3. Offset must not be less than zero.
+// 4. Fuel is taken from the ship's storage. The amount depends on the
+// distance, the mass of the ship, and the tech level of its engines.
+// 5. If the destination is beyond the ship's range or its fuel, the ship
+// misjumps and arrives in deep space short of the destination.
+// 6. A ship without fuel, or that can't get far enough to leave its
+// current location, does not jump.
+// 7. Arriving ships are placed at the jump point, at the offset from the destination.
+// Any move in progress is cancelled.
+func (st *State) Jump(issuedByID, shipID string, coords Coords, offset int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Jump: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	ship := st.Ship(shipID)
+	if ship == nil {
+		return fieldError("ship_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
+	} else if !ship.acceptsOrdersFrom(issuedBy) {
+		return fieldError("ship_id", fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN))
+	} else if offset < 0 {
+		return fieldError("offset", fmt.Errorf("invalid offset %d: %w", offset, ERRBADREQUEST))
+	} else if thrust, _ := ship.engines(); thrust == 0 {
+		return fieldError("ship_id", fmt.Errorf("ship has no engines: %w", ERRBADREQUEST))
+	} else if ship.storage.fuel == 0 {
+		return fieldError("ship_id", fmt.Errorf("ship has no fuel: %w", ERRBADREQUEST))
+	}
+
+	from, origin := ship.locationName(), ship.location()
+	distance := origin.distance(coords)
+	if distance == 0 {
+		return fieldError("coords", fmt.Errorf("ship is already at %s: %w", coords, ERRBADREQUEST))
+	}
+
+	// the ship can travel only as far as its engines and fuel allow
+	reach := math.Min(distance, ship.jumpRange())
+	if fuel := ship.jumpFuel(reach); fuel > ship.storage.fuel {
+		reach = reach * float64(ship.storage.fuel) / float64(fuel)
+	}
+	if reach == 0 {
+		return fieldError("coords", fmt.Errorf("ship can not reach %s: %w", coords, ERRBADREQUEST))
+	}
+
+	arrival, misjump := coords, reach < distance
+	if misjump {
+		fraction := reach / distance
+		arrival = Coords{
+			X: origin.X + int(math.Round(fraction*float64(coords.X-origin.X))),
+			Y: origin.Y + int(math.Round(fraction*float64(coords.Y-origin.Y))),
+			Z: origin.Z + int(math.Round(fraction*float64(coords.Z-origin.Z))),
+		}
+		if arrival == origin {
+			// too short a jump to leave the location; keep the fuel
+			return fieldError("coords", fmt.Errorf("ship can not get far enough toward %s: %w", coords, ERRBADREQUEST))
+		}
+	}
+	fuel := minInt(ship.jumpFuel(reach), ship.storage.fuel)
+	ship.storage.fuel -= fuel
+	ship.setOrbit(nil)
+	ship.system, ship.coords, ship.offset, ship.moving = st.systemAt(arrival), arrival, offset, nil
+
+	p := ship.polity
+	if misjump {
+		p.eventf(SHIPJUMPED, []string{shipID}, "%s: misjumped from %s toward %s, arriving at %s after %.1f of %.1f light years (%s fuel)", shipID, from, coords, ship.locationName(), reach, distance, utils.Commas(fuel))
+	} else {
+		p.eventf(SHIPJUMPED, []string{shipID}, "%s: jumped from %s to %s, %.1f light years (%s fuel)", shipID, from, ship.locationName(), distance, utils.Commas(fuel))
+	}
+	if offset != 0 {
+		p.logf("%s: arrived %d tactical units from %s", shipID, offset, ship.locationName())
+	}
+	return nil
+}
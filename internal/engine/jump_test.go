@@ -0,0 +1,100 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_Jump(t *testing.T) {
+	mizugame := Coords{X: 1, Y: 1, Z: 1}
+	for _, tc := range []struct {
+		name    string
+		engines int // assembled ENGINE-1
+		fuel    int
+		metal   int // cargo that adds to the mass of the ship
+		to      Coords
+		offset  int
+		err     error
+		arrival Coords
+		left    int // fuel left after the jump
+	}{
+		{"jumps", 10, 100, 0, Coords{X: 1, Y: 1, Z: 5}, 0, nil, Coords{X: 1, Y: 1, Z: 5}, 92},
+		{"arrives at offset", 10, 100, 0, Coords{X: 1, Y: 1, Z: 5}, 3, nil, Coords{X: 1, Y: 1, Z: 5}, 92},
+		{"misjumps short of fuel", 10, 1, 0, Coords{X: 1, Y: 1, Z: 11}, 0, nil, Coords{X: 1, Y: 1, Z: 2}, 0},
+		{"misjumps farther with more fuel", 10, 3, 0, Coords{X: 1, Y: 1, Z: 11}, 0, nil, Coords{X: 1, Y: 1, Z: 4}, 0},
+		{"no fuel", 10, 0, 0, Coords{X: 1, Y: 1, Z: 5}, 0, ERRBADREQUEST, mizugame, 0},
+		{"too short to leave", 10, 1, 200, Coords{X: 1, Y: 1, Z: 31}, 0, ERRBADREQUEST, mizugame, 1},
+		{"no engines", 0, 100, 0, Coords{X: 1, Y: 1, Z: 5}, 0, ERRBADREQUEST, mizugame, 100},
+		{"already there", 10, 100, 0, mizugame, 0, ERRBADREQUEST, mizugame, 100},
+		{"negative offset", 10, 100, 0, Coords{X: 1, Y: 1, Z: 5}, -1, ERRBADREQUEST, mizugame, 100},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1", Unit{Kind: ENGINE, TechLevel: 1, Quantity: tc.engines, Assembled: true})
+			s.storage.fuel, s.storage.metal = tc.fuel, tc.metal
+			err := st.Jump("usagi", "S1", tc.to, tc.offset)
+			is.True(errors.Is(err, tc.err))         // error
+			is.Equal(s.location(), tc.arrival)      // where the ship ended up
+			is.Equal(s.storage.fuel, tc.left)       // fuel left
+			is.Equal(s.orbit == nil, tc.err == nil) // ships leave orbit only when they jump
+			if tc.err == nil {
+				is.Equal(s.system, (*System)(nil)) // arrived in deep space
+				is.Equal(s.offset, tc.offset)
+			}
+		})
+	}
+}
+
+func Test_JumpAuthority(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	mkrival(st, "kuma")
+	s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1", Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true})
+	s.storage.fuel = 100
+	to := Coords{X: 1, Y: 1, Z: 5}
+	is.True(errors.Is(st.Jump("usagi", "S2", to, 0), ERRBADREQUEST)) // unknown ship
+	is.True(errors.Is(st.Jump("kuma", "S1", to, 0), ERRFORBIDDEN))   // ship refuses a rival
+	is.Equal(st.Jump("nobody", "S1", to, 0), ERRBUG)                 // issuer must exist
+}
+
+func Test_JumpRange(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		units   []Unit
+		jumpRng float64
+		fuel    int // fuel needed to jump 10 light years
+	}{
+		{"no engines", nil, 0, 0},
+		{"tech level 1", []Unit{{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true}}, 100, 10},
+		{"tech level 2 is heavier but burns less fuel", []Unit{{Kind: ENGINE, TechLevel: 2, Quantity: 10, Assembled: true}}, 20_000.0 / 120, 6},
+		{"unassembled engines", []Unit{{Kind: ENGINE, TechLevel: 1, Quantity: 10}}, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1", tc.units...)
+			is.Equal(s.jumpRange(), tc.jumpRng)
+			is.Equal(s.jumpFuel(10), tc.fuel)
+		})
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/internal/lists"
+	"strconv"
+	"strings"
+)
+
+// SystemView is what a polity can see of a system it knows about.
+type SystemView struct {
+	ID       string
+	Name     string // the polity's name for the system
+	Coords   Coords
+	Colonies int // number of the polity's colonies in the system
+	Ships    int // number of the polity's ships in the system
+}
+
+// within returns a test for the "within" filter, which looks like
+// "10ly of 12-04-07". The test returns true if the coordinates are no
+// farther than the distance from the origin. It always returns true if
+// the filter is not set.
+func within(opts lists.Options) (func(Coords) bool, error) {
+	value, ok := opts.Filter["within"]
+	if !ok {
+		return func(Coords) bool { return true }, nil
+	}
+	fields := strings.Fields(value)
+	if len(fields) != 3 || fields[1] != "of" || !strings.HasSuffix(fields[0], "ly") {
+		return nil, fmt.Errorf("invalid filter within %q: %w", value, ERRBADREQUEST)
+	}
+	distance, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "ly"), 64)
+	if err != nil || distance < 0 {
+		return nil, fmt.Errorf("invalid filter within %q: %w", value, ERRBADREQUEST)
+	}
+	origin, err := parseCoords(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	return func(c Coords) bool { return origin.distance(c) <= distance }, nil
+}
+
+// badOption returns an error from the list options as a bad request.
+func badOption(err error) error {
+	return fmt.Errorf("%v: %w", err, ERRBADREQUEST)
+}
+
+// parseCoords returns the coordinates from a string like "12-04-07".
+func parseCoords(s string) (Coords, error) {
+	fields := strings.Split(s, "-")
+	if len(fields) != 3 {
+		return Coords{}, fmt.Errorf("invalid coordinates %q: %w", s, ERRBADREQUEST)
+	}
+	var xyz [3]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return Coords{}, fmt.Errorf("invalid coordinates %q: %w", s, ERRBADREQUEST)
+		}
+		xyz[i] = n
+	}
+	return Coords{X: xyz[0], Y: xyz[1], Z: xyz[2]}, nil
+}
+
+// compareCoords orders coordinates by x, then y, then z.
+func compareCoords(a, b Coords) int {
+	if c := lists.CompareInts(a.X, b.X); c != 0 {
+		return c
+	} else if c = lists.CompareInts(a.Y, b.Y); c != 0 {
+		return c
+	}
+	return lists.CompareInts(a.Z, b.Z)
+}
+
+// ListSystems returns a page of the systems that a polity knows about,
+// and the number of systems that matched the filters.
+//
+// 1. Sort keys are "name" and "coordinates". The default is by id.
+// 2. Filters are "owner" ("me" for systems with the polity's colonies
+// or ships) and "within" (for example "10ly of 12-04-07").
+func (st *State) ListSystems(polityID string, opts lists.Options) ([]SystemView, int, error) {
+	p := st.Polity(polityID)
+	if p == nil {
+		return nil, 0, fmt.Errorf("invalid polity %q: %w", polityID, ERRNOTFOUND)
+	}
+	if err := opts.CheckFilters("owner", "within"); err != nil {
+		return nil, 0, badOption(err)
+	}
+	ownerIsMe, err := opts.OwnerIsMe()
+	if err != nil {
+		return nil, 0, badOption(err)
+	}
+	inRange, err := within(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// a polity knows the systems in its intel and the systems its assets are in
+	known := make(map[string]*SystemView)
+	view := func(s *System) *SystemView {
+		if v, ok := known[s.id]; ok {
+			return v
+		}
+		v := &SystemView{ID: s.id, Name: p.names.nameOf(s.id, s.name), Coords: s.location()}
+		known[s.id] = v
+		return v
+	}
+	for id := range p.intel.systems {
+		if s, ok := st.systems[id]; ok {
+			view(s)
+		}
+	}
+	for _, c := range p.controls.colonies {
+		if c.system != nil {
+			view(c.system).Colonies++
+		}
+	}
+	for _, s := range p.controls.ships {
+		if s.system != nil {
+			view(s.system).Ships++
+		}
+	}
+
+	list := []SystemView{}
+	for _, v := range known {
+		if ownerIsMe && v.Colonies == 0 && v.Ships == 0 {
+			continue
+		} else if !inRange(v.Coords) {
+			continue
+		}
+		list = append(list, *v)
+	}
+	err = lists.Sort(list, opts.Sort, map[string]lists.Comparer{
+		"name":        func(i, j int) int { return strings.Compare(list[i].Name, list[j].Name) },
+		"coordinates": func(i, j int) int { return compareCoords(list[i].Coords, list[j].Coords) },
+	}, func(i, j int) int { return strings.Compare(list[i].ID, list[j].ID) })
+	if err != nil {
+		return nil, 0, badOption(err)
+	}
+	lo, hi := opts.Page(len(list))
+	return list[lo:hi], len(list), nil
+}
+
+// ListColonies returns a page of the colonies that a polity controls,
+// and the number of colonies that matched the filters.
+//
+// 1. Sort keys are "name", "population", and "coordinates". The default is by id.
+// 2. Filters are "owner" (the only colonies listed are the polity's, so
+// "me" is accepted but has no effect), "kind", and "within".
+func (st *State) ListColonies(polityID string, opts lists.Options) ([]ColonyView, int, error) {
+	views, err := st.ColonyViews(polityID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := opts.CheckFilters("owner", "kind", "within"); err != nil {
+		return nil, 0, badOption(err)
+	} else if _, err := opts.OwnerIsMe(); err != nil {
+		return nil, 0, badOption(err)
+	}
+	inRange, err := within(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	kind, filterKind := opts.Filter["kind"]
+	if filterKind {
+		if _, ok := colonyKindFromString(kind); !ok {
+			return nil, 0, fmt.Errorf("invalid filter kind %q: %w", kind, ERRBADREQUEST)
+		}
+	}
+
+	list := []ColonyView{}
+	coords := make(map[string]Coords)
+	for _, v := range views {
+		c := st.Colony(v.ID)
+		if c.system != nil {
+			coords[v.ID] = c.system.location()
+		}
+		if filterKind && !strings.EqualFold(v.Kind, kind) {
+			continue
+		} else if !inRange(coords[v.ID]) {
+			continue
+		}
+		list = append(list, v)
+	}
+	err = lists.Sort(list, opts.Sort, map[string]lists.Comparer{
+		"name":        func(i, j int) int { return strings.Compare(list[i].Name, list[j].Name) },
+		"population":  func(i, j int) int { return lists.CompareInts(list[i].Population, list[j].Population) },
+		"coordinates": func(i, j int) int { return compareCoords(coords[list[i].ID], coords[list[j].ID]) },
+	}, func(i, j int) int { return strings.Compare(list[i].ID, list[j].ID) })
+	if err != nil {
+		return nil, 0, badOption(err)
+	}
+	lo, hi := opts.Page(len(list))
+	return list[lo:hi], len(list), nil
+}
+
+// ListShips returns a page of the ships that a polity controls,
+// and the number of ships that matched the filters.
+//
+// 1. Sort keys are "name", "population", and "coordinates". The default is by id.
+// 2. Filters are "owner" (the only ships listed are the polity's, so
+// "me" is accepted but has no effect) and "within". Ships in deep space
+// are filtered by their own coordinates.
+func (st *State) ListShips(polityID string, opts lists.Options) ([]ShipView, int, error) {
+	views, err := st.ShipViews(polityID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := opts.CheckFilters("owner", "within"); err != nil {
+		return nil, 0, badOption(err)
+	} else if _, err := opts.OwnerIsMe(); err != nil {
+		return nil, 0, badOption(err)
+	}
+	inRange, err := within(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list := []ShipView{}
+	coords := make(map[string]Coords)
+	for _, v := range views {
+		coords[v.ID] = st.Ship(v.ID).location()
+		if !inRange(coords[v.ID]) {
+			continue
+		}
+		list = append(list, v)
+	}
+	err = lists.Sort(list, opts.Sort, map[string]lists.Comparer{
+		"name":        func(i, j int) int { return strings.Compare(list[i].Name, list[j].Name) },
+		"population":  func(i, j int) int { return lists.CompareInts(list[i].Population, list[j].Population) },
+		"coordinates": func(i, j int) int { return compareCoords(coords[list[i].ID], coords[list[j].ID]) },
+	}, func(i, j int) int { return strings.Compare(list[i].ID, list[j].ID) })
+	if err != nil {
+		return nil, 0, badOption(err)
+	}
+	lo, hi := opts.Page(len(list))
+	return list[lo:hi], len(list), nil
+}
@@ -0,0 +1,164 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"github.com/mdhender/server/internal/lists"
+	"testing"
+)
+
+func Test_ListSystems(t *testing.T) {
+	// usagi knows mizugame (01-01-01) with sanuki, shikoku (05-01-01,
+	// named "Alpha") with tosa, and kyushu (30-01-01, named "Beta"),
+	// which has none of its colonies or ships
+	st, _ := Make()
+	usagi := st.Polity("usagi")
+	shikoku, kyushu := mktestsystem(st, "shikoku", 5, 1, 1), mktestsystem(st, "kyushu", 30, 1, 1)
+	for _, s := range []*System{shikoku, kyushu} {
+		usagi.intel.systems[s.id] = &systemIntel{name: s.name, coords: s.location()}
+	}
+	is := is.New(t)
+	is.NoErr(usagi.names.assign("shikoku", "Alpha"))
+	is.NoErr(usagi.names.assign("kyushu", "Beta"))
+	st.Colony("tosa").system = shikoku
+	for _, tc := range []struct {
+		name   string
+		polity string
+		opts   lists.Options
+		err    error
+		ids    []string
+		total  int
+	}{
+		{"default is by id", "usagi", lists.Options{}, nil, []string{"kyushu", "mizugame", "shikoku"}, 3},
+		{"by name", "usagi", lists.Options{Sort: []string{"name"}}, nil, []string{"mizugame", "shikoku", "kyushu"}, 3},
+		{"by name descending", "usagi", lists.Options{Sort: []string{"-name"}}, nil, []string{"kyushu", "shikoku", "mizugame"}, 3},
+		{"by coordinates descending", "usagi", lists.Options{Sort: []string{"-coordinates"}}, nil, []string{"kyushu", "shikoku", "mizugame"}, 3},
+		{"owner me", "usagi", lists.Options{Filter: map[string]string{"owner": "me"}}, nil, []string{"mizugame", "shikoku"}, 2},
+		{"owner any", "usagi", lists.Options{Filter: map[string]string{"owner": "any"}}, nil, []string{"kyushu", "mizugame", "shikoku"}, 3},
+		{"within", "usagi", lists.Options{Filter: map[string]string{"within": "4ly of 01-01-01"}}, nil, []string{"mizugame", "shikoku"}, 2},
+		{"first page", "usagi", lists.Options{Limit: 2}, nil, []string{"kyushu", "mizugame"}, 3},
+		{"second page", "usagi", lists.Options{Offset: 2, Limit: 2}, nil, []string{"shikoku"}, 3},
+		{"page past the end", "usagi", lists.Options{Offset: 5, Limit: 2}, nil, []string{}, 3},
+		{"page after sorting", "usagi", lists.Options{Sort: []string{"-coordinates"}, Offset: 1, Limit: 1}, nil, []string{"shikoku"}, 3},
+		{"invalid sort key", "usagi", lists.Options{Sort: []string{"population"}}, ERRBADREQUEST, nil, 0},
+		{"invalid filter", "usagi", lists.Options{Filter: map[string]string{"kind": "open"}}, ERRBADREQUEST, nil, 0},
+		{"invalid owner", "usagi", lists.Options{Filter: map[string]string{"owner": "kuma"}}, ERRBADREQUEST, nil, 0},
+		{"invalid within", "usagi", lists.Options{Filter: map[string]string{"within": "near 01-01-01"}}, ERRBADREQUEST, nil, 0},
+		{"unknown polity", "tora", lists.Options{}, ERRNOTFOUND, nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			list, total, err := st.ListSystems(tc.polity, tc.opts)
+			is.True(errors.Is(err, tc.err)) // error
+			if err != nil {
+				return
+			}
+			ids := []string{}
+			for _, v := range list {
+				ids = append(ids, v.ID)
+			}
+			is.Equal(ids, tc.ids)
+			is.Equal(total, tc.total)
+		})
+	}
+}
+
+func Test_ListColonies(t *testing.T) {
+	// sanuki is in mizugame (01-01-01) and tosa is in shikoku (05-01-01)
+	st, _ := Make()
+	sanuki, tosa := st.Colony("sanuki"), st.Colony("tosa")
+	sanuki.population.total, tosa.population.total = 500, 100
+	tosa.system = mktestsystem(st, "shikoku", 5, 1, 1)
+	for _, tc := range []struct {
+		name  string
+		opts  lists.Options
+		err   error
+		ids   []string
+		total int
+	}{
+		{"default is by id", lists.Options{}, nil, []string{"sanuki", "tosa"}, 2},
+		{"by population", lists.Options{Sort: []string{"population"}}, nil, []string{"tosa", "sanuki"}, 2},
+		{"by coordinates descending", lists.Options{Sort: []string{"-coordinates"}}, nil, []string{"tosa", "sanuki"}, 2},
+		{"kind", lists.Options{Filter: map[string]string{"kind": "enclosed"}}, nil, []string{"tosa"}, 1},
+		{"within", lists.Options{Filter: map[string]string{"within": "1ly of 01-01-01"}}, nil, []string{"sanuki"}, 1},
+		{"owner me has no effect", lists.Options{Filter: map[string]string{"owner": "me"}}, nil, []string{"sanuki", "tosa"}, 2},
+		{"second page", lists.Options{Offset: 1, Limit: 1}, nil, []string{"tosa"}, 2},
+		{"invalid sort key", lists.Options{Sort: []string{"-size"}}, ERRBADREQUEST, nil, 0},
+		{"invalid kind", lists.Options{Filter: map[string]string{"kind": "floating"}}, ERRBADREQUEST, nil, 0},
+		{"invalid owner", lists.Options{Filter: map[string]string{"owner": "kuma"}}, ERRBADREQUEST, nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			list, total, err := st.ListColonies("usagi", tc.opts)
+			is.True(errors.Is(err, tc.err)) // error
+			if err != nil {
+				return
+			}
+			ids := []string{}
+			for _, v := range list {
+				ids = append(ids, v.ID)
+			}
+			is.Equal(ids, tc.ids)
+			is.Equal(total, tc.total)
+		})
+	}
+}
+
+func Test_ListShips(t *testing.T) {
+	// S1 and S2 are in mizugame (01-01-01) and S3 is in deep space at 20-01-01
+	st, _ := Make()
+	for id, pop := range map[string]int{"S1": 30, "S2": 10, "S3": 20} {
+		s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), id)
+		s.population.total = pop
+	}
+	s3 := st.Ship("S3")
+	s3.system, s3.orbit, s3.coords = nil, nil, Coords{X: 20, Y: 1, Z: 1}
+	for _, tc := range []struct {
+		name  string
+		opts  lists.Options
+		err   error
+		ids   []string
+		total int
+	}{
+		{"default is by id", lists.Options{}, nil, []string{"S1", "S2", "S3"}, 3},
+		{"by population", lists.Options{Sort: []string{"population"}}, nil, []string{"S2", "S3", "S1"}, 3},
+		{"by coordinates descending", lists.Options{Sort: []string{"-coordinates"}}, nil, []string{"S3", "S1", "S2"}, 3},
+		{"deep space is filtered by the ship's coordinates", lists.Options{Filter: map[string]string{"within": "1ly of 20-01-01"}}, nil, []string{"S3"}, 1},
+		{"last page", lists.Options{Sort: []string{"-population"}, Offset: 2, Limit: 2}, nil, []string{"S2"}, 3},
+		{"invalid sort key", lists.Options{Sort: []string{"name", "mass"}}, ERRBADREQUEST, nil, 0},
+		{"invalid filter", lists.Options{Filter: map[string]string{"kind": "open"}}, ERRBADREQUEST, nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			list, total, err := st.ListShips("usagi", tc.opts)
+			is.True(errors.Is(err, tc.err)) // error
+			if err != nil {
+				return
+			}
+			ids := []string{}
+			for _, v := range list {
+				ids = append(ids, v.ID)
+			}
+			is.Equal(ids, tc.ids)
+			is.Equal(total, tc.total)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"math"
+	"sort"
+)
+
+// messageLightYearsPerTurn is how far a message travels in one turn.
+const messageLightYearsPerTurn = 10
+
+// message is text sent from a ship or colony to a ship or colony.
+// It is held by the state until it is delivered to the target's inbox.
+type message struct {
+	id        string
+	from      *Polity
+	to        *Polity // set only for notices; see recipient
+	sourceID  string
+	targetID  string
+	text      Text // sanitized before it is stored
+	sent      int  // turn the message was sent
+	delivered int  // turn the message is delivered
+	read      bool
+}
+
+// InboxMessage is a message in a polity's inbox.
+type InboxMessage struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`      // id of the polity that sent the message
+	SourceID  string `json:"source_id"` // id of the ship or colony that sent the message
+	TargetID  string `json:"target_id"` // id of the ship or colony the message was sent to
+	Text      string `json:"text"`
+	Sent      int    `json:"sent"`      // turn the message was sent
+	Delivered int    `json:"delivered"` // turn the message was delivered
+	Read      bool   `json:"read"`
+}
+
+// Message sends text from a ship or colony to a ship or colony.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. Target identified by TargetID must be a ship or colony.
+// 3. The issuer must stand at least as an ACQUAINTANCE with the polity controlling the target.
+// 4. Text must not be empty and is truncated at 200 runes.
+// 5. The message travels ten light years a turn and is delivered when it arrives.
+// 6. The message is delivered to the polity controlling the target when it arrives.
+func (st *State) Message(issuedByID, sourceID, targetID string, text Text) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Message: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	target, err := st.findTarget(targetID)
+	if err != nil {
+		return fieldError("target_id", err)
+	}
+	if !issuedBy.permits(target.polity(), ACQUAINTANCE) {
+		return fieldError("target_id", fmt.Errorf("target is not an acquaintance: %w", ERRFORBIDDEN))
+	}
+	if text = text.TrimSpace().Truncate(200); text.Length() == 0 {
+		return fieldError("text", fmt.Errorf("invalid text: %w", ERRBADREQUEST))
+	}
+
+	delay := int(math.Ceil(d.location().distance(target.location()) / messageLightYearsPerTurn))
+	st.mail = append(st.mail, &message{
+		id:        uuid.New().String(),
+		from:      issuedBy,
+		sourceID:  sourceID,
+		targetID:  targetID,
+		text:      text.Sanitize(),
+		sent:      st.turn,
+		delivered: st.turn + delay,
+	})
+	return nil
+}
+
+// notify sends a notice from one polity to another. Notices are not
+// carried by ships or colonies, so they arrive on the turn they are sent.
+func (st *State) notify(from, to *Polity, text string) {
+	st.mail = append(st.mail, &message{
+		id:        uuid.New().String(),
+		from:      from,
+		to:        to,
+		sourceID:  from.id,
+		targetID:  to.id,
+		text:      Text{text: text}.Sanitize(),
+		sent:      st.turn,
+		delivered: st.turn,
+	})
+}
+
+// recipient returns the polity that receives the message. Notices go to
+// the polity they were sent to. Messages go to the polity controlling the
+// target when they arrive, or nil if the target no longer exists.
+func (st *State) recipient(m *message) *Polity {
+	if m.to != nil {
+		return m.to
+	}
+	target, err := st.findTarget(m.targetID)
+	if err != nil {
+		return nil
+	}
+	return target.polity()
+}
+
+// deliverMessages moves the messages that have arrived to their inboxes.
+func (st *State) deliverMessages() {
+	var inTransit []*message
+	for _, m := range st.mail {
+		if m.delivered > st.turn {
+			inTransit = append(inTransit, m)
+			continue
+		}
+		to := st.recipient(m)
+		if to == nil {
+			m.from.logf("%s: message to %s was lost", m.sourceID, m.targetID)
+			continue
+		}
+		to.inbox = append(to.inbox, m)
+		to.eventf(MESSAGEDELIVERED, []string{m.id, m.targetID}, "%s: message received from %s", m.targetID, m.from.name)
+	}
+	st.mail = inTransit
+}
+
+// Inbox returns the messages delivered to a polity, oldest first.
+// If unreadOnly is set, messages that have been read are skipped.
+func (st *State) Inbox(polityID string, unreadOnly bool) ([]InboxMessage, error) {
+	p := st.Polity(polityID)
+	if p == nil {
+		return nil, fmt.Errorf("invalid polity %q: %w", polityID, ERRNOTFOUND)
+	}
+	messages := []InboxMessage{}
+	for _, m := range p.inbox {
+		if unreadOnly && m.read {
+			continue
+		}
+		messages = append(messages, InboxMessage{
+			ID:        m.id,
+			From:      m.from.id,
+			SourceID:  m.sourceID,
+			TargetID:  m.targetID,
+			Text:      m.text.String(),
+			Sent:      m.sent,
+			Delivered: m.delivered,
+			Read:      m.read,
+		})
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Delivered < messages[j].Delivered
+	})
+	return messages, nil
+}
+
+// MarkMessage sets the read state of a message in a polity's inbox.
+func (st *State) MarkMessage(polityID, messageID string, read bool) error {
+	p := st.Polity(polityID)
+	if p == nil {
+		return fmt.Errorf("invalid polity %q: %w", polityID, ERRNOTFOUND)
+	}
+	for _, m := range p.inbox {
+		if m.id == messageID {
+			m.read = read
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid message %q: %w", messageID, ERRNOTFOUND)
+}
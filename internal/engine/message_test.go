@@ -0,0 +1,149 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func Test_Message(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   DiplomaticStatus
+		source   string
+		target   string
+		text     string
+		err      error
+		want     string
+		delivery int
+	}{
+		{"across the cluster", ACQUAINTANCE, "tosa", "K1", "hello", nil, "hello", 3},
+		{"same system", ACQUAINTANCE, "tosa", "sanuki", "hello", nil, "hello", 0},
+		{"trimmed", ACQUAINTANCE, "tosa", "K1", "  hello  ", nil, "hello", 3},
+		{"truncated", ACQUAINTANCE, "tosa", "K1", strings.Repeat("x", 201), nil, strings.Repeat("x", 200), 3},
+		{"strangers", UNKNOWN, "tosa", "K1", "hello", ERRFORBIDDEN, "", 0},
+		{"empty", ACQUAINTANCE, "tosa", "K1", "   ", ERRBADREQUEST, "", 0},
+		{"unknown target", ACQUAINTANCE, "tosa", "K2", "hello", ERRBADREQUEST, "", 0},
+		{"source refuses", ACQUAINTANCE, "K1", "tosa", "hello", ERRFORBIDDEN, "", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = tc.status, ACQUAINTANCE
+			k1 := mktestship(st, kuma, st.Colony("tosa"), "K1")
+			k1.system, k1.orbit = mktestsystem(st, "hoshi", 1, 1, 25), nil // twenty-four light years from tosa
+			text, err := NewText(tc.text)
+			is.NoErr(err)
+			err = st.Message("usagi", tc.source, tc.target, text)
+			is.True(errors.Is(err, tc.err)) // error
+			if tc.err != nil {
+				is.Equal(len(st.mail), 0) // nothing sent
+				return
+			}
+			is.Equal(len(st.mail), 1)
+			m := st.mail[0]
+			is.Equal(m.text.String(), tc.want)
+			is.Equal(m.delivered, st.turn+tc.delivery) // ten light years a turn
+			is.Equal(m.to, (*Polity)(nil))             // recipient is found on delivery
+		})
+	}
+	is := is.New(t)
+	st, _ := Make()
+	is.Equal(st.Message("nobody", "tosa", "K1", Text{text: "hello"}), ERRBUG)
+}
+
+func Test_DeliverMessages(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = ACQUAINTANCE, ACQUAINTANCE
+	k1 := mktestship(st, kuma, st.Colony("tosa"), "K1")
+	k1.system, k1.orbit = mktestsystem(st, "hoshi", 1, 1, 25), nil
+	is.NoErr(st.Message("usagi", "tosa", "K1", Text{text: "hello"}))
+	for turn := 0; turn < 3; turn++ {
+		st.deliverMessages()
+		is.Equal(len(kuma.inbox), 0) // still in transit
+		st.turn++
+	}
+	st.deliverMessages()
+	is.Equal(len(kuma.inbox), 1) // arrives after three turns
+	is.Equal(len(st.mail), 0)
+}
+
+func Test_DeliverMessagesToController(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = ACQUAINTANCE, ACQUAINTANCE
+	k1 := mktestship(st, kuma, st.Colony("tosa"), "K1")
+	k1.system, k1.orbit = mktestsystem(st, "hoshi", 1, 1, 25), nil
+	tora := mkrival(st, "tora")
+	is.NoErr(st.Message("usagi", "tosa", "K1", Text{text: "hello"}))
+	k1.polity = tora // captured while the message is in transit
+	st.turn = 3
+	st.deliverMessages()
+	is.Equal(len(kuma.inbox), 0)
+	is.Equal(len(tora.inbox), 1) // delivered to whoever controls the target
+
+	is.NoErr(st.Message("usagi", "tosa", "sanuki", Text{text: "hello"}))
+	delete(st.colonies, "sanuki") // target is gone
+	st.deliverMessages()
+	is.Equal(len(usagi.inbox), 0) // lost
+	is.Equal(len(st.mail), 0)
+}
+
+func Test_Notify(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	st.notify(usagi, kuma, "war")
+	st.deliverMessages()
+	is.Equal(len(kuma.inbox), 1) // notices arrive on the turn they are sent
+	is.Equal(kuma.inbox[0].targetID, "kuma")
+}
+
+func Test_Inbox(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	st.notify(usagi, kuma, "first")
+	st.notify(usagi, kuma, "second")
+	st.deliverMessages()
+
+	messages, err := st.Inbox("kuma", false)
+	is.NoErr(err)
+	is.Equal(len(messages), 2)
+	is.Equal(messages[0].Text, "first")
+	is.Equal(messages[0].From, "usagi")
+
+	is.NoErr(st.MarkMessage("kuma", messages[0].ID, true))
+	messages, err = st.Inbox("kuma", true)
+	is.NoErr(err)
+	is.Equal(len(messages), 1) // read messages are skipped
+	is.Equal(messages[0].Text, "second")
+
+	is.True(errors.Is(st.MarkMessage("kuma", "nothing", true), ERRNOTFOUND))
+	is.True(errors.Is(st.MarkMessage("usagi", messages[0].ID, true), ERRNOTFOUND)) // only the polity's own inbox
+	_, err = st.Inbox("nobody", false)
+	is.True(errors.Is(err, ERRNOTFOUND))
+}
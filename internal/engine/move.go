@@ -0,0 +1,155 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+)
+
+// jumpPointRing is the ring used for ships at a system's jump point.
+// It sits just outside the tenth orbit.
+const jumpPointRing = 10
+
+// ringsPerTurn is the number of rings that a ship can cross in one turn.
+const ringsPerTurn = 4
+
+// moveFuelFactor is the mass, in mass units, that one unit of fuel
+// will carry across one ring.
+const moveFuelFactor = 1_000
+
+// setOrbit moves the ship into the orbit, keeping the ships in each
+// orbit consistent. A nil orbit leaves the ship at the jump point.
+func (s *Ship) setOrbit(o *Orbit) {
+	if s.orbit == o {
+		return
+	}
+	s.orbit.delShip(s)
+	s.orbit = o
+	o.addShip(s)
+}
+
+// ring returns the ring of the orbit that the ship is in.
+func (s *Ship) ring() int {
+	if s.orbit == nil {
+		return jumpPointRing
+	}
+	return s.orbit.ring
+}
+
+// orbitAt returns the orbit around the star at the ring, creating
+// an empty orbit if the star doesn't have one there yet.
+func (st *State) orbitAt(star *Star, ring int) *Orbit {
+	if o := star.orbits[ring]; o != nil {
+		return o
+	}
+	return mkorbit(star, ring)
+}
+
+// moveFuel returns the fuel needed for the ship to cross the rings.
+func (s *Ship) moveFuel(rings int) int {
+	return int(math.Ceil(float64(rings) * s.mass() / moveFuelFactor))
+}
+
+// Move sends a ship to another orbit in the system that it is in.
+//
+// 1. Ship identified by ShipID must accept orders from the polity issuing the order.
+// 2. The ship must be in a system and have assembled engines.
+// 3. Orbit must be 1 through 10. Ships at the jump point move around the
+// system's primary star; ships in orbit stay with their star.
+// 4. Offset must not be less than zero.
+// 5. Fuel is charged per ring crossed and depends on the mass of the ship.
+// 6. A ship crosses at most four rings per turn. Longer moves continue
+// on the following turns unless the ship is given a new move or jump.
+func (st *State) Move(issuedByID, shipID string, orbit, offset int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Move: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	ship := st.Ship(shipID)
+	if ship == nil {
+		return fieldError("ship_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
+	} else if !ship.acceptsOrdersFrom(issuedBy) {
+		return fieldError("ship_id", fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN))
+	} else if ship.system == nil || len(ship.system.stars) == 0 {
+		return fieldError("ship_id", fmt.Errorf("ship is not in a system: %w", ERRBADREQUEST))
+	} else if orbit < 1 || orbit > 10 {
+		return fieldError("orbit", fmt.Errorf("invalid orbit %d: %w", orbit, ERRBADREQUEST))
+	} else if offset < 0 {
+		return fieldError("offset", fmt.Errorf("invalid offset %d: %w", offset, ERRBADREQUEST))
+	} else if thrust, _ := ship.engines(); thrust == 0 {
+		return fieldError("ship_id", fmt.Errorf("ship has no engines: %w", ERRBADREQUEST))
+	}
+	ship.moving = &Move{ShipID: shipID, Orbit: orbit, Offset: offset}
+	return st.continueMove(ship)
+}
+
+// continueMove moves the ship toward the orbit that it was ordered to.
+func (st *State) continueMove(ship *Ship) error {
+	if ship.system == nil || len(ship.system.stars) == 0 {
+		ship.moving = nil
+		return fmt.Errorf("ship %q is not in a system: %w", ship.id, ERRBADREQUEST)
+	}
+	star := ship.system.stars[0]
+	if ship.orbit != nil {
+		star = ship.orbit.star
+	}
+	target := ship.moving.Orbit - 1
+
+	from, rings := ship.ring(), ship.ring()-target
+	if rings < 0 {
+		rings = -rings
+	}
+	if rings == 0 {
+		ship.offset, ship.moving = ship.moving.Offset, nil
+		return nil
+	}
+	if rings > ringsPerTurn {
+		rings = ringsPerTurn
+	}
+
+	// the ship only crosses the rings that it has fuel for
+	for rings > 0 && ship.moveFuel(rings) > ship.storage.fuel {
+		rings--
+	}
+	if rings == 0 {
+		ship.moving = nil
+		return fmt.Errorf("ship %q does not have fuel to move: %w", ship.id, ERRBADREQUEST)
+	}
+	fuel := ship.moveFuel(rings)
+	ship.storage.fuel -= fuel
+
+	ring := from - rings
+	if target > from {
+		ring = from + rings
+	}
+	ship.setOrbit(st.orbitAt(star, ring))
+	if ring == target {
+		ship.offset = ship.moving.Offset
+		ship.moving = nil
+		ship.polity.eventf(SHIPMOVED, []string{ship.id, ship.orbit.id}, "%s: moved to orbit %s, %d tactical units out (%s fuel)", ship.id, ship.orbit.name, ship.offset, utils.Commas(fuel))
+	} else {
+		ship.offset = 0
+		ship.polity.eventf(SHIPMOVED, []string{ship.id, ship.orbit.id}, "%s: moving to orbit %d, now at orbit %s (%s fuel)", ship.id, target+1, ship.orbit.name, utils.Commas(fuel))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_Move(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		engines int // assembled ENGINE-1
+		fuel    int
+		metal   int // cargo that adds to the mass of the ship
+		orbit   int
+		offset  int
+		err     error
+		ring    int  // ring of the ship afterwards; tosa is in ring 9
+		moving  bool // move continues next turn
+		left    int  // fuel left after the move
+	}{
+		{"moves", 10, 10, 0, 8, 2, nil, 7, false, 9},
+		{"long move continues", 10, 10, 0, 1, 0, nil, 5, true, 9},
+		{"stays in orbit", 10, 10, 0, 10, 3, nil, 9, false, 10},
+		{"fuel limits rings crossed", 10, 7, 2_900, 1, 0, nil, 7, true, 0},
+		{"no fuel", 10, 0, 0, 8, 0, ERRBADREQUEST, 9, false, 0},
+		{"orbit too low", 10, 10, 0, 0, 0, ERRBADREQUEST, 9, false, 10},
+		{"orbit too high", 10, 10, 0, 11, 0, ERRBADREQUEST, 9, false, 10},
+		{"negative offset", 10, 10, 0, 8, -1, ERRBADREQUEST, 9, false, 10},
+		{"no engines", 0, 10, 0, 8, 0, ERRBADREQUEST, 9, false, 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1", Unit{Kind: ENGINE, TechLevel: 1, Quantity: tc.engines, Assembled: true})
+			s.storage.fuel, s.storage.metal = tc.fuel, tc.metal
+			err := st.Move("usagi", "S1", tc.orbit, tc.offset)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(s.ring(), tc.ring)
+			is.Equal(s.moving != nil, tc.moving)
+			is.Equal(s.storage.fuel, tc.left)
+			if tc.err == nil && !tc.moving {
+				is.Equal(s.offset, tc.offset) // offset applies on arrival
+			}
+		})
+	}
+}
+
+func Test_MoveNotInSystem(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	mkrival(st, "kuma")
+	s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1", Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true})
+	s.storage.fuel = 10
+	is.True(errors.Is(st.Move("usagi", "S2", 8, 0), ERRBADREQUEST)) // unknown ship
+	is.True(errors.Is(st.Move("kuma", "S1", 8, 0), ERRFORBIDDEN))   // ship refuses a rival
+	s.setOrbit(nil)
+	s.system = nil
+	is.True(errors.Is(st.Move("usagi", "S1", 8, 0), ERRBADREQUEST)) // ship is in deep space
+}
+
+func Test_ContinueMove(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1", Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true})
+	s.storage.fuel = 10
+	is.Equal(len(st.Colony("tosa").orbit.ships), 1) // starts in tosa's orbit
+	is.NoErr(st.Move("usagi", "S1", 1, 5))
+	for _, ring := range []int{1, 0} {
+		is.True(s.moving != nil) // still under way
+		is.NoErr(st.continueMove(s))
+		is.Equal(s.ring(), ring)
+	}
+	is.Equal(s.moving, (*Move)(nil)) // arrived
+	is.Equal(s.offset, 5)
+	is.Equal(s.storage.fuel, 7) // one unit of fuel per turn under way
+	is.Equal(len(s.orbit.ships), 1)
+	is.True(s.orbit.ships[0] == s)                  // ship is listed in its new orbit
+	is.Equal(len(st.Colony("tosa").orbit.ships), 0) // and no longer in the old one
+}
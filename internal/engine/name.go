@@ -21,6 +21,7 @@ package engine
 import (
 	"fmt"
 	"log"
+	"reflect"
 	"strings"
 	"unicode/utf8"
 )
@@ -44,59 +45,154 @@ type Name struct {
 // 6. Type must match exactly the type of entity being named.
 // 7. If the entity is a ship, colony, or polity, it must be controlled by the issuer of the order.
 // 8. Names of ships and colonies are not required to be unique.
+// 9. Names of polities must be unique.
 //
 // Note: each Polity maintains its own "database" of unique names for Stars, Star Systems, and Planets.
+// Those names are seen only by the polity (and the allies it shares them with) and may be used in
+// place of the entity's id in the polity's orders.
 func (st *State) Name(issuedByID, entityID, typeFlag, name string) error {
 	issuedBy := st.Polity(issuedByID)
 	if issuedBy == nil {
-		log.Printf("[bug] State.Give: issuedByID is invalid\n")
+		log.Printf("[bug] State.Name: issuedByID is invalid\n")
 		return ERRBUG
 	}
 
 	if name != strings.TrimSpace(sanitize(name)) {
-		return fmt.Errorf("invalid characters in name: %w", ERRBADREQUEST)
+		return fieldError("name", fmt.Errorf("invalid characters in name: %w", ERRBADREQUEST))
 	} else if n := utf8.RuneCountInString(name); n == 0 || n > 50 {
-		return fmt.Errorf("invalid name: %w", ERRBADREQUEST)
+		return fieldError("name", fmt.Errorf("invalid name: %w", ERRBADREQUEST))
 	}
 
 	if colony := st.Colony(entityID); colony != nil {
 		if typeFlag != "colony" {
-			return fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST)
+			return fieldError("type", fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST))
 		} else if colony.polity != issuedBy {
-			return fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN)
+			return fieldError("entity_id", fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN))
 		}
-		return st.assignColonyName(colony, name)
+		return fieldError("name", st.assignColonyName(colony, name))
 	} else if planet := st.Planet(entityID); planet != nil {
 		if typeFlag != "planet" {
-			return fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST)
+			return fieldError("type", fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST))
 		}
-		return st.assignPlanetName(planet, name)
+		return fieldError("name", st.assignPlanetName(issuedBy, planet, name))
 	} else if polity := st.Polity(entityID); polity != nil {
 		if typeFlag != "polity" {
-			return fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST)
+			return fieldError("type", fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST))
 		} else if polity != issuedBy {
-			return fmt.Errorf("polity refuses order: %w", ERRFORBIDDEN)
+			return fieldError("entity_id", fmt.Errorf("polity refuses order: %w", ERRFORBIDDEN))
 		}
-		return st.assignPolityName(polity, name)
+		return fieldError("name", st.assignPolityName(polity, name))
 	} else if ship := st.Ship(entityID); ship != nil {
 		if typeFlag != "ship" {
-			return fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST)
+			return fieldError("type", fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST))
 		} else if ship.polity != issuedBy {
-			return fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN)
+			return fieldError("entity_id", fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN))
 		}
-		return st.assignShipName(ship, name)
+		return fieldError("name", st.assignShipName(ship, name))
 	} else if star := st.Star(entityID); star != nil {
 		if typeFlag != "star" {
-			return fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST)
+			return fieldError("type", fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST))
 		}
-		return st.assignStarName(star, name)
+		return fieldError("name", st.assignStarName(issuedBy, star, name))
 	} else if system := st.System(entityID); system != nil {
 		if typeFlag != "system" {
-			return fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST)
+			return fieldError("type", fmt.Errorf("invalid type %q: %w", typeFlag, ERRBADREQUEST))
 		}
-		return st.assignSystemName(system, name)
+		return fieldError("name", st.assignSystemName(issuedBy, system, name))
 	}
 
 	// if we fall through to here, it can only be because we weren't given a valid entity
-	return fmt.Errorf("invalid entity %q: %w", entityID, ERRBADREQUEST)
+	return fieldError("entity_id", fmt.Errorf("invalid entity %q: %w", entityID, ERRBADREQUEST))
+}
+
+// nameTable is a polity's database of the names it has given to stars,
+// systems, and planets. Names are unique within the table, ignoring case.
+type nameTable struct {
+	names map[string]string // name by entity id
+	ids   map[string]string // entity id by lower-case name
+}
+
+func mknameTable() *nameTable {
+	return &nameTable{
+		names: make(map[string]string),
+		ids:   make(map[string]string),
+	}
+}
+
+// assign gives the entity a name, freeing the entity's old name.
+// It fails if another entity already has the name.
+func (nt *nameTable) assign(id, name string) error {
+	key := strings.ToLower(name)
+	if other, ok := nt.ids[key]; ok && other != id {
+		return fmt.Errorf("duplicate name %q: %w", name, ERRBADREQUEST)
+	}
+	if old, ok := nt.names[id]; ok {
+		delete(nt.ids, strings.ToLower(old))
+	}
+	nt.names[id], nt.ids[key] = name, id
+	return nil
+}
+
+// id returns the id of the entity with the name.
+func (nt *nameTable) id(name string) (string, bool) {
+	if nt == nil {
+		return "", false
+	}
+	id, ok := nt.ids[strings.ToLower(strings.TrimSpace(name))]
+	return id, ok
+}
+
+// nameOf returns the name the table gives the entity, or the default
+// if the entity hasn't been named.
+func (nt *nameTable) nameOf(id, dflt string) string {
+	if nt != nil {
+		if name, ok := nt.names[id]; ok {
+			return name
+		}
+	}
+	return dflt
+}
+
+// share copies the names in the table to the other table, skipping
+// entities already named in the other table and names already in use.
+// It returns the number of names copied.
+func (nt *nameTable) share(to *nameTable) (shared int) {
+	for id, name := range nt.names {
+		if _, ok := to.names[id]; ok {
+			continue
+		} else if _, ok := to.ids[strings.ToLower(name)]; ok {
+			continue
+		}
+		to.names[id], to.ids[strings.ToLower(name)] = name, id
+		shared++
+	}
+	return shared
+}
+
+// resolveNames replaces the names in each order's "_id" fields with the
+// ids they stand for in the name table of the polity issuing the order.
+// Values that are not names are left alone.
+func (st *State) resolveNames(orders Orders) {
+	for _, order := range orders {
+		issuedBy := st.Polity(order.issuedBy)
+		if issuedBy == nil || len(issuedBy.names.ids) == 0 {
+			continue
+		}
+		ov := reflect.ValueOf(order).Elem()
+		for i := 0; i < ov.NumField(); i++ {
+			f := ov.Field(i)
+			if f.Kind() != reflect.Ptr || f.IsNil() || f.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			o := f.Elem()
+			for j := 0; j < o.NumField(); j++ {
+				tag := strings.Split(o.Type().Field(j).Tag.Get("json"), ",")[0]
+				if field := o.Field(j); field.Kind() == reflect.String && field.CanSet() && strings.HasSuffix(tag, "_id") {
+					if id, ok := issuedBy.names.id(field.String()); ok {
+						field.SetString(id)
+					}
+				}
+			}
+		}
+	}
 }
@@ -0,0 +1,150 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func Test_Name(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		issuedBy string
+		entity   string
+		typeFlag string
+		newName  string
+		err      error
+	}{
+		{"colony", "usagi", "tosa", "colony", "Tosa Station", nil},
+		{"ship", "usagi", "S1", "ship", "Hayabusa", nil},
+		{"polity", "usagi", "usagi", "polity", "Usagi Empire", nil},
+		{"planet", "usagi", "suisei", "planet", "Mercury", nil},
+		{"star", "usagi", "shikoku", "star", "Sol", nil},
+		{"system", "usagi", "mizugame", "system", "Home", nil},
+		{"rival planet", "kuma", "suisei", "planet", "Mercury", nil},
+		{"rival colony", "kuma", "tosa", "colony", "Tosa Station", ERRFORBIDDEN},
+		{"rival ship", "kuma", "S1", "ship", "Hayabusa", ERRFORBIDDEN},
+		{"rival polity", "kuma", "usagi", "polity", "Usagi Empire", ERRFORBIDDEN},
+		{"wrong type", "usagi", "tosa", "ship", "Tosa Station", ERRBADREQUEST},
+		{"duplicate polity name", "usagi", "usagi", "polity", "KUMA", ERRBADREQUEST},
+		{"empty", "usagi", "tosa", "colony", "", ERRBADREQUEST},
+		{"too long", "usagi", "tosa", "colony", strings.Repeat("x", 51), ERRBADREQUEST},
+		{"padded", "usagi", "tosa", "colony", " Tosa", ERRBADREQUEST},
+		{"unknown entity", "usagi", "nowhere", "colony", "Tosa Station", ERRBADREQUEST},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			mkrival(st, "kuma")
+			mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1")
+			err := st.Name(tc.issuedBy, tc.entity, tc.typeFlag, tc.newName)
+			is.True(errors.Is(err, tc.err)) // error
+			if tc.err != nil {
+				return
+			}
+			p := st.Polity(tc.issuedBy)
+			switch tc.typeFlag {
+			case "colony":
+				is.Equal(st.Colony(tc.entity).name, tc.newName)
+			case "ship":
+				is.Equal(st.Ship(tc.entity).name, tc.newName)
+			case "polity":
+				is.Equal(p.name, tc.newName)
+			default:
+				id, ok := p.names.id(tc.newName) // named in the issuer's table
+				is.True(ok)
+				is.Equal(id, tc.entity)
+			}
+		})
+	}
+	is := is.New(t)
+	st, _ := Make()
+	is.Equal(st.Name("nobody", "tosa", "colony", "Tosa Station"), ERRBUG)
+}
+
+func Test_NameTable(t *testing.T) {
+	is := is.New(t)
+	nt := mknameTable()
+	is.NoErr(nt.assign("suisei", "Mercury"))
+	is.True(errors.Is(nt.assign("kinsei", "MERCURY"), ERRBADREQUEST)) // names ignore case
+	is.NoErr(nt.assign("suisei", "Mercury"))                          // entities may keep their name
+	is.NoErr(nt.assign("suisei", "Hermes"))
+	_, ok := nt.id("mercury")
+	is.True(!ok) // renaming frees the old name
+	is.NoErr(nt.assign("kinsei", "Mercury"))
+	id, ok := nt.id(" hermes ")
+	is.True(ok)
+	is.Equal(id, "suisei")
+	is.Equal(nt.nameOf("suisei", "suisei"), "Hermes")
+	is.Equal(nt.nameOf("kasei", "kasei"), "kasei") // unnamed entities use the default
+
+	var none *nameTable
+	_, ok = none.id("Hermes")
+	is.True(!ok)
+	is.Equal(none.nameOf("suisei", "suisei"), "suisei")
+}
+
+func Test_ShareNames(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		ally   string
+		status DiplomaticStatus
+		err    error
+		shared int
+	}{
+		{"ally", "kuma", ALLY, nil, 1},
+		{"friend", "kuma", FRIEND, ERRFORBIDDEN, 0},
+		{"self", "usagi", ALLY, ERRBADREQUEST, 0},
+		{"unknown polity", "tora", ALLY, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = tc.status, tc.status
+			is.NoErr(usagi.names.assign("suisei", "Mercury"))
+			is.NoErr(usagi.names.assign("shikoku", "Sol"))
+			is.NoErr(usagi.names.assign("mizugame", "Home"))
+			is.NoErr(kuma.names.assign("mizugame", "Den")) // already named by the ally
+			is.NoErr(kuma.names.assign("kasei", "Sol"))    // name already in use by the ally
+			err := st.ShareNames("usagi", tc.ally)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(len(kuma.names.names), 2+tc.shared)
+			is.Equal(kuma.names.nameOf("mizugame", ""), "Den")
+			is.Equal(kuma.names.nameOf("shikoku", ""), "")
+		})
+	}
+}
+
+func Test_ResolveNames(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	is.NoErr(st.Polity("usagi").names.assign("mizugame", "Home"))
+	orders := Orders{
+		{issuedBy: "usagi", ProbeOrbit: &ProbeOrbit{SourceID: "tosa", TargetID: "home"}},
+		{issuedBy: "kuma", ProbeOrbit: &ProbeOrbit{SourceID: "tosa", TargetID: "home"}},
+	}
+	st.resolveNames(orders)
+	is.Equal(orders[0].ProbeOrbit.TargetID, "mizugame") // names are replaced by ids
+	is.Equal(orders[0].ProbeOrbit.SourceID, "tosa")     // other values are left alone
+	is.Equal(orders[1].ProbeOrbit.TargetID, "home")     // names are only known to the polity that gave them
+}
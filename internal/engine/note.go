@@ -38,25 +38,25 @@ func (st *State) Note(issuedByID, targetID string, note Text) error {
 	}
 
 	if note = note.TrimSpace(); note.Length() > 200 {
-		return fmt.Errorf("invalid text: %w", ERRBADREQUEST)
+		return fieldError("text", fmt.Errorf("invalid text: %w", ERRBADREQUEST))
 	}
 
 	// colony must be controlled by the polity issuing the order
 	if colony := st.Colony(targetID); colony != nil {
 		if colony.polity != issuedBy {
-			return fmt.Errorf("target refuses order: %w", targetID, ERRBADREQUEST)
+			return fieldError("target_id", fmt.Errorf("target refuses order: %w", ERRFORBIDDEN))
 		}
-		return st.assignColonyNote(colony, note)
+		return fieldError("text", st.assignColonyNote(colony, note))
 	}
 
 	// ship must be controlled by the polity issuing the order
 	if ship := st.Ship(targetID); ship != nil {
 		if ship.polity != issuedBy {
-			return fmt.Errorf("target refuses order: %w", targetID, ERRBADREQUEST)
+			return fieldError("target_id", fmt.Errorf("target refuses order: %w", ERRFORBIDDEN))
 		}
-		return st.assignShipNote(ship, note)
+		return fieldError("text", st.assignShipNote(ship, note))
 	}
 
 	// target is not a ship or colony
-	return fmt.Errorf("invalid target %q: %w", targetID, ERRBADREQUEST)
+	return fieldError("target_id", fmt.Errorf("invalid target %q: %w", targetID, ERRBADREQUEST))
 }
@@ -45,3 +45,24 @@ type Orbit struct {
 	colonies []*Colony   // there may be many colonies in the orbit
 	ships    []*Ship     // there may be many ships in the orbit
 }
+
+// addShip puts the ship in the orbit.
+func (o *Orbit) addShip(s *Ship) {
+	if o == nil || s == nil {
+		return
+	}
+	o.ships = append(o.ships, s)
+}
+
+// delShip removes the ship from the orbit.
+func (o *Orbit) delShip(s *Ship) {
+	if o == nil || s == nil {
+		return
+	}
+	for i, ship := range o.ships {
+		if ship == s {
+			o.ships = append(o.ships[:i], o.ships[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// OrderStatus is the final status of an order after the turn is processed.
+type OrderStatus int
+
+const (
+	PENDING  OrderStatus = iota // the order has not been processed yet
+	EXECUTED                    // the order was carried out in full
+	PARTIAL                     // the order was carried out in part
+	REJECTED                    // the order failed its checks
+	SKIPPED                     // the order was not carried out this turn
+)
+
+// String implements the stringer interface
+func (s OrderStatus) String() string {
+	switch s {
+	case PENDING:
+		return "pending"
+	case EXECUTED:
+		return "executed"
+	case PARTIAL:
+		return "partial"
+	case REJECTED:
+		return "rejected"
+	case SKIPPED:
+		return "skipped"
+	}
+	return fmt.Sprintf("OrderStatus(%d)", int(s))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (s OrderStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// OrderResult is the outcome of an order, tied back to the order by its ID.
+type OrderResult struct {
+	ID      string      `json:"id"`
+	Kind    string      `json:"kind"` // name of the order, for example "assemble_item"
+	Status  OrderStatus `json:"status"`
+	Reason  string      `json:"reason,omitempty"`
+	Pointer string      `json:"pointer,omitempty"` // JSON pointer to the order or field that caused a rejection
+}
+
+// kind returns the name of the order, taken from the json tag of the
+// field that holds it.
+func (o *Order) kind() string {
+	ov := reflect.ValueOf(o).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		if f := ov.Field(i); f.Kind() == reflect.Ptr && !f.IsNil() {
+			return strings.Split(ov.Type().Field(i).Tag.Get("json"), ",")[0]
+		}
+	}
+	return "unknown"
+}
+
+// stampIDs gives every order without an ID one that is based on the turn
+// and the order's position in the submitted list. It also saves the
+// position so that errors can point back to the order.
+func (st *State) stampIDs(orders Orders) {
+	for i, order := range orders {
+		order.index = i
+		if order.ID == "" {
+			order.ID = fmt.Sprintf("%d.%d", st.turn, i+1)
+		}
+		order.status, order.reason, order.pointer = PENDING, "", ""
+	}
+}
+
+// record sets the status of the order from the error returned by the
+// function that carried it out. It returns the error for the stage to
+// collect, or nil if the order succeeded in full or in part.
+func (st *State) record(order *Order, err error) error {
+	if err == nil {
+		st.executed(order)
+		return nil
+	} else if errors.Is(err, ERRPARTIAL) {
+		order.status, order.reason = PARTIAL, err.Error()
+		return nil
+	}
+	return st.reject(order, err)
+}
+
+// executed marks the order as carried out unless it already has a status.
+func (st *State) executed(order *Order) {
+	if order.status == PENDING {
+		order.status = EXECUTED
+	}
+}
+
+// settleOrders gives a status to every order that no stage processed.
+func (st *State) settleOrders() {
+	for _, order := range st.orders {
+		if order.status != PENDING {
+			continue
+		} else if order.Debug != nil {
+			order.status = EXECUTED
+		} else {
+			order.status, order.reason = SKIPPED, "not processed this turn"
+		}
+	}
+}
+
+// OrderResults returns the outcome of the orders a polity issued this turn,
+// in the order they were submitted.
+func (st *State) OrderResults(polityID string) ([]OrderResult, error) {
+	if st.Polity(polityID) == nil {
+		return nil, fmt.Errorf("invalid polity %q: %w", polityID, ERRNOTFOUND)
+	}
+	results := []OrderResult{}
+	for _, order := range st.submitted {
+		if order.issuedBy == polityID {
+			results = append(results, OrderResult{ID: order.ID, Kind: order.kind(), Status: order.status, Reason: order.reason, Pointer: order.pointer})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,66 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_OrderStatus(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa := st.Colony("tosa")
+	tosa.population = Population{unskilled: 40, total: 40}
+	tosa.units = append(tosa.units, Unit{Kind: CONSUMERGOOD, TechLevel: 1, Quantity: 100})
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = ALLY, ALLY
+	for _, id := range []string{"S1", "S2"} {
+		s := mktestship(st, usagi, tosa, id, Unit{Kind: ENGINE, TechLevel: 1, Quantity: 10, Assembled: true})
+		s.storage.fuel = 100
+	}
+	mktestship(st, usagi, tosa, "S3")
+
+	st.ExecuteOrders(Orders{
+		{issuedBy: "usagi", ID: "1", Draft: &Draft{SourceID: "tosa", PopulationType: "soldiers", Quantity: 50}},
+		{issuedBy: "usagi", ID: "2", Jump: &Jump{ShipID: "S1", Coords: Coords{X: 1, Y: 1, Z: 5}}},
+		{issuedBy: "usagi", ID: "3", Jump: &Jump{ShipID: "S1", Coords: Coords{X: 1, Y: 1, Z: 6}}},
+		{issuedBy: "usagi", ID: "4", Move: &Move{ShipID: "S2", Orbit: 8}},
+		{issuedBy: "usagi", ID: "5", Move: &Move{ShipID: "S2", Orbit: 7}},
+		{issuedBy: "usagi", ID: "6", Give: &Give{AssetID: "S3", TargetID: "kuma"}},
+		{issuedBy: "usagi", ID: "7", Give: &Give{AssetID: "tosa", TargetID: "tora"}},
+	}, false)
+
+	results, err := st.OrderResults("usagi")
+	is.NoErr(err)
+	is.Equal(len(results), 7)
+	for i, want := range []OrderStatus{
+		PARTIAL,  // only 40 unskilled to draft
+		EXECUTED, // first jump
+		REJECTED, // ships jump once per turn
+		EXECUTED, // first move
+		REJECTED, // ships move once per turn
+		EXECUTED, // given to an ally
+		REJECTED, // unknown target
+	} {
+		is.Equal(results[i].Status, want) // status
+		is.Equal(results[i].Reason != "", want != EXECUTED)
+	}
+	is.Equal(st.Ship("S3").polity, kuma)
+}
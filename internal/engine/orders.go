@@ -112,6 +112,12 @@ func (o Orders) Prioritize() {
 		case order.HomePortChange != nil:
 			order.priority = 11002
 		// Permissions Orders Stage - Diplomacy
+		case order.Diplomacy != nil:
+			order.priority = 11003
+		case order.AppointViceroy != nil:
+			order.priority = 11004
+		case order.DismissViceroy != nil:
+			order.priority = 11005
 		// Disassembly Stage
 		case order.Disassemble != nil:
 			order.priority = 12001
@@ -155,6 +161,10 @@ func (o Orders) Prioritize() {
 			order.priority = 16101
 		case order.AssembleMineGroup != nil:
 			order.priority = 16101
+		case order.DesignShip != nil:
+			order.priority = 16102
+		case order.CommissionShip != nil:
+			order.priority = 16103
 		case order.ExpendResearchPointsOnly != nil:
 			order.priority = 16110
 		case order.ExpendPrototype != nil:
@@ -196,6 +206,8 @@ func (o Orders) Prioritize() {
 			order.priority = 19004
 		case order.Message != nil:
 			order.priority = 19005
+		case order.ShareNames != nil:
+			order.priority = 19006
 		// Ship Travel Stage
 		case order.Jump != nil:
 			order.priority = 20001
@@ -232,10 +244,16 @@ func (o Orders) Prioritize() {
 type Order struct {
 	priority                            int                                  // priority for sorting orders
 	issuedBy                            string                               // polity that issued the order
+	index                               int                                  // position in the submitted list
+	status                              OrderStatus                          // outcome of the order
+	reason                              string                               // why the order was not executed in full
+	pointer                             string                               // JSON pointer to the order or field that caused a rejection
+	ID                                  string                               `json:"id,omitempty"` // assigned when the order is executed if not submitted
 	Accept                              *Accept                              `json:"accept,omitempty"`
 	AddOn                               *AddOn                               `json:"add_on,omitempty"`
 	AfterManeuverEnergyWeaponFire       *AfterManeuverEnergyWeaponFire       `json:"after_maneuver_energy_weapon_fire,omitempty"`
 	AfterManeuverMissileFire            *AfterManeuverMissileFire            `json:"after_maneuver_missile_fire,omitempty"`
+	AppointViceroy                      *AppointViceroy                      `json:"appoint_viceroy,omitempty"`
 	AssembleFactory                     *AssembleFactory                     `json:"assemble_factory,omitempty"`
 	AssembleFactoryGroup                *AssembleFactoryGroup                `json:"assemble_factory_group,omitempty"`
 	AssembleItem                        *AssembleItem                        `json:"assemble_item,omitempty"`
@@ -246,6 +264,7 @@ type Order struct {
 	Close                               *Close                               `json:"close,omitempty"`
 	CloseProximityTargeting             *CloseProximityTargeting             `json:"close_proximity_targeting,omitempty"`
 	CombineFactoryGroup                 *CombineFactoryGroup                 `json:"combine_factory_group,omitempty"`
+	CommissionShip                      *CommissionShip                      `json:"commission_ship,omitempty"`
 	ControlPlanet                       *ControlPlanet                       `json:"control_planet,omitempty"`
 	CreateAdmin                         *CreateAdmin                         `json:"create_admin,omitempty"`
 	CreatePolity                        *CreatePolity                        `json:"create_polity,omitempty"`
@@ -253,8 +272,11 @@ type Order struct {
 	Debug                               *Debug                               `json:"debug,omitempty"`
 	DefensiveSupport                    *DefensiveSupport                    `json:"defensive_support,omitempty"`
 	DefineCargoHold                     *DefineCargoHold                     `json:"define_cargo_hold,omitempty"`
+	DesignShip                          *DesignShip                          `json:"design_ship,omitempty"`
+	Diplomacy                           *Diplomacy                           `json:"diplomacy,omitempty"`
 	Disassemble                         *Disassemble                         `json:"disassemble,omitempty"`
 	Disband                             *Disband                             `json:"disband,omitempty"`
+	DismissViceroy                      *DismissViceroy                      `json:"dismiss_viceroy,omitempty"`
 	Dock                                *Dock                                `json:"dock,omitempty"`
 	Dodge                               *Dodge                               `json:"dodge,omitempty"`
 	Draft                               *Draft                               `json:"draft,omitempty"`
@@ -291,6 +313,7 @@ type Order struct {
 	Run                                 *Run                                 `json:"run,omitempty"`
 	Scrap                               *Scrap                               `json:"scrap,omitempty"`
 	SetUp                               *SetUp                               `json:"set_up,omitempty"`
+	ShareNames                          *ShareNames                          `json:"share_names,omitempty"`
 	ShutDown                            *ShutDown                            `json:"shut_down,omitempty"`
 	StartUp                             *StartUp                             `json:"start_up,omitempty"`
 	Survey                              *Survey                              `json:"survey,omitempty"`
@@ -408,6 +431,13 @@ type CombineFactoryGroup struct {
 	WIPQuarters []int  `json:"wip_quarters,omitempty"` // quarters of WIP to combine
 }
 
+// CommissionShip order assembles ships at a colony from a saved design.
+type CommissionShip struct {
+	ColonyID string `json:"colony_id"` // id of colony being ordered
+	Design   string `json:"design"`    // name of the polity's ship design
+	Quantity int    `json:"quantity"`  // number of ships to commission
+}
+
 // ControlPlanet order...
 type ControlPlanet struct {
 	ColonyID string `json:"colony_id"` // id of surface colony being ordered
@@ -427,8 +457,20 @@ type Debug struct {
 
 // DefineCargoHold order...
 type DefineCargoHold struct {
-	ShipID   string `json:"ship_id"` // id of ship being ordered
-	Quantity int    `json:"quantity"`
+	ShipID   string `json:"ship_id"`  // id of ship being ordered
+	Quantity int    `json:"quantity"` // volume units of the hull to set aside for cargo
+}
+
+// DesignShip order saves a named ship design for the polity.
+// Saving a design with the name of an existing design replaces it.
+type DesignShip struct {
+	Name  string `json:"name"`
+	Parts []struct {
+		Item      string `json:"item"`
+		TechLevel int    `json:"tech_level"`
+		Quantity  int    `json:"quantity"`
+	} `json:"parts"`
+	CargoHold int `json:"cargo_hold"` // volume units of the hull to set aside for cargo
 }
 
 // DefensiveSupport order...
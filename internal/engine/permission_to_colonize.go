@@ -35,24 +35,25 @@ type PermissionToColonize struct {
 // 1. Planet identified by PlanetID must be valid
 // 2. Planet must contain a colony that is controlled by the polity issuing the order
 // 3. Ship identified by ShipID must be valid
-// 4. This is a no-op if the ship already has permission to establish a colony on the planet
+// 4. The polity issuing the order must be allied to the ship's polity
+// 5. This is a no-op if the ship already has permission to establish a colony on the planet
 //
 // Note that permission expires at the end of the current turn.
 func (st *State) PermissionToColonize(issuedByID, planetID, shipID string) error {
 	issuedBy := st.Polity(issuedByID)
 	if issuedBy == nil {
-		log.Printf("[bug] State.Give: PermissionToColonize is invalid\n")
+		log.Printf("[bug] State.PermissionToColonize: issuedByID is invalid\n")
 		return ERRBUG
 	}
 
 	planet := st.Planet(planetID)
 	if planet == nil {
-		return fmt.Errorf("invalid planet %q: %w", planetID, ERRBADREQUEST)
+		return fieldError("planet_id", fmt.Errorf("invalid planet %q: %w", planetID, ERRBADREQUEST))
 	}
 
 	ship := st.Ship(shipID)
 	if ship == nil {
-		return fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST)
+		return fieldError("ship_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
 	}
 
 	// the polity issuing the order must control at least one colony on the planet
@@ -70,7 +71,9 @@ func (st *State) PermissionToColonize(issuedByID, planetID, shipID string) error
 	}
 
 	if !hasColony {
-		return fmt.Errorf("planet refuses orders: %w", ERRFORBIDDEN)
+		return fieldError("planet_id", fmt.Errorf("planet refuses orders: %w", ERRFORBIDDEN))
+	} else if !issuedBy.isAlliedTo(ship.polity) {
+		return fieldError("ship_id", fmt.Errorf("ship is not allied: %w", ERRFORBIDDEN))
 	}
 
 	// all checks passed
@@ -21,7 +21,7 @@ package engine
 import (
 	"fmt"
 	"github.com/google/uuid"
-	"log"
+	"sort"
 )
 
 type Polity struct {
@@ -39,9 +39,21 @@ type Polity struct {
 		polities map[string]*Polity // there should never be more than one level in this hierarchy.
 		ships    map[string]*Ship
 	}
-	viceroyOf *Polity // viceroy to this polity
+	designs   map[string]*ShipDesign // ship designs, by lower-case name
+	viceroyOf *Polity                // viceroy to this polity
+	entrusted map[string]bool        // ids of colonies and ships given to a viceroy by its ruler
 	diplomacy map[string]DiplomaticStatus
-	seq       struct {
+	intel     *intel     // what the polity knows about the cluster
+	names     *nameTable // names for stars, systems, and planets
+	inbox     []*message // messages delivered to the polity
+	events    *eventLog  // events for the current turn, shared with the state
+	research  struct {
+		points    int              // banked points that have not been expended
+		committed int              // points left over after advancing a tech level
+		progress  map[UnitKind]int // points expended toward the next tech level
+	}
+	techLevels map[UnitKind]int // highest tech level that may be assembled, by kind
+	seq        struct {
 		colony int
 		ship   int
 	}
@@ -52,7 +64,13 @@ func polity() *Polity {
 	p.controls.colonies = make(map[string]*Colony)
 	p.controls.polities = make(map[string]*Polity)
 	p.controls.ships = make(map[string]*Ship)
+	p.designs = make(map[string]*ShipDesign)
 	p.diplomacy = make(map[string]DiplomaticStatus)
+	p.entrusted = make(map[string]bool)
+	p.intel = mkintel()
+	p.names = mknameTable()
+	p.research.progress = make(map[UnitKind]int)
+	p.techLevels = make(map[UnitKind]int)
 	return p
 }
 
@@ -75,6 +93,7 @@ func (p *Polity) delColony(c *Colony) {
 		return
 	}
 	delete(p.controls.colonies, c.id)
+	delete(p.entrusted, c.id)
 }
 
 func (p *Polity) delShip(s *Ship) {
@@ -82,6 +101,7 @@ func (p *Polity) delShip(s *Ship) {
 		return
 	}
 	delete(p.controls.ships, s.id)
+	delete(p.entrusted, s.id)
 }
 
 // diplomaticStatus returns the status that the polity thinks that it
@@ -96,8 +116,30 @@ func (p *Polity) diplomaticStatus(t *Polity) DiplomaticStatus {
 	return UNKNOWN
 }
 
+// standing returns the status that the two polities share, which is the
+// lower of their stances toward each other. A polity, its viceroys, and
+// the polity that they rule always stand as allies.
+func (p *Polity) standing(t *Polity) DiplomaticStatus {
+	if p == nil || t == nil {
+		return UNKNOWN
+	} else if p == t || p.isViceroyOf(t) || t.isViceroyOf(p) {
+		return ALLY
+	}
+	ds, tds := p.diplomaticStatus(t), t.diplomaticStatus(p)
+	if tds < ds {
+		return tds
+	}
+	return ds
+}
+
+// permits returns true if the standing between the polities is at least
+// the given status.
+func (p *Polity) permits(t *Polity, ds DiplomaticStatus) bool {
+	return p.standing(t) >= ds
+}
+
 func (p *Polity) isAlliedTo(t *Polity) bool {
-	return p.isViceroyOf(t) || (p.diplomaticStatus(t) == ALLY && t.diplomaticStatus(p) == ALLY)
+	return p.permits(t, ALLY)
 }
 
 func (p *Polity) isAllyOf(t *Polity) bool {
@@ -111,6 +153,11 @@ func (p *Polity) isViceroyOf(t *Polity) bool {
 	return p.viceroyOf == t
 }
 
+// logf adds a notice to the polity's report for the current turn.
+func (p *Polity) logf(format string, args ...interface{}) {
+	p.eventf(NOTICE, nil, format, args...)
+}
+
 func (p *Polity) nextColonyNumber() string {
 	number := fmt.Sprintf("C%d", p.seq.colony)
 	p.seq.colony++
@@ -119,27 +166,88 @@ func (p *Polity) nextColonyNumber() string {
 
 func (p *Polity) nextShipNumber() string {
 	p.seq.ship++
-	return fmt.Sprintf("C%d", p.seq.ship)
+	return fmt.Sprintf("S%d", p.seq.ship)
 }
 
+// xferColony transfers control of the colony to the polity.
+// Ships home-ported at the colony go with it, along with their cargo and
+// the population on board. Units, storage, and population at the colony
+// belong to the colony and need no transfer.
 func (p *Polity) xferColony(c *Colony) error {
 	if p == nil || c == nil {
 		return nil
 	}
 	c.polity.delColony(c)
 	p.addColony(c)
-	// recursively transfer control of all assets assigned to the colony.
-	log.Printf("[todo] xferColony: recursively transfer control of all assets assigned to the colony\n")
+	p.entrust(c.id, c.polity)
+	c.polity = p
+	for _, s := range c.controls.ships {
+		if s.polity != p {
+			s.polity.eventf(ASSETTRANSFERRED, []string{s.id, p.id, s.polity.id}, "%s: transferred to %s with colony %s", s.number, p.name, c.number)
+			p.eventf(ASSETTRANSFERRED, []string{s.id, p.id, s.polity.id}, "%s: transferred from %s with colony %s", s.number, s.polity.name, c.number)
+			s.polity.delShip(s)
+			p.addShip(s)
+			p.entrust(s.id, s.polity)
+			s.polity = p
+		}
+	}
 	return nil
 }
 
+// entrust records the asset as given to the polity by its ruler when it
+// comes from the polity that the polity is viceroy to. Entrusted assets
+// are returned to the ruler when the viceroy is dismissed.
+func (p *Polity) entrust(id string, from *Polity) {
+	if p.isViceroyOf(from) {
+		p.entrusted[id] = true
+	}
+}
+
+// xferShip transfers control of the ship to the polity. The ship's cargo
+// and the population on board go with it. A ship whose home port is not
+// controlled by the polity is rehomed.
 func (p *Polity) xferShip(s *Ship) error {
 	if p == nil || s == nil {
 		return nil
 	}
 	s.polity.delShip(s)
 	p.addShip(s)
-	// recursively transfer control of all assets assigned to the ship.
-	log.Printf("[todo] xferShip: recursively transfer control of all assets assigned to the ship\n")
+	p.entrust(s.id, s.polity)
+	s.polity = p
+	if s.homePort == nil || s.homePort.polity != p {
+		p.rehome(s)
+	}
 	return nil
 }
+
+// rehome assigns the ship a home port controlled by the polity. It prefers
+// a colony in the ship's orbit, then one in the ship's system, then the
+// polity's home colony, then any colony. A polity without colonies leaves
+// the ship without a home port.
+func (p *Polity) rehome(s *Ship) {
+	s.homePort.delShip(s)
+	var ids []string
+	for id := range p.controls.colonies {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var inSystem, any *Colony
+	for _, id := range ids {
+		c := p.controls.colonies[id]
+		if s.orbit != nil && c.orbitOf() == s.orbit {
+			c.addShip(s)
+			return
+		} else if inSystem == nil && s.system != nil && c.system == s.system {
+			inSystem = c
+		} else if any == nil {
+			any = c
+		}
+	}
+	if inSystem != nil {
+		inSystem.addShip(s)
+	} else if home := p.home.colony; home != nil && home.polity == p {
+		home.addShip(s)
+	} else if any != nil {
+		any.addShip(s)
+	}
+}
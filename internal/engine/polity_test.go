@@ -0,0 +1,96 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"github.com/matryer/is"
+	"testing"
+)
+
+// mktestcolony adds an enclosed colony in the orbit, controlled by the polity.
+func mktestcolony(st *State, p *Polity, orbit *Orbit, id string) *Colony {
+	c := mkcolony(p, orbit, nil, ENCLOSED)
+	c.id = id
+	st.colonies[c.id] = c
+	p.addColony(c)
+	return c
+}
+
+func Test_TransferColony(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	tosa := st.Colony("tosa")
+	s := mktestship(st, usagi, tosa, "S1")
+
+	is.NoErr(st.transferColony(tosa, usagi, kuma))
+	is.Equal(tosa.polity, kuma)
+	is.Equal(tosa.previousPolity, usagi)
+	is.Equal(s.polity, kuma) // home-ported ships go with the colony
+	is.Equal(s.homePort, tosa)
+	is.Equal(len(st.checkOwnership()), 0)
+
+	is.NoErr(st.transferColony(tosa, kuma, usagi))
+	is.Equal(tosa.previousPolity, (*Polity)(nil)) // back with its former ruler
+	is.Equal(s.polity, usagi)
+	is.Equal(len(st.checkOwnership()), 0)
+}
+
+func Test_TransferShipRehomes(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		colonies func(st *State, kuma *Polity)
+		homePort string
+	}{
+		{"same orbit", func(st *State, kuma *Polity) {
+			mktestcolony(st, kuma, st.Planet("suisei").orbit, "K-planet")
+			mktestcolony(st, kuma, st.Colony("tosa").orbitOf(), "K-orbit")
+		}, "K-orbit"},
+		{"same system", func(st *State, kuma *Polity) {
+			mktestcolony(st, kuma, mkorbit(mktestsystem(st, "hoshi", 1, 1, 4).stars[0], 0), "K-away")
+			mktestcolony(st, kuma, st.Planet("suisei").orbit, "K-planet")
+		}, "K-planet"},
+		{"home colony", func(st *State, kuma *Polity) {
+			mktestcolony(st, kuma, mkorbit(mktestsystem(st, "hoshi", 1, 1, 4).stars[0], 0), "K-away")
+			kuma.home.colony = mktestcolony(st, kuma, mkorbit(mktestsystem(st, "tsuki", 1, 1, 8).stars[0], 0), "K-home")
+		}, "K-home"},
+		{"any colony", func(st *State, kuma *Polity) {
+			mktestcolony(st, kuma, mkorbit(mktestsystem(st, "hoshi", 1, 1, 4).stars[0], 0), "K-away")
+		}, "K-away"},
+		{"no colonies", func(st *State, kuma *Polity) {}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			tc.colonies(st, kuma)
+			s := mktestship(st, usagi, st.Colony("tosa"), "S1")
+			is.NoErr(st.transferShip(s, usagi, kuma))
+			is.Equal(s.polity, kuma)
+			_, ok := st.Colony("tosa").controls.ships["S1"]
+			is.True(!ok) // no longer home-ported with the former owner
+			if tc.homePort == "" {
+				is.Equal(s.homePort, (*Colony)(nil))
+				return
+			}
+			is.Equal(s.homePort, st.Colony(tc.homePort))
+			is.Equal(len(st.checkOwnership()), 0)
+		})
+	}
+}
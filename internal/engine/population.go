@@ -43,3 +43,63 @@ func (p Population) FoodNeededPerTurn() (min, full int) {
 	}
 	return min, p.total
 }
+
+// count returns the number of people of the given kind.
+func (p *Population) count(kind PopulationKind) *int {
+	switch kind {
+	case CONSTRUCTION:
+		return &p.construction
+	case PROFESSIONALS:
+		return &p.professionals
+	case SOLDIERS:
+		return &p.soldiers
+	case SPIES:
+		return &p.spies
+	case TRAINEES:
+		return &p.trainees
+	case UNSKILLED:
+		return &p.unskilled
+	}
+	return &p.others
+}
+
+// split removes up to quantity people, taking from each kind in proportion
+// to its share of the population, and returns them.
+func (p *Population) split(quantity int) (out Population) {
+	kinds := []PopulationKind{CONSTRUCTION, PROFESSIONALS, SOLDIERS, SPIES, TRAINEES, UNSKILLED, OTHERS}
+	var total int
+	for _, kind := range kinds {
+		total += *p.count(kind)
+	}
+	if quantity > total {
+		quantity = total
+	}
+	if quantity <= 0 {
+		return out
+	}
+	remaining := quantity
+	for _, kind := range kinds {
+		n := *p.count(kind) * quantity / total
+		*out.count(kind), remaining = n, remaining-n
+	}
+	// rounding leaves a few people that are taken from the first kinds with any left
+	for _, kind := range kinds {
+		if n := minInt(remaining, *p.count(kind)-*out.count(kind)); n > 0 {
+			*out.count(kind), remaining = *out.count(kind)+n, remaining-n
+		}
+	}
+	for _, kind := range kinds {
+		*p.count(kind) -= *out.count(kind)
+	}
+	p.total -= quantity
+	out.total = quantity
+	return out
+}
+
+// add adds the people to the population.
+func (p *Population) add(q Population) {
+	for _, kind := range []PopulationKind{CONSTRUCTION, PROFESSIONALS, SOLDIERS, SPIES, TRAINEES, UNSKILLED, OTHERS} {
+		*p.count(kind) += *q.count(kind)
+	}
+	p.total += q.total
+}
@@ -0,0 +1,260 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"math"
+	"strings"
+)
+
+// spiesPerProbe is the number of spies needed to make an intensive probe.
+const spiesPerProbe = 10
+
+// probeSystemRange is the farthest, in light years, that a system probe
+// can reach from the source to the center of the probe and from the center
+// of the probe to the systems that it finds.
+const probeSystemRange = 10
+
+// robotProbeFuelPerLightYear is the fuel burned by a robot probe for every
+// light year that it travels.
+const robotProbeFuelPerLightYear = 10
+
+// location returns the stellar coordinates of the depot.
+func (d depot) location() Coords {
+	if d.colony != nil {
+		return d.colony.system.location()
+	}
+	return d.ship.location()
+}
+
+// system returns the system the depot is in, or nil if it is in deep space.
+func (d depot) system() *System {
+	if d.colony != nil {
+		return d.colony.system
+	}
+	return d.ship.system
+}
+
+// Survey records a planet and its deposits in the polity's intel.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. The source must be in the planet's orbit.
+func (st *State) Survey(issuedByID, sourceID, planetID string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Survey: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	planet := st.Planet(planetID)
+	if planet == nil {
+		return fieldError("planet_id", fmt.Errorf("invalid planet %q: %w", planetID, ERRBADREQUEST))
+	} else if d.orbit() != planet.orbit {
+		return fieldError("planet_id", fmt.Errorf("planet is not in the source's orbit: %w", ERRBADREQUEST))
+	}
+	p := d.polity()
+	p.intel.surveyPlanet(st.turn, planet)
+	p.logf("%s: surveyed %s, found %d deposits", sourceID, planet.orbit.name, len(planet.deposits))
+	return nil
+}
+
+// Probe is an intensive probe that records the units at a colony or on a ship.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. Target identified by TargetID must be a colony or ship in the same system.
+// 3. The source must have at least ten spies.
+// 4. The probe is blocked if the target has at least as many spies as the source.
+func (st *State) Probe(issuedByID, sourceID, targetID string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.Probe: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	} else if d.population.spies < spiesPerProbe {
+		return fieldError("source_id", fmt.Errorf("probes need %d spies: %w", spiesPerProbe, ERRBADREQUEST))
+	}
+	target, err := st.findTarget(targetID)
+	if err != nil {
+		return fieldError("target_id", err)
+	} else if d.system() == nil || d.system() != target.system() {
+		return fieldError("target_id", fmt.Errorf("target is not in the source's system: %w", ERRBADREQUEST))
+	}
+
+	p := d.polity()
+	if target.population.spies >= d.population.spies {
+		p.logf("%s: probe of %s was blocked", sourceID, targetID)
+		target.polity().logf("%s: blocked a probe from %s", targetID, sourceID)
+		return nil
+	}
+	if target.colony != nil {
+		p.intel.observeColony(st.turn, target.colony, true)
+	} else {
+		p.intel.observeShip(st.turn, target.ship, true)
+	}
+	p.logf("%s: probed %s", sourceID, targetID)
+	return nil
+}
+
+// probeOrbits records the system and the contents of its orbits. An orbit
+// of zero probes every orbit.
+func (st *State) probeOrbits(p *Polity, system *System, star *Star, orbit int) {
+	p.intel.observeSystem(st.turn, system)
+	stars := system.stars
+	if star != nil {
+		stars = []*Star{star}
+	}
+	for _, s := range stars {
+		for ring, o := range s.orbits {
+			if o != nil && (orbit == 0 || ring == orbit-1) {
+				p.intel.observeOrbit(st.turn, o)
+			}
+		}
+	}
+}
+
+// probeSystems records the systems within the radius of the coordinates
+// and returns the number found.
+func (st *State) probeSystems(p *Polity, center Coords, radius float64) (found int) {
+	for _, s := range st.systems {
+		if center.distance(s.location()) <= radius {
+			p.intel.observeSystem(st.turn, s)
+			found++
+		}
+	}
+	return found
+}
+
+// ProbeOrbit records the planets, colonies, and ships in the orbits of a system.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. The source must be in the system identified by TargetID.
+// 3. Orbit must be 0 through 10. Zero probes every orbit.
+func (st *State) ProbeOrbit(issuedByID, sourceID, targetID string, orbit int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.ProbeOrbit: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	system := st.System(targetID)
+	if system == nil {
+		return fieldError("target_id", fmt.Errorf("invalid system %q: %w", targetID, ERRBADREQUEST))
+	} else if d.system() != system {
+		return fieldError("target_id", fmt.Errorf("source is not in the system: %w", ERRBADREQUEST))
+	} else if orbit < 0 || orbit > 10 {
+		return fieldError("orbit", fmt.Errorf("invalid orbit %d: %w", orbit, ERRBADREQUEST))
+	}
+	st.probeOrbits(d.polity(), system, nil, orbit)
+	d.polity().logf("%s: probed the orbits of %s", sourceID, system.name)
+	return nil
+}
+
+// ProbeSystem records the systems within a radius of a system.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. The system identified by TargetID must be within ten light years of the source.
+// 3. Magnitude is the radius of the probe and must be 0 through 10 light years.
+func (st *State) ProbeSystem(issuedByID, sourceID, targetID string, magnitude int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.ProbeSystem: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	system := st.System(targetID)
+	if system == nil {
+		return fieldError("target_id", fmt.Errorf("invalid system %q: %w", targetID, ERRBADREQUEST))
+	} else if d.location().distance(system.location()) > probeSystemRange {
+		return fieldError("target_id", fmt.Errorf("system is out of range: %w", ERRBADREQUEST))
+	} else if magnitude < 0 || magnitude > probeSystemRange {
+		return fieldError("magnitude", fmt.Errorf("invalid magnitude %d: %w", magnitude, ERRBADREQUEST))
+	}
+	found := st.probeSystems(d.polity(), system.location(), float64(magnitude))
+	d.polity().logf("%s: probed %d light years around %s, found %d systems", sourceID, magnitude, system.name, found)
+	return nil
+}
+
+// LaunchRobotProbe sends a robot probe to probe the orbits of a distant
+// system or to find the system at the coordinates.
+//
+// 1. Source identified by SourceID must accept orders from the polity issuing the order.
+// 2. Type must be "orbit" or "system".
+// 3. The probe burns ten units of fuel from the source's storage for every
+// light year to the coordinates.
+// 4. Orbit probes look at one star, picked by StarLetter in multiple star
+// systems, and one orbit, or every orbit if Orbit is zero.
+// 5. A probe that arrives in deep space finds nothing.
+func (st *State) LaunchRobotProbe(issuedByID, sourceID, probeType string, coords Coords, starLetter string, orbit int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.LaunchRobotProbe: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	d, err := st.findDepot(issuedBy, sourceID)
+	if err != nil {
+		return fieldError("source_id", err)
+	}
+	probeType = strings.ToLower(strings.TrimSpace(probeType))
+	if probeType != "orbit" && probeType != "system" {
+		return fieldError("type", fmt.Errorf("invalid type %q: %w", probeType, ERRBADREQUEST))
+	} else if orbit < 0 || orbit > 10 {
+		return fieldError("orbit", fmt.Errorf("invalid orbit %d: %w", orbit, ERRBADREQUEST))
+	}
+	system := st.systemAt(coords)
+	var star *Star
+	if system != nil && probeType == "orbit" && starLetter != "" {
+		letter := strings.ToUpper(strings.TrimSpace(starLetter))
+		if len(letter) != 1 || letter[0] < 'A' || int(letter[0]-'A') >= len(system.stars) {
+			return fieldError("star_letter", fmt.Errorf("invalid star letter %q: %w", starLetter, ERRBADREQUEST))
+		}
+		star = system.stars[letter[0]-'A']
+	}
+	fuel := int(math.Ceil(d.location().distance(coords) * robotProbeFuelPerLightYear))
+	if fuel > d.storage.fuel {
+		return fieldError("source_id", fmt.Errorf("probe needs %s fuel: %w", utils.Commas(fuel), ERRBADREQUEST))
+	}
+	d.storage.fuel -= fuel
+
+	p := d.polity()
+	if system == nil {
+		p.logf("%s: robot probe found nothing at %s (%s fuel)", sourceID, coords, utils.Commas(fuel))
+	} else if probeType == "orbit" {
+		st.probeOrbits(p, system, star, orbit)
+		p.logf("%s: robot probe reached the orbits of %s (%s fuel)", sourceID, system.name, utils.Commas(fuel))
+	} else {
+		st.probeSystems(p, coords, 0)
+		p.logf("%s: robot probe found %s (%s fuel)", sourceID, system.name, utils.Commas(fuel))
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+// mktestsystem adds a single star system at the coordinates.
+func mktestsystem(st *State, id string, x, y, z int) *System {
+	s := mksystem(x, y, z)
+	s.id = id
+	mkstar(s)
+	st.systems[s.id] = s
+	return s
+}
+
+func Test_Survey(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		source   string
+		planet   string
+		err      error
+		deposits int
+	}{
+		{"from the surface", "sanuki", "suisei", nil, 4},
+		{"from another orbit", "tosa", "suisei", ERRBADREQUEST, 0},
+		{"unknown planet", "sanuki", "kasei", ERRBADREQUEST, 0},
+		{"unknown source", "nowhere", "suisei", ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			st.turn = 3
+			err := st.Survey("usagi", tc.source, tc.planet)
+			is.True(errors.Is(err, tc.err)) // error
+			pi, ok := st.Polity("usagi").intel.planets[tc.planet]
+			is.Equal(ok, tc.err == nil) // only surveyed planets are recorded
+			if ok {
+				is.Equal(pi.surveyed, 3)
+				is.Equal(len(pi.deposits), tc.deposits)
+			}
+		})
+	}
+}
+
+func Test_Probe(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		spies   int
+		against int // spies on the target
+		target  string
+		err     error
+		probed  bool
+	}{
+		{"probes", 10, 0, "K1", nil, true},
+		{"blocked", 10, 10, "K1", nil, false},
+		{"outnumbers the defense", 20, 19, "K1", nil, true},
+		{"too few spies", 9, 0, "K1", ERRBADREQUEST, false},
+		{"unknown target", 10, 0, "K2", ERRBADREQUEST, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			st.turn = 2
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			s := mktestship(st, usagi, st.Colony("tosa"), "S1")
+			s.population.spies = tc.spies
+			k := mktestship(st, kuma, st.Colony("tosa"), "K1", Unit{Kind: MISSILE, TechLevel: 1, Quantity: 10, Assembled: true})
+			k.population.spies = tc.against
+			err := st.Probe("usagi", "S1", tc.target)
+			is.True(errors.Is(err, tc.err)) // error
+			si, ok := usagi.intel.ships["K1"]
+			is.Equal(ok, tc.probed) // only a successful probe records the target
+			if ok {
+				is.Equal(si.probed, 2)
+				is.Equal(si.polity, "kuma")
+				is.Equal(si.units, k.units)
+			}
+		})
+	}
+}
+
+func Test_ProbeOrbit(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		orbit    int
+		err      error
+		systems  int
+		planets  int
+		colonies int
+	}{
+		{"every orbit", 0, nil, 1, 1, 2},
+		{"planet orbit", 5, nil, 1, 1, 1},
+		{"colony orbit", 10, nil, 1, 0, 1},
+		{"empty orbit", 1, nil, 1, 0, 0},
+		{"no such orbit", 11, ERRBADREQUEST, 0, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			err := st.ProbeOrbit("usagi", "tosa", "mizugame", tc.orbit)
+			is.True(errors.Is(err, tc.err)) // error
+			in := st.Polity("usagi").intel
+			is.Equal(len(in.systems), tc.systems)
+			is.Equal(len(in.planets), tc.planets)
+			is.Equal(len(in.colonies), tc.colonies)
+		})
+	}
+}
+
+func Test_ProbeIndependentColony(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	is.NoErr(st.releaseColony(st.Colony("tosa")))
+	is.NoErr(st.ProbeOrbit("usagi", "sanuki", "mizugame", 0))
+	ci := st.Polity("usagi").intel.colonies["tosa"]
+	is.True(ci != nil)
+	is.Equal(ci.polity, "nobody") // independents are owned by nobody
+}
+
+func Test_ProbeOrbitSource(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	mktestsystem(st, "hoshi", 1, 1, 4)
+	is.True(errors.Is(st.ProbeOrbit("usagi", "tosa", "hoshi", 0), ERRBADREQUEST))   // source must be in the system
+	is.True(errors.Is(st.ProbeOrbit("usagi", "tosa", "nowhere", 0), ERRBADREQUEST)) // unknown system
+	is.Equal(st.ProbeOrbit("nobody", "tosa", "mizugame", 0), ERRBUG)
+}
+
+func Test_ProbeSystem(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		target    string
+		magnitude int
+		err       error
+		found     []string
+	}{
+		{"only the target", "mizugame", 0, nil, []string{"mizugame"}},
+		{"around the source", "mizugame", 3, nil, []string{"mizugame", "hoshi"}},
+		{"around another system", "hoshi", 10, nil, []string{"mizugame", "hoshi", "tsuki"}},
+		{"target out of range", "tsuki", 0, ERRBADREQUEST, nil},
+		{"too large", "mizugame", 11, ERRBADREQUEST, nil},
+		{"negative", "mizugame", -1, ERRBADREQUEST, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			mktestsystem(st, "hoshi", 1, 1, 4)
+			mktestsystem(st, "tsuki", 1, 1, 14)
+			err := st.ProbeSystem("usagi", "tosa", tc.target, tc.magnitude)
+			is.True(errors.Is(err, tc.err)) // error
+			in := st.Polity("usagi").intel
+			is.Equal(len(in.systems), len(tc.found))
+			for _, id := range tc.found {
+				_, ok := in.systems[id]
+				is.True(ok) // system was found
+			}
+		})
+	}
+}
+
+func Test_LaunchRobotProbe(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		probeType string
+		to        Coords
+		star      string
+		orbit     int
+		fuel      int
+		err       error
+		left      int
+		systems   int
+		colonies  int
+	}{
+		{"system", "system", Coords{X: 1, Y: 1, Z: 4}, "", 0, 100, nil, 70, 1, 0},
+		{"orbits", "orbit", Coords{X: 1, Y: 1, Z: 1}, "", 0, 100, nil, 100, 1, 2},
+		{"star letter", "ORBIT", Coords{X: 1, Y: 1, Z: 1}, "a", 10, 100, nil, 100, 1, 1},
+		{"deep space", "system", Coords{X: 1, Y: 1, Z: 3}, "", 0, 100, nil, 80, 0, 0},
+		{"short of fuel", "system", Coords{X: 1, Y: 1, Z: 4}, "", 0, 29, ERRBADREQUEST, 29, 0, 0},
+		{"no such star", "orbit", Coords{X: 1, Y: 1, Z: 1}, "B", 0, 100, ERRBADREQUEST, 100, 0, 0},
+		{"bad type", "planet", Coords{X: 1, Y: 1, Z: 4}, "", 0, 100, ERRBADREQUEST, 100, 0, 0},
+		{"bad orbit", "orbit", Coords{X: 1, Y: 1, Z: 4}, "", 11, 100, ERRBADREQUEST, 100, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			mktestsystem(st, "hoshi", 1, 1, 4)
+			c := st.Colony("tosa")
+			c.storage.fuel = tc.fuel
+			err := st.LaunchRobotProbe("usagi", "tosa", tc.probeType, tc.to, tc.star, tc.orbit)
+			is.True(errors.Is(err, tc.err))   // error
+			is.Equal(c.storage.fuel, tc.left) // ten fuel for every light year
+			in := st.Polity("usagi").intel
+			is.Equal(len(in.systems), tc.systems)
+			is.Equal(len(in.colonies), tc.colonies)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"github.com/mdhender/server/pkg/utils"
+)
+
+// thresholds for rebel actions, expressed as the fraction of the
+// population that has joined the rebels.
+const (
+	rebelsReduceProduction = 0.10 // production starts to fall
+	rebelsStrike           = 0.25 // workers go on strike, production stops
+	rebelsRevolt           = 0.50 // colony throws off its ruler
+)
+
+// unrest updates the rebel fractions for the colony.
+//
+// Starvation, low pay, and occupation by a foreign polity all push
+// people into the rebel camp. Soldiers and spies suppress the rebels.
+// When nothing is pushing, the rebels slowly lose support.
+func (c *Colony) unrest(starving bool) {
+	if c == nil {
+		return
+	}
+
+	var pressure float64
+	if starving {
+		pressure += 0.05
+	}
+	if c.ration < 1 {
+		pressure += 0.10 * (1 - c.ration)
+	}
+	if c.pay < 1 {
+		pressure += 0.05 * (1 - c.pay)
+	}
+	if c.isOccupied() {
+		pressure += 0.03
+	}
+
+	var suppression float64
+	if c.population.total != 0 {
+		total := float64(c.population.total)
+		suppression = 0.5*float64(c.population.soldiers)/total + 1.0*float64(c.population.spies)/total
+	}
+	if pressure == 0 {
+		suppression += 0.01 // natural decay
+	}
+
+	delta := pressure - suppression
+	c.rebels.construction = clamp(c.rebels.construction+delta, 0, 1)
+	c.rebels.professionals = clamp(c.rebels.professionals+delta, 0, 1)
+	c.rebels.trainees = clamp(c.rebels.trainees+delta, 0, 1)
+	c.rebels.unskilled = clamp(c.rebels.unskilled+delta, 0, 1)
+	c.rebels.others = clamp(c.rebels.others+delta, 0, 1)
+	// soldiers and spies are paid to be loyal, so they are slower to turn
+	c.rebels.soldiers = clamp(c.rebels.soldiers+delta/2, 0, 1)
+	c.rebels.spies = clamp(c.rebels.spies+delta/2, 0, 1)
+}
+
+// isOccupied returns true if the colony is controlled by a polity
+// other than its rightful ruler.
+func (c *Colony) isOccupied() bool {
+	if c == nil || c.rightfulPolity() == nil {
+		return false
+	}
+	return c.polity != c.rightfulPolity()
+}
+
+// rightfulPolity returns the polity that the people of the colony see as
+// their ruler. For a home colony that is the polity that founded it. For
+// other colonies it is the polity that controlled the colony before it
+// last changed hands, or nil if it never has.
+func (c *Colony) rightfulPolity() *Polity {
+	if c.originalPolity != nil {
+		return c.originalPolity
+	}
+	return c.previousPolity
+}
+
+// isOnStrike returns true if the rebels have shut down production.
+func (c *Colony) isOnStrike() bool {
+	return c.rebelFraction() >= rebelsStrike
+}
+
+// productionModifier returns the fraction of normal production that the
+// colony achieves given the current level of unrest.
+func (c *Colony) productionModifier() float64 {
+	f := c.rebelFraction()
+	if f < rebelsReduceProduction {
+		return 1
+	} else if f >= rebelsStrike {
+		return 0
+	}
+	// production falls from 100% to 50% as the rebels approach the strike threshold
+	return 1 - 0.5*(f-rebelsReduceProduction)/(rebelsStrike-rebelsReduceProduction)
+}
+
+// rebelFraction returns the fraction of the population that has joined
+// the rebels, weighted by the number of people of each kind.
+func (c *Colony) rebelFraction() float64 {
+	if c == nil {
+		return 0
+	}
+	if c.population.total == 0 {
+		return (c.rebels.construction + c.rebels.professionals + c.rebels.soldiers + c.rebels.spies + c.rebels.trainees + c.rebels.unskilled + c.rebels.others) / 7
+	}
+	rebels := c.rebels.construction*float64(c.population.construction) +
+		c.rebels.professionals*float64(c.population.professionals) +
+		c.rebels.soldiers*float64(c.population.soldiers) +
+		c.rebels.spies*float64(c.population.spies) +
+		c.rebels.trainees*float64(c.population.trainees) +
+		c.rebels.unskilled*float64(c.population.unskilled) +
+		c.rebels.others*float64(c.population.others)
+	return rebels / float64(c.population.total)
+}
+
+// rebelActions applies the effects of unrest to the colony.
+// Past the revolt threshold the colony throws off its ruler.
+// Independent colonies may strike but have no ruler to revolt against.
+// An occupied colony returns to its rightful ruler, otherwise it defects
+// to the polity with the strongest presence in the system or becomes
+// independent if there is no such polity.
+func (st *State) rebelActions(c *Colony) error {
+	f := c.rebelFraction()
+	if f < rebelsRevolt || c.polity == nil {
+		// independent colonies have no ruler to throw off
+		if f >= rebelsStrike {
+			c.polity.logf("colony %s: rebels (%s) have shut down production", c.number, utils.Percentage(f))
+		} else if f >= rebelsReduceProduction {
+			c.polity.logf("colony %s: rebels (%s) are disrupting production", c.number, utils.Percentage(f))
+		}
+		return nil
+	}
+
+	from, to := c.polity, c.rightfulPolity()
+	if to == nil || to == from {
+		to = st.strongestPolityInSystem(c.system, from)
+	}
+
+	// the rebels are now in charge, so there is no one left to rebel
+	c.rebels.construction, c.rebels.professionals, c.rebels.others = 0, 0, 0
+	c.rebels.soldiers, c.rebels.spies, c.rebels.trainees, c.rebels.unskilled = 0, 0, 0, 0
+
+	if to == nil {
+		from.logf("colony %s: rebels (%s) have declared independence", c.number, utils.Percentage(f))
+		return st.releaseColony(c)
+	}
+	from.logf("colony %s: rebels (%s) have defected to %s", c.number, utils.Percentage(f), to.name)
+	to.logf("colony %s: rebels have overthrown %s and joined us", c.number, from.name)
+	return st.transferColony(c, from, to)
+}
+
+// releaseColony makes the colony independent.
+// Independent colonies are not controlled by any polity, so they keep
+// running on the last settings (ration, pay, and so on) they were given.
+func (st *State) releaseColony(c *Colony) error {
+	if c.polity == nil {
+		return nil // already independent
+	}
+	from := c.polity
+	from.delColony(c)
+	c.polity, c.previousPolity = nil, from
+	// ships home-ported at the colony stay with the polity
+	for _, s := range c.controls.ships {
+		from.rehome(s)
+	}
+	from.eventf(ASSETTRANSFERRED, []string{c.id, from.id}, "%s: transferred to independents", c.number)
+	return nil
+}
+
+// strongestPolityInSystem returns the polity, other than the one given,
+// that controls the most colonies in the system.
+func (st *State) strongestPolityInSystem(system *System, not *Polity) *Polity {
+	var strongest *Polity
+	count := make(map[*Polity]int)
+	for _, c := range st.colonies {
+		if c.system != system || c.polity == nil || c.polity == not {
+			continue
+		}
+		count[c.polity]++
+		if strongest == nil || count[c.polity] > count[strongest] || (count[c.polity] == count[strongest] && c.polity.id < strongest.id) {
+			strongest = c.polity
+		}
+	}
+	return strongest
+}
+
+// clamp returns the value limited to the range [min, max].
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	} else if value > max {
+		return max
+	}
+	return value
+}
@@ -0,0 +1,194 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_ColonyIsOccupied(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		colonyID string
+		transfer []string // ids of the polities the colony is given to, in order
+		want     bool
+	}{
+		{"home colony with founder", "sanuki", nil, false},
+		{"home colony taken", "sanuki", []string{"kuma"}, true},
+		{"home colony taken back", "sanuki", []string{"kuma", "usagi"}, false},
+		{"colony never transferred", "tosa", nil, false},
+		{"colony taken", "tosa", []string{"kuma"}, true},
+		{"colony taken twice", "tosa", []string{"kuma", "tora"}, true},
+		{"colony taken back", "tosa", []string{"kuma", "usagi"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi := st.Polity("usagi")
+			mkrival(st, "kuma")
+			mkrival(st, "tora")
+			c := st.Colony(tc.colonyID)
+			if c.id == "sanuki" {
+				c.originalPolity = usagi
+			}
+			for _, id := range tc.transfer {
+				is.NoErr(st.transferColony(c, c.polity, st.Polity(id)))
+			}
+			is.Equal(c.isOccupied(), tc.want)
+		})
+	}
+}
+
+func Test_ColonyProductionModifier(t *testing.T) {
+	for _, tc := range []struct {
+		rebels float64
+		want   float64
+	}{
+		{0, 1},
+		{0.05, 1},
+		{rebelsReduceProduction, 1},
+		{0.175, 0.75},
+		{rebelsStrike, 0},
+		{0.9, 0},
+	} {
+		is := is.New(t)
+		c := &Colony{}
+		c.rebels.construction, c.rebels.professionals, c.rebels.soldiers, c.rebels.spies = tc.rebels, tc.rebels, tc.rebels, tc.rebels
+		c.rebels.trainees, c.rebels.unskilled, c.rebels.others = tc.rebels, tc.rebels, tc.rebels
+		got := c.productionModifier()
+		is.True(got > tc.want-1e-9 && got < tc.want+1e-9) // modifier for rebel fraction
+	}
+}
+
+func Test_ColonyUnrest(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		starving bool
+		ration   float64
+		pay      float64
+		occupied bool
+		soldiers int
+		rising   bool
+	}{
+		{"content", false, 1, 1, false, 0, false},
+		{"starving", true, 1, 1, false, 0, true},
+		{"short rations", false, 0.5, 1, false, 0, true},
+		{"low pay", false, 1, 0.5, false, 0, true},
+		{"occupied", false, 1, 1, true, 0, true},
+		{"occupied with garrison", false, 1, 1, true, 500, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			c.population = Population{unskilled: 1000 - tc.soldiers, soldiers: tc.soldiers, total: 1000}
+			c.rebels.unskilled = 0.05
+			c.ration, c.pay = tc.ration, tc.pay
+			if tc.occupied {
+				is.NoErr(st.transferColony(c, c.polity, mkrival(st, "kuma")))
+			}
+			c.unrest(tc.starving)
+			is.Equal(c.rebels.unskilled > 0.05, tc.rising) // rebels gained support
+		})
+	}
+}
+
+func Test_RebelActions(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		taken  bool // colony was taken from usagi by kuma
+		rebels float64
+		want   string // id of the polity controlling the colony afterwards
+	}{
+		{"quiet colony", false, 0, "usagi"},
+		{"striking colony", false, rebelsStrike, "usagi"},
+		{"revolt with no one to join", false, rebelsRevolt, ""},
+		{"occupied colony returns to its ruler", true, rebelsRevolt, "usagi"},
+		{"occupied colony on strike", true, rebelsStrike, "kuma"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			if tc.taken {
+				is.NoErr(st.transferColony(c, c.polity, mkrival(st, "kuma")))
+			}
+			c.population = Population{unskilled: 1000, total: 1000}
+			c.rebels.unskilled = tc.rebels
+			is.NoErr(st.rebelActions(c))
+			var got string
+			if c.polity != nil {
+				got = c.polity.id
+			}
+			is.Equal(got, tc.want)
+			is.Equal(len(st.Verify()), 0) // state is consistent
+		})
+	}
+}
+
+func Test_ReleaseColonyRehomesShips(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, tosa := st.Polity("usagi"), st.Colony("tosa")
+	s := mktestship(st, usagi, tosa, "S1")
+	is.NoErr(st.releaseColony(tosa))
+	is.True(tosa.polity == nil)
+	is.Equal(tosa.previousPolity, usagi) // former ruler is remembered
+	is.True(s.homePort != tosa)          // ship was rehomed
+	is.Equal(s.homePort.polity, usagi)   // at a colony the polity still controls
+	events, err := st.Events("usagi")
+	is.NoErr(err)
+	is.Equal(len(events), 1) // the former ruler is told
+	is.Equal(events[0].Kind, ASSETTRANSFERRED)
+	is.Equal(events[0].Entities, []string{"tosa", "usagi"})
+}
+
+func Test_ColonyEatsFood(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		ration   float64
+		stored   int
+		left     int
+		starving bool
+	}{
+		{"full ration from storage", 1, 3000, 2000, false},
+		{"storage covers the minimum", 1, 600, 0, false},
+		{"storage runs out", 1, 200, 0, true},
+		{"short ration", 0.2, 3000, 2800, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("sanuki")
+			c.units = nil // no farms, so the people eat from storage
+			c.population = Population{unskilled: 1000, total: 1000}
+			c.ration, c.storage.food = tc.ration, tc.stored
+			st.colonyProductionStage(false)
+			is.Equal(c.storage.food, tc.left) // food eaten
+			events, err := st.Events("usagi")
+			is.NoErr(err)
+			starving := false
+			for _, e := range events {
+				starving = starving || (e.Kind == STARVATION && e.Entities[0] == c.id)
+			}
+			is.Equal(starving, tc.starving)
+		})
+	}
+}
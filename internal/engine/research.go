@@ -0,0 +1,259 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"strconv"
+)
+
+// professionalsPerResearchPoint is the number of professionals needed
+// to produce one research point per turn.
+const professionalsPerResearchPoint = 1_000
+
+// traineesPerProfessional is the number of trainees that do the research
+// of one professional.
+const traineesPerProfessional = 4
+
+// researchCost returns the number of research points needed to raise
+// the tech level of a unit kind to the given level without a prototype.
+// Using a prototype halves the cost.
+func researchCost(techLevel int) int {
+	return 1_000 * techLevel * techLevel
+}
+
+// researchable returns true if the tech level of the kind of unit can be
+// raised. Raw resources and consumer goods do not have a tech level.
+func researchable(kind UnitKind) bool {
+	switch kind {
+	case ANTIMISSILE, ENERGYWEAPON, ENGINE, FACTORY, FARM, LIGHTSTRUCTURAL, MINE, MISSILE, POWER, STRUCTURAL, TRANSPORT:
+		return true
+	}
+	return false
+}
+
+// techLevel returns the highest tech level of the kind of unit that
+// the polity may assemble. Every polity starts at tech level 1.
+func (p *Polity) techLevel(kind UnitKind) int {
+	if p == nil {
+		return 0
+	} else if tl, ok := p.techLevels[kind]; ok {
+		return tl
+	}
+	return 1
+}
+
+// canAssemble returns an error if the polity may not assemble the unit.
+func (p *Polity) canAssemble(u Unit) error {
+	if !researchable(u.Kind) {
+		return nil
+	} else if max := p.techLevel(u.Kind); u.TechLevel > max {
+		return fmt.Errorf("%s exceeds tech level %d: %w", u, max, ERRFORBIDDEN)
+	}
+	return nil
+}
+
+// canManufacture returns an error if the polity may not manufacture the
+// unit. Factories can build prototypes one level above the polity's tech level.
+func (p *Polity) canManufacture(u Unit) error {
+	if !researchable(u.Kind) {
+		return nil
+	} else if max := p.techLevel(u.Kind) + 1; u.TechLevel > max {
+		return fmt.Errorf("%s exceeds prototype tech level %d: %w", u, max, ERRFORBIDDEN)
+	}
+	return nil
+}
+
+// advance applies research points toward the next tech level of the kind
+// of unit, raising it as many times as the points allow. Points left over
+// after a raise go to the committed buffer.
+func (p *Polity) advance(kind UnitKind, points int) {
+	if p.research.progress == nil {
+		p.research.progress = make(map[UnitKind]int)
+	}
+	if p.techLevels == nil {
+		p.techLevels = make(map[UnitKind]int)
+	}
+	progress, raised := p.research.progress[kind]+points, false
+	for next := p.techLevel(kind) + 1; progress >= researchCost(next); next++ {
+		progress -= researchCost(next)
+		p.techLevels[kind], raised = next, true
+	}
+	if raised {
+		p.research.committed += progress
+		progress = 0
+		p.logf("research: %s raised to tech level %d", kind, p.techLevel(kind))
+	}
+	p.research.progress[kind] = progress
+}
+
+// research returns the research points produced by the colony this turn.
+// Trainees help, but it takes several of them to do the work of a professional.
+func (c *Colony) research() int {
+	researchers := c.population.professionals + c.population.trainees/traineesPerProfessional
+	return int(float64(researchers/professionalsPerResearchPoint) * c.productionModifier())
+}
+
+// researchItem returns the kind of unit named by the item.
+func researchItem(item string) (UnitKind, error) {
+	kind, ok := unitKindFromString(item)
+	if !ok {
+		return NOOP, fieldError("item", fmt.Errorf("invalid item %q: %w", item, ERRBADREQUEST))
+	} else if !researchable(kind) {
+		return NOOP, fieldError("item", fmt.Errorf("item %q can not be researched: %w", item, ERRBADREQUEST))
+	}
+	return kind, nil
+}
+
+// ExpendResearchPointsOnly expends banked research points to raise the
+// tech level of an item.
+//
+// 1. Colony identified by ColonyID must accept orders from the polity issuing the order.
+// 2. Quantity must be greater than zero.
+// 3. Quantity may exceed the points in the bank; the overage is ignored
+// and reported.
+// 4. Points that are not needed for a raise are kept toward the next one.
+func (st *State) ExpendResearchPointsOnly(issuedByID, colonyID string, quantity int, item string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.ExpendResearchPointsOnly: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	colony := st.Colony(colonyID)
+	if colony == nil {
+		return fieldError("colony_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	} else if !colony.acceptsOrdersFrom(issuedBy) {
+		return fieldError("colony_id", fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN))
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	kind, err := researchItem(item)
+	if err != nil {
+		return err
+	}
+	p := colony.polity
+	points := minInt(quantity, p.research.points)
+	if points == 0 {
+		return fieldError("colony_id", fmt.Errorf("no research points: %w", ERRBADREQUEST))
+	}
+	p.research.points -= points
+	if points < quantity {
+		p.logf("%s: expended %s of %s research points on %s (not enough points)", colonyID, utils.Commas(points), utils.Commas(quantity), kind)
+	} else {
+		p.logf("%s: expended %s research points on %s", colonyID, utils.Commas(points), kind)
+	}
+	p.advance(kind, points)
+	if points < quantity {
+		return fmt.Errorf("expended %d of %d: %w", points, quantity, ERRPARTIAL)
+	}
+	return nil
+}
+
+// ExpendPrototype consumes a prototype and banked research points to
+// raise the tech level of an item.
+//
+// 1. Colony identified by ColonyID must accept orders from the polity issuing the order.
+// 2. Quantity must be greater than zero. Only one prototype is consumed.
+// 3. The prototype must be in storage at the colony and must be one
+// tech level above the polity's current tech level for the item.
+// 4. Half the usual research points are needed and must be in the bank.
+func (st *State) ExpendPrototype(issuedByID, colonyID string, quantity int, item, techLevel string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.ExpendPrototype: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	colony := st.Colony(colonyID)
+	if colony == nil {
+		return fieldError("colony_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	} else if !colony.acceptsOrdersFrom(issuedBy) {
+		return fieldError("colony_id", fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN))
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	kind, err := researchItem(item)
+	if err != nil {
+		return err
+	}
+	tl, err := strconv.Atoi(techLevel)
+	if err != nil {
+		return fieldError("tech_level", fmt.Errorf("invalid tech level %q: %w", techLevel, ERRBADREQUEST))
+	}
+	p := colony.polity
+	if next := p.techLevel(kind) + 1; tl != next {
+		return fieldError("tech_level", fmt.Errorf("prototype must be tech level %d: %w", next, ERRBADREQUEST))
+	}
+	cost := researchCost(tl) / 2
+	if p.research.points < cost {
+		return fieldError("colony_id", fmt.Errorf("need %s research points: %w", utils.Commas(cost), ERRBADREQUEST))
+	}
+	d := colony.depot()
+	if d.removeUnit(kind, tl, false, 1) == 0 {
+		return fieldError("quantity", fmt.Errorf("no %s prototype in storage: %w", Unit{Kind: kind, TechLevel: tl}, ERRBADREQUEST))
+	}
+	p.research.points -= cost
+	p.logf("%s: expended %s prototype and %s research points", colonyID, Unit{Kind: kind, TechLevel: tl}, utils.Commas(cost))
+	p.advance(kind, researchCost(tl)-p.research.progress[kind])
+	return nil
+}
+
+// ExpendCommittedBufferResearchPoints expends points from the committed
+// buffer to raise the tech level of an item.
+//
+// 1. Colony identified by ColonyID must accept orders from the polity issuing the order.
+// 2. Quantity must be greater than zero.
+// 3. Quantity may exceed the points in the buffer; the overage is ignored
+// and reported.
+func (st *State) ExpendCommittedBufferResearchPoints(issuedByID, colonyID string, quantity int, item string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.ExpendCommittedBufferResearchPoints: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	colony := st.Colony(colonyID)
+	if colony == nil {
+		return fieldError("colony_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	} else if !colony.acceptsOrdersFrom(issuedBy) {
+		return fieldError("colony_id", fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN))
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	kind, err := researchItem(item)
+	if err != nil {
+		return err
+	}
+	p := colony.polity
+	points := minInt(quantity, p.research.committed)
+	if points == 0 {
+		return fieldError("colony_id", fmt.Errorf("no committed research points: %w", ERRBADREQUEST))
+	}
+	p.research.committed -= points
+	if points < quantity {
+		p.logf("%s: expended %s of %s committed research points on %s (not enough points)", colonyID, utils.Commas(points), utils.Commas(quantity), kind)
+	} else {
+		p.logf("%s: expended %s committed research points on %s", colonyID, utils.Commas(points), kind)
+	}
+	p.advance(kind, points)
+	if points < quantity {
+		return fmt.Errorf("expended %d of %d: %w", points, quantity, ERRPARTIAL)
+	}
+	return nil
+}
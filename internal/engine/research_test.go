@@ -0,0 +1,188 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_ColonyResearch(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		professionals int
+		trainees      int
+		rebels        float64
+		want          int
+	}{
+		{"nobody", 0, 0, 0, 0},
+		{"professionals", 2_500, 0, 0, 2},
+		{"trainees help", 2_000, 4_000, 0, 3},
+		{"strike", 2_000, 4_000, rebelsStrike, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			c := &Colony{population: Population{professionals: tc.professionals, trainees: tc.trainees, total: tc.professionals + tc.trainees}}
+			c.rebels.professionals, c.rebels.trainees = tc.rebels, tc.rebels
+			is.Equal(c.research(), tc.want)
+		})
+	}
+}
+
+func Test_PolityAdvance(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		progress  int
+		points    int
+		techLevel int
+		remaining int // progress toward the next tech level
+		committed int
+	}{
+		{"short of a raise", 0, 1_000, 1, 1_000, 0},
+		{"adds to progress", 3_000, 999, 1, 3_999, 0},
+		{"one raise", 3_000, 1_000, 2, 0, 0},
+		{"leftover is committed", 0, 5_000, 2, 0, 1_000},
+		{"two raises", 0, 13_000, 3, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			p := polity()
+			p.research.progress[ENGINE] = tc.progress
+			p.advance(ENGINE, tc.points)
+			is.Equal(p.techLevel(ENGINE), tc.techLevel)
+			is.Equal(p.research.progress[ENGINE], tc.remaining)
+			is.Equal(p.research.committed, tc.committed)
+			is.Equal(p.techLevel(MISSILE), 1) // other kinds are not raised
+		})
+	}
+}
+
+func Test_ExpendResearchPointsOnly(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		issuedBy  string
+		colonyID  string
+		banked    int
+		quantity  int
+		item      string
+		err       error
+		techLevel int
+		left      int // points left in the bank
+	}{
+		{"raises tech level", "usagi", "tosa", 5_000, 4_000, "ENGINE", nil, 2, 1_000},
+		{"quantity exceeds bank", "usagi", "tosa", 2_000, 5_000, "ENGINE", ERRPARTIAL, 1, 0},
+		{"zero quantity", "usagi", "tosa", 5_000, 0, "ENGINE", ERRBADREQUEST, 1, 5_000},
+		{"empty bank", "usagi", "tosa", 0, 1_000, "ENGINE", ERRBADREQUEST, 1, 0},
+		{"not researchable", "usagi", "tosa", 5_000, 4_000, "GOODS", ERRBADREQUEST, 1, 5_000},
+		{"unknown colony", "usagi", "nowhere", 5_000, 4_000, "ENGINE", ERRBADREQUEST, 1, 5_000},
+		{"colony refuses", "kuma", "tosa", 5_000, 4_000, "ENGINE", ERRFORBIDDEN, 1, 5_000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			mkrival(st, "kuma")
+			usagi := st.Polity("usagi")
+			usagi.research.points = tc.banked
+			err := st.ExpendResearchPointsOnly(tc.issuedBy, tc.colonyID, tc.quantity, tc.item)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(usagi.techLevel(ENGINE), tc.techLevel)
+			is.Equal(usagi.research.points, tc.left)
+		})
+	}
+}
+
+func Test_ExpendPrototype(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		banked     int
+		progress   int
+		prototype  int // tech level of the ENGINE prototype in storage, 0 for none
+		techLevel  string
+		err        error
+		raisedTo   int
+		left       int // points left in the bank
+		prototypes int // prototypes left in storage
+	}{
+		{"raises tech level", 2_000, 0, 2, "2", nil, 2, 0, 0},
+		{"progress is kept", 2_000, 1_000, 2, "2", nil, 2, 0, 0},
+		{"wrong tech level", 2_000, 0, 3, "3", ERRBADREQUEST, 1, 2_000, 1},
+		{"invalid tech level", 2_000, 0, 2, "two", ERRBADREQUEST, 1, 2_000, 1},
+		{"not enough points", 1_999, 0, 2, "2", ERRBADREQUEST, 1, 1_999, 1},
+		{"no prototype", 2_000, 0, 0, "2", ERRBADREQUEST, 1, 2_000, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi := st.Polity("usagi")
+			usagi.research.points = tc.banked
+			usagi.research.progress[ENGINE] = tc.progress
+			d := st.Colony("tosa").depot()
+			if tc.prototype != 0 {
+				d.addUnit(Unit{Kind: ENGINE, TechLevel: tc.prototype, Quantity: 1})
+			}
+			err := st.ExpendPrototype("usagi", "tosa", 1, "ENGINE", tc.techLevel)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(usagi.techLevel(ENGINE), tc.raisedTo)
+			is.Equal(usagi.research.points, tc.left)
+			is.Equal(d.countUnit(ENGINE, tc.prototype, false), tc.prototypes)
+			if tc.err == nil {
+				is.Equal(usagi.research.progress[ENGINE], 0) // prototype completes the raise
+				is.Equal(usagi.research.committed, 0)        // and leaves nothing over
+			}
+		})
+	}
+}
+
+func Test_ExpendCommittedBufferResearchPoints(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		committed int
+		quantity  int
+		err       error
+		techLevel int
+		left      int // points left in the committed buffer
+	}{
+		{"raises tech level", 5_000, 4_000, nil, 2, 1_000},
+		{"quantity exceeds buffer", 3_000, 4_000, ERRPARTIAL, 1, 0},
+		{"zero quantity", 5_000, 0, ERRBADREQUEST, 1, 5_000},
+		{"empty buffer", 0, 4_000, ERRBADREQUEST, 1, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi := st.Polity("usagi")
+			usagi.research.committed = tc.committed
+			err := st.ExpendCommittedBufferResearchPoints("usagi", "tosa", tc.quantity, "ENGINE")
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(usagi.techLevel(ENGINE), tc.techLevel)
+			is.Equal(usagi.research.committed, tc.left)
+		})
+	}
+}
+
+func Test_CreatePolityTechLevels(t *testing.T) {
+	is := is.New(t)
+	st, admin := Make()
+	is.Equal(len(st.CreatePolity(admin, "tomoe", "Tomoe")), 0)
+	p := st.Polity("tomoe")
+	is.Equal(p.techLevel(ENGINE), 1) // new polities start at tech level 1
+	is.True(p.research.progress != nil)
+	is.True(p.techLevels != nil)
+}
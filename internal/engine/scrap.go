@@ -20,7 +20,9 @@ package engine
 
 import (
 	"fmt"
+	"github.com/mdhender/server/pkg/utils"
 	"log"
+	"math"
 )
 
 // Scrap order...
@@ -37,7 +39,8 @@ type Scrap struct {
 // 2. The Item to scrap must be Unassembled or a Non-Assembly unit.
 // 3. Quantity must not be less than zero (0).
 // 4. Quantity may exceed the actual number of items controlled by the actor.
-//    Any overage will be ignored.
+//    Any overage will be ignored and the order is partially executed.
+//    The order is rejected if no items can be scrapped.
 // 5. One (1) Constructor is required per 300 Mass Units (or portion).
 // 6. The Item being scrapped will lose 30% of its Mass as waste.
 func (st *State) Scrap(issuedByID, actorID, item string, techLevel, quantity int) error {
@@ -48,25 +51,50 @@ func (st *State) Scrap(issuedByID, actorID, item string, techLevel, quantity int
 	}
 
 	// actor must be a colony or ship controlled by the polity issuing the order
-	var actor struct {
-		polity *Polity
-		colony *Colony
-		ship   *Ship
-		system *System
+	d, err := st.findDepot(issuedBy, actorID)
+	if err != nil {
+		return fieldError("actor_id", err)
+	} else if quantity < 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
 	}
-	if colony := st.Colony(actorID); colony != nil {
-		actor.polity = colony.polity
-		actor.colony = colony
-	} else if ship := st.Ship(actorID); ship != nil {
-		actor.polity = ship.polity
-		actor.ship = ship
-	} else {
-		return fmt.Errorf("invalid actor %q: %w", actorID, ERRBADREQUEST)
+	kind, ok := unitKindFromString(item)
+	if !ok {
+		return fieldError("item", fmt.Errorf("invalid item %q: %w", item, ERRBADREQUEST))
 	}
-	if actor.polity != issuedBy {
-		return fmt.Errorf("actor refuses order: %w", ERRFORBIDDEN)
+	one := Unit{Kind: kind, TechLevel: techLevel, Quantity: 1}
+	metals, nonMetals := one.Materials()
+	if metals+nonMetals <= 0 {
+		return fieldError("item", fmt.Errorf("item %q can not be scrapped: %w", item, ERRBADREQUEST))
 	}
 
-	log.Printf("[bug] State.Scrap: not implemented\n")
-	return ERRNOTIMPLEMENTED
+	// limit by the items in storage, the constructors available, and the
+	// storage needed for any increase in volume from the recovered materials
+	u := Unit{Kind: kind, TechLevel: techLevel}
+	u.Quantity = minInt(quantity, d.countUnit(kind, techLevel, false))
+	if mass := int(math.Ceil(one.Mass())); mass > 0 {
+		u.Quantity = minInt(u.Quantity, d.constructors()*3*massPerConstructor/mass)
+	}
+	recovered := scrapRecovery * (Unit{Kind: METAL, Quantity: 1}.Volume()*metals + Unit{Kind: NONMETAL, Quantity: 1}.Volume()*nonMetals)
+	if growth := recovered - one.Volume(); growth > 0 {
+		u.Quantity = d.storageLimit(growth, 0, u.Quantity)
+	}
+	if quantity == 0 {
+		return nil // nothing to do
+	} else if u.Quantity <= 0 {
+		return fmt.Errorf("not enough units, constructors, or storage to scrap %s: %w", u, ERRBADREQUEST)
+	} else if u.Quantity < quantity {
+		d.polity().logf("%s: scrapped %s of %s %s (not enough units, constructors, or storage)", actorID, utils.Commas(u.Quantity), utils.Commas(quantity), u)
+	} else {
+		d.polity().logf("%s: scrapped %s %s", actorID, utils.Commas(u.Quantity), u)
+	}
+
+	*d.constructionUsed += int(math.Ceil(u.Mass() / (3 * massPerConstructor)))
+	d.removeUnit(kind, techLevel, false, u.Quantity)
+	// the epsilon keeps float error from costing a whole unit of material
+	d.storage.metal += int(scrapRecovery*metals*float64(u.Quantity) + 1e-9)
+	d.storage.nonmetal += int(scrapRecovery*nonMetals*float64(u.Quantity) + 1e-9)
+	if u.Quantity < quantity {
+		return fmt.Errorf("scrapped %d of %d: %w", u.Quantity, quantity, ERRPARTIAL)
+	}
+	return nil
 }
@@ -0,0 +1,322 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+)
+
+// setUpItems is the list of items in a SetUp order.
+type setUpItems []struct {
+	Factory *struct {
+		Quantity      int    `json:"quantity"`
+		ItemToBuild   string `json:"item_to_build"`
+		ItemTechLevel int    `json:"item_tech_level"`
+	} `json:"factory,omitempty"`
+	Item *struct {
+		Quantity      int    `json:"quantity"`
+		Item          string `json:"item"`
+		TechLevel     int    `json:"tech_level"`
+		DoNotAssemble bool   `json:"do_not_assemble,omitempty"`
+	} `json:"item,omitempty"`
+	Mine *struct {
+		Quantity    int    `json:"quantity"`
+		DepositID   string `json:"deposit_id,omitempty"`
+		DepositType string `json:"deposit_type,omitempty"`
+	} `json:"mine,omitempty"`
+}
+
+// mayColonize returns true if the ship has permission to colonize the planet this turn.
+func (s *Ship) mayColonize(planet *Planet) bool {
+	for _, p := range s.colonize {
+		if p == planet {
+			return true
+		}
+	}
+	return false
+}
+
+// claimed returns true if the planet has a colony that doesn't accept
+// orders from the polity.
+func (planet *Planet) claimed(p *Polity) bool {
+	for _, c := range planet.colonies {
+		if !c.acceptsOrdersFrom(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// habitable returns an error if the kind of colony can't be set up on
+// the planet. A nil planet is an orbiting colony.
+func habitable(kind ColonyKind, planet *Planet) error {
+	switch kind {
+	case OPEN:
+		if planet == nil || planet.kind != TERRESTRIAL || planet.habitability <= 0 {
+			return fmt.Errorf("open colonies need a habitable planet: %w", ERRBADREQUEST)
+		}
+	case ENCLOSED:
+		if planet == nil || planet.kind == GASGIANT {
+			return fmt.Errorf("enclosed colonies need a planet with a surface: %w", ERRBADREQUEST)
+		}
+	case ORBITING:
+		if planet != nil {
+			return fmt.Errorf("orbiting colonies are not set up on a planet: %w", ERRBADREQUEST)
+		}
+	}
+	return nil
+}
+
+// assembleFor assembles units that were unloaded at the depot, using the
+// labor and power of the crew. It returns the number of units assembled.
+// Factories are assembled into a new group that builds the item.
+func (crew depot) assembleFor(at depot, u Unit, builds Unit) int {
+	u.Quantity = crew.assemblyLimit(u, minInt(u.Quantity, at.countUnit(u.Kind, u.TechLevel, false)))
+	if u.Quantity <= 0 {
+		return 0
+	}
+	crew.useAssembly(u)
+	at.removeUnit(u.Kind, u.TechLevel, false, u.Quantity)
+	u.Assembled = true
+	if u.Kind == FACTORY {
+		*at.factories = append(*at.factories, &FactoryGroup{id: at.nextFactoryGroupID(), units: u, builds: builds})
+	} else {
+		at.addUnit(u)
+	}
+	return u.Quantity
+}
+
+// addOn moves stored items from one depot to another and assembles them
+// with the source's labor and power unless told not to. Only units that are
+// assembled on their own are assembled; the rest stay in storage. Storage
+// isn't checked because assembling structural units makes room. It returns
+// ERRBADREQUEST if nothing is in storage, or ERRPARTIAL if less than the
+// full quantity was unloaded or assembled.
+func addOn(from, to depot, item string, techLevel, quantity int, doNotAssemble bool) error {
+	c, err := itemCargo(item, techLevel, quantity)
+	if err != nil {
+		return err
+	}
+	p, kind := from.polity(), c.unit.Kind
+	assemble := !doNotAssemble && assemblable(kind) && kind != FACTORY && kind != MINE
+	if assemble {
+		if err := p.canAssemble(c.unit); err != nil {
+			return fieldError("tech_level", err)
+		}
+	}
+	c.unit.Quantity = minInt(quantity, from.stored(c))
+	if c.unit.Quantity == 0 {
+		return fieldError("item", fmt.Errorf("no %s in storage: %w", c, ERRBADREQUEST))
+	}
+	from.unload(c)
+	to.load(c)
+	if c.unit.Quantity < quantity {
+		p.logf("%s: unloaded %s of %s %s at %s (not enough in storage)", from.id, utils.Commas(c.unit.Quantity), utils.Commas(quantity), c, to.id)
+	} else {
+		p.logf("%s: unloaded %s %s at %s", from.id, utils.Commas(c.unit.Quantity), c, to.id)
+	}
+	n, u := c.unit.Quantity, c.unit
+	if assemble {
+		if n = from.assembleFor(to, u, Unit{}); n < u.Quantity {
+			p.logf("%s: assembled %s of %s %s (not enough labor or power)", to.id, utils.Commas(n), utils.Commas(u.Quantity), u)
+		} else {
+			p.logf("%s: assembled %s %s", to.id, utils.Commas(n), u)
+		}
+	}
+	if n < quantity {
+		return fmt.Errorf("added on %d of %d: %w", n, quantity, ERRPARTIAL)
+	}
+	return nil
+}
+
+// SetUp founds a new colony with the people and items carried by a ship.
+//
+// 1. Ship identified by SourceID must accept orders from the polity issuing the order.
+// 2. The ship must be in an orbit. Open and enclosed colonies are set up on
+// the orbit's planet and orbiting colonies are set up in the orbit.
+// 3. Open colonies need a habitable terrestrial planet. Enclosed colonies
+// need a planet with a surface, so gas giants are excluded.
+// 4. A planet with a colony that doesn't accept orders from the polity
+// needs permission to colonize from one of the colonies.
+// 5. Quantity people are unloaded from the ship, taken from each kind of
+// population in proportion. At least one person must be unloaded.
+// 6. Items are unloaded from the ship's storage and assembled by the ship's
+// construction workers and power. Factories are assembled into a group
+// that builds the item. Mines are not implemented.
+// 7. Each item must be in the ship's storage. Quantity may exceed the number
+// in storage or the labor and power available; the colony is still set up
+// and the overage is ignored and reported.
+func (st *State) SetUp(issuedByID, sourceID, typeOfColony string, quantity int, items setUpItems) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.SetUp: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	ship := st.Ship(sourceID)
+	if ship == nil {
+		return fieldError("source_id", fmt.Errorf("invalid ship %q: %w", sourceID, ERRBADREQUEST))
+	} else if !ship.acceptsOrdersFrom(issuedBy) {
+		return fieldError("source_id", fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN))
+	} else if ship.orbit == nil {
+		return fieldError("source_id", fmt.Errorf("ship is not in an orbit: %w", ERRBADREQUEST))
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	} else if ship.population.total == 0 {
+		return fieldError("source_id", fmt.Errorf("ship has no people: %w", ERRBADREQUEST))
+	}
+	kind, ok := colonyKindFromString(typeOfColony)
+	if !ok {
+		return fieldError("type_of_colony", fmt.Errorf("invalid type of colony %q: %w", typeOfColony, ERRBADREQUEST))
+	}
+	planet := ship.orbit.planet
+	if kind == ORBITING {
+		planet = nil
+	}
+	if err := habitable(kind, planet); err != nil {
+		return fieldError("type_of_colony", err)
+	} else if planet != nil && planet.claimed(ship.polity) && !ship.mayColonize(planet) {
+		return fieldError("source_id", fmt.Errorf("no permission to colonize %q: %w", planet.id, ERRFORBIDDEN))
+	}
+
+	// check the items and factories before anything is unloaded
+	from := ship.depot()
+	builds := make([]Unit, len(items))
+	for i, item := range items {
+		if item.Mine != nil {
+			return fieldError(fmt.Sprintf("items/%d", i), fmt.Errorf("setting up mines: %w", ERRNOTIMPLEMENTED))
+		} else if item.Item != nil {
+			c, err := itemCargo(item.Item.Item, item.Item.TechLevel, item.Item.Quantity)
+			if err != nil {
+				return fieldError(fmt.Sprintf("items/%d", i), err)
+			} else if u := c.unit; !item.Item.DoNotAssemble && assemblable(u.Kind) && u.Kind != FACTORY && u.Kind != MINE {
+				if err = ship.polity.canAssemble(u); err != nil {
+					return fieldError(fmt.Sprintf("items/%d", i), err)
+				}
+			}
+			if from.stored(c) == 0 {
+				return fieldError(fmt.Sprintf("items/%d", i), fmt.Errorf("no %s in storage: %w", c, ERRBADREQUEST))
+			}
+		} else if item.Factory != nil {
+			u, err := manufacturable(item.Factory.ItemToBuild, item.Factory.ItemTechLevel)
+			if err != nil {
+				return fieldError(fmt.Sprintf("items/%d", i), err)
+			} else if err = ship.polity.canManufacture(u); err != nil {
+				return fieldError(fmt.Sprintf("items/%d", i), err)
+			} else if item.Factory.Quantity <= 0 {
+				return fieldError(fmt.Sprintf("items/%d", i), fmt.Errorf("invalid quantity %d: %w", item.Factory.Quantity, ERRBADREQUEST))
+			} else if from.setUpTechLevel() == 0 {
+				return fieldError(fmt.Sprintf("items/%d", i), fmt.Errorf("no factory units in storage: %w", ERRBADREQUEST))
+			}
+			builds[i] = u
+		}
+	}
+
+	colony := mkcolony(ship.polity, ship.orbit, planet, kind)
+	st.colonies[colony.id] = colony
+	ship.polity.addColony(colony)
+	colonists := ship.population.split(quantity)
+	colony.population.add(colonists)
+	ship.polity.logf("%s: set up %s colony %s with %s people", sourceID, kind, colony.number, utils.Commas(colonists.total))
+
+	// items checked above can still come up short when they share the
+	// same stock, labor, or power, but the colony has been set up
+	to, partial := colony.depot(), false
+	for i, item := range items {
+		var err error
+		if item.Item != nil {
+			err = addOn(from, to, item.Item.Item, item.Item.TechLevel, item.Item.Quantity, item.Item.DoNotAssemble)
+		} else if item.Factory != nil {
+			err = setUpFactories(from, to, item.Factory.Quantity, builds[i])
+		}
+		if err != nil {
+			if !errors.Is(err, ERRPARTIAL) {
+				ship.polity.logf("%s: items/%d: %v", sourceID, i, err)
+			}
+			partial = true
+		}
+	}
+	if partial {
+		return fmt.Errorf("set up %s without all of the items: %w", colony.number, ERRPARTIAL)
+	}
+	return nil
+}
+
+// setUpTechLevel returns the highest tech level of the factory units in
+// the depot's storage that its polity can assemble, or zero if there are none.
+func (d depot) setUpTechLevel() (techLevel int) {
+	p := d.polity()
+	for _, u := range *d.units {
+		if u.Kind == FACTORY && !u.Assembled && u.Quantity != 0 && u.TechLevel > techLevel && p.canAssemble(u) == nil {
+			techLevel = u.TechLevel
+		}
+	}
+	return techLevel
+}
+
+// setUpFactories unloads factory units from the ship, highest tech level
+// first, and assembles them into a group at the colony. It returns
+// ERRBADREQUEST if there are no factory units in storage, or ERRPARTIAL
+// if less than the full quantity was assembled.
+func setUpFactories(from, to depot, quantity int, builds Unit) error {
+	p, techLevel := from.polity(), from.setUpTechLevel()
+	if techLevel == 0 {
+		return fmt.Errorf("no factory units in storage: %w", ERRBADREQUEST)
+	}
+	u := Unit{Kind: FACTORY, TechLevel: techLevel}
+	u.Quantity = from.removeUnit(FACTORY, techLevel, false, quantity)
+	to.addUnit(u)
+	n := from.assembleFor(to, u, builds)
+	if n < quantity {
+		p.logf("%s: assembled %s of %s %s to build %s (not enough units, labor, or power)", to.id, utils.Commas(n), utils.Commas(quantity), u, builds)
+		return fmt.Errorf("assembled %d of %d: %w", n, quantity, ERRPARTIAL)
+	}
+	p.logf("%s: assembled %s %s to build %s", to.id, utils.Commas(n), u, builds)
+	return nil
+}
+
+// AddOn unloads items from a ship or colony into an existing colony.
+//
+// 1. Source identified by SourceID and the colony identified by TargetID
+// must accept orders from the polity issuing the order.
+// 2. Both must be in the same orbit.
+// 3. Items are assembled by the source's construction workers and power
+// unless DoNotAssemble is set. Factories and mines are left in storage.
+// 4. Quantity may exceed the number in storage or the labor and power
+// available; the overage is ignored and reported.
+func (st *State) AddOn(issuedByID, sourceID, targetID, item string, techLevel, quantity int, doNotAssemble bool) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.AddOn: issuedByID is invalid\n")
+		return ERRBUG
+	} else if st.Colony(targetID) == nil {
+		return fieldError("target_id", fmt.Errorf("invalid colony %q: %w", targetID, ERRBADREQUEST))
+	}
+	from, to, err := st.cargoDepots(issuedBy, "source_id", sourceID, "target_id", targetID)
+	if err != nil {
+		return err
+	} else if from.id == to.id {
+		return fieldError("target_id", fmt.Errorf("can not add on to itself: %w", ERRBADREQUEST))
+	} else if from.orbit() == nil || from.orbit() != to.orbit() {
+		return fieldError("target_id", fmt.Errorf("%q and %q are not in the same orbit: %w", sourceID, targetID, ERRBADREQUEST))
+	}
+	return addOn(from, to, item, techLevel, quantity, doNotAssemble)
+}
@@ -0,0 +1,149 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+// mksetupitems returns the items of a SetUp order from their JSON.
+func mksetupitems(s string) (items setUpItems) {
+	if err := json.Unmarshal([]byte(s), &items); err != nil {
+		panic(err)
+	}
+	return items
+}
+
+// mkcolonyship returns a ship in orbit around sanuki, which is on the
+// terrestrial planet suisei, carrying colonists, power, and five
+// unassembled tech level 1 engines.
+func mkcolonyship(st *State, p *Polity) *Ship {
+	s := mktestship(st, p, st.Colony("sanuki"), "S1",
+		Unit{Kind: POWER, TechLevel: 1, Quantity: 10, Assembled: true},
+		Unit{Kind: ENGINE, TechLevel: 1, Quantity: 5})
+	s.population = Population{construction: 10, unskilled: 90, total: 100}
+	return s
+}
+
+func Test_SetUp(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		kind     string
+		quantity int
+		items    string
+		err      error
+		onPlanet bool // colony is on suisei rather than in orbit
+	}{
+		{"open colony", "open", 50, `[]`, nil, true},
+		{"enclosed colony", "enclosed", 50, `[]`, nil, true},
+		{"orbiting colony", "orbiting", 50, `[]`, nil, false},
+		{"with items", "open", 50, `[{"item":{"item":"ENGINE","tech_level":1,"quantity":5}}]`, nil, true},
+		{"unknown kind", "domed", 50, `[]`, ERRBADREQUEST, false},
+		{"zero quantity", "open", 0, `[]`, ERRBADREQUEST, false},
+		{"unknown item", "open", 50, `[{"item":{"item":"WIDGET","tech_level":1,"quantity":5}}]`, ERRBADREQUEST, false},
+		{"item tech level too high", "open", 50, `[{"item":{"item":"ENGINE","tech_level":2,"quantity":5}}]`, ERRFORBIDDEN, false},
+		{"mines", "open", 50, `[{"mine":{"quantity":5}}]`, ERRNOTIMPLEMENTED, false},
+		{"item not in storage", "open", 50, `[{"item":{"item":"METAL","tech_level":1,"quantity":5}}]`, ERRBADREQUEST, false},
+		{"no factory units", "open", 50, `[{"factory":{"quantity":5,"item_to_build":"ENGINE","item_tech_level":1}}]`, ERRBADREQUEST, false},
+		{"more items than stored", "open", 50, `[{"item":{"item":"ENGINE","tech_level":1,"quantity":8}}]`, ERRPARTIAL, true},
+		{"stock used by an earlier item", "open", 50, `[{"item":{"item":"ENGINE","tech_level":1,"quantity":5}},{"item":{"item":"ENGINE","tech_level":1,"quantity":5}}]`, ERRPARTIAL, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi := st.Polity("usagi")
+			s := mkcolonyship(st, usagi)
+			colonies, suisei := len(st.colonies), st.Planet("suisei")
+			settlers := len(suisei.colonies)
+			err := st.SetUp("usagi", "S1", tc.kind, tc.quantity, mksetupitems(tc.items))
+			is.True(errors.Is(err, tc.err)) // error
+			if tc.err != nil && !errors.Is(tc.err, ERRPARTIAL) {
+				is.Equal(len(st.colonies), colonies) // rejected orders don't found colonies
+				is.Equal(s.population.total, 100)
+				return
+			}
+			is.Equal(len(st.colonies), colonies+1)
+			is.Equal(len(suisei.colonies)-settlers == 1, tc.onPlanet)
+			is.Equal(s.population.total, 50) // colonists left the ship
+		})
+	}
+}
+
+func Test_SetUpAssemblesItems(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi := st.Polity("usagi")
+	s := mkcolonyship(st, usagi)
+	is.NoErr(st.SetUp("usagi", "S1", "orbiting", 50, mksetupitems(`[{"item":{"item":"ENGINE","tech_level":1,"quantity":5}}]`)))
+	var colony *Colony
+	for _, c := range st.colonies {
+		if c.kind == ORBITING && c.orbit == s.orbit {
+			colony = c
+		}
+	}
+	is.True(colony != nil)
+	is.Equal(colony.polity, usagi)
+	is.Equal(colony.depot().countUnit(ENGINE, 1, true), 5) // assembled by the ship's crew
+	is.Equal(s.depot().countUnit(ENGINE, 1, false), 0)
+}
+
+func Test_SetUpNeedsPermission(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	mkcolonyship(st, kuma)
+	is.True(errors.Is(st.SetUp("kuma", "S1", "open", 50, nil), ERRFORBIDDEN))          // suisei is claimed by usagi
+	is.True(errors.Is(st.PermissionToColonize("usagi", "suisei", "S1"), ERRFORBIDDEN)) // usagi is not allied to kuma
+	usagi.diplomacy["kuma"], kuma.diplomacy["usagi"] = ALLY, ALLY
+	is.True(errors.Is(st.PermissionToColonize("kuma", "suisei", "S1"), ERRFORBIDDEN))    // kuma has no colony on suisei
+	is.True(errors.Is(st.PermissionToColonize("usagi", "nowhere", "S1"), ERRBADREQUEST)) // unknown planet
+	is.True(errors.Is(st.PermissionToColonize("usagi", "suisei", "S2"), ERRBADREQUEST))  // unknown ship
+	is.NoErr(st.PermissionToColonize("usagi", "suisei", "S1"))
+	is.NoErr(st.SetUp("kuma", "S1", "open", 50, nil))
+}
+
+func Test_AddOn(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa, s1 := mktestfreighter(st)
+	s1.storage.metal = 10
+	is.True(errors.Is(st.AddOn("usagi", "S1", "tosa", "METAL", 1, 20, false), ERRPARTIAL))
+	is.Equal(tosa.storage.metal, 10) // quantity may exceed the stock
+	is.Equal(s1.storage.metal, 0)
+	is.True(errors.Is(st.AddOn("usagi", "S1", "tosa", "METAL", 1, 20, false), ERRBADREQUEST)) // nothing left to add on
+
+	is.True(errors.Is(st.AddOn("usagi", "S1", "S1", "METAL", 1, 20, false), ERRBADREQUEST))     // target must be a colony
+	is.True(errors.Is(st.AddOn("usagi", "tosa", "tosa", "METAL", 1, 20, false), ERRBADREQUEST)) // not to itself
+	is.True(errors.Is(st.AddOn("usagi", "S1", "sanuki", "METAL", 1, 20, false), ERRBADREQUEST)) // not in the same orbit
+}
+
+func Test_AddOnChecksTechLevelFirst(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	tosa, s1 := mktestfreighter(st)
+	s1.depot().addUnit(Unit{Kind: ENGINE, TechLevel: 2, Quantity: 5})
+	is.True(errors.Is(st.AddOn("usagi", "S1", "tosa", "ENGINE", 2, 5, false), ERRFORBIDDEN)) // usagi can't assemble tech level 2
+	is.Equal(s1.depot().countUnit(ENGINE, 2, false), 5)                                      // so nothing is unloaded
+	is.Equal(tosa.depot().countUnit(ENGINE, 2, false), 0)
+	is.NoErr(st.AddOn("usagi", "S1", "tosa", "ENGINE", 2, 5, true)) // unless it isn't assembled
+	is.Equal(tosa.depot().countUnit(ENGINE, 2, false), 5)
+}
@@ -0,0 +1,53 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"log"
+)
+
+// ShareNames order copies the polity's names for stars, systems, and planets to an ally.
+type ShareNames struct {
+	PolityID string `json:"polity_id"` // id of polity to share names with
+}
+
+// ShareNames copies the names the issuer has given to stars, systems, and planets
+// into the name table of another polity.
+//
+// 1. Polity identified by PolityID must be allied to the polity issuing the order.
+// 2. Names are only copied for entities that the ally has not named.
+// 3. Names that the ally is already using for another entity are not copied.
+func (st *State) ShareNames(issuedByID, polityID string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.ShareNames: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	ally := st.Polity(polityID)
+	if ally == nil || ally == issuedBy {
+		return fieldError("polity_id", fmt.Errorf("invalid polity %q: %w", polityID, ERRBADREQUEST))
+	} else if !issuedBy.isAlliedTo(ally) {
+		return fieldError("polity_id", fmt.Errorf("polity is not an ally: %w", ERRFORBIDDEN))
+	}
+	shared := issuedBy.names.share(ally.names)
+	issuedBy.logf("shared %d names with %s", shared, ally.name)
+	ally.logf("%s shared %d names", issuedBy.name, shared)
+	return nil
+}
@@ -18,20 +18,122 @@
 
 package engine
 
-import "github.com/mdhender/server/internal/obsolete/population"
+import (
+	"github.com/google/uuid"
+	"math"
+)
+
+// massPerTransport is the mass that one tech level of transport
+// can move in a turn.
+const massPerTransport = 100
+
+func mkship(polity *Polity, homePort *Colony) *Ship {
+	ship := &Ship{
+		id:     uuid.New().String(),
+		number: polity.nextShipNumber(),
+		polity: polity,
+		system: homePort.system,
+		ration: 1,
+	}
+	ship.setOrbit(homePort.orbitOf())
+	polity.addShip(ship)
+	homePort.addShip(ship)
+	return ship
+}
 
 type Ship struct {
 	id         string
 	polity     *Polity
 	number     string
-	system     *System
+	system     *System // nil when the ship is in deep space
+	orbit      *Orbit  // nil when the ship is at the jump point or in deep space
+	coords     Coords  // location of the ship when in deep space
+	offset     int     // tactical distance from the system or location in deep space
 	homePort   *Colony
 	name       string
 	note       Text
-	population population.Population
-	units      struct {
-		farms []FarmUnit
-	}
+	population Population
+	// people loaded as cargo; they ride in the cargo hold and the rest
+	// of the population is the ship's crew
+	passengers Population
+	units      []Unit
+	storage    storage
+	factories  []*FactoryGroup
+	batteries  batteries
+	transports transports
+	// volume units of the hull set aside for cargo
+	cargoHold int
+	// construction workers that are busy for the rest of the turn
+	constructionUsed int
 	// percent of a full food allotment to be dispersed each turn
 	ration float64
+	// in-system move that will take more than one turn to complete
+	moving *Move
+	// planets that the ship has permission to colonize this turn
+	colonize []*Planet
+}
+
+// shipPart returns true if the kind of unit can be installed in a ship.
+func shipPart(kind UnitKind) bool {
+	switch kind {
+	case ANTIMISSILE, ENERGYWEAPON, ENGINE, LIGHTSTRUCTURAL, MISSILE, POWER, STRUCTURAL, TRANSPORT:
+		return true
+	}
+	return false
+}
+
+// hullSpace returns the volume units enclosed by the assembled
+// structural and light structural units.
+func hullSpace(units []Unit) (space int) {
+	for _, u := range units {
+		switch u.Kind {
+		case STRUCTURAL:
+			space += u.Space(1)
+		case LIGHTSTRUCTURAL:
+			space += u.Space(5)
+		}
+	}
+	return space
+}
+
+// installedVolume returns the volume units of the hull taken up by
+// assembled units other than the hull itself.
+func installedVolume(units []Unit) (volume float64) {
+	for _, u := range units {
+		if u.Assembled && u.Kind != STRUCTURAL && u.Kind != LIGHTSTRUCTURAL {
+			volume += u.Volume()
+		}
+	}
+	return volume
+}
+
+// hull returns the volume units of the ship's hull that are not
+// taken up by installed parts or the cargo hold.
+func (s *Ship) hull() int {
+	free := hullSpace(s.units) - int(math.Ceil(installedVolume(s.units))) - s.cargoHold
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// speed returns the tactical units that the ship can move in a turn.
+func (s *Ship) speed() int {
+	thrust, _ := s.engines()
+	mass := s.mass()
+	if thrust == 0 || mass <= 0 {
+		return 0
+	}
+	return int(float64(thrust) * tacticalSpeedFactor / mass)
+}
+
+// transportCapacity returns the mass that the depot's assembled
+// transports can move in a turn.
+func (d depot) transportCapacity() (capacity int) {
+	for _, u := range *d.units {
+		if u.Kind == TRANSPORT && u.Assembled {
+			capacity += u.TechLevel * u.Quantity * massPerTransport
+		}
+	}
+	return capacity
 }
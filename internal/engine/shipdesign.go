@@ -0,0 +1,211 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"github.com/mdhender/server/pkg/utils"
+	"log"
+	"strings"
+)
+
+// ShipDesign is a named list of the parts that are assembled into a ship.
+type ShipDesign struct {
+	name      string
+	parts     []Unit // assembled parts installed in each ship
+	cargoHold int    // volume units of the hull set aside for cargo
+}
+
+// shipParts is the list of parts in a DesignShip order.
+type shipParts []struct {
+	Item      string `json:"item"`
+	TechLevel int    `json:"tech_level"`
+	Quantity  int    `json:"quantity"`
+}
+
+// ship returns a ship built from the design. It is not part of the
+// game and is used to derive the stats of the design.
+func (sd *ShipDesign) ship() *Ship {
+	s := &Ship{cargoHold: sd.cargoHold}
+	d := s.depot()
+	for _, u := range sd.parts {
+		d.addUnit(u)
+	}
+	return s
+}
+
+// design returns the polity's ship design with the given name.
+// Names are not case sensitive.
+func (p *Polity) design(name string) *ShipDesign {
+	return p.designs[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// DefineCargoHold sets aside part of a ship's hull for cargo.
+//
+// 1. Ship identified by ShipID must accept orders from the polity issuing the order.
+// 2. Quantity must not be less than zero.
+// 3. The hull must be able to enclose the cargo hold along with the
+// ship's working units.
+// 4. The cargo hold must be large enough for the cargo already on board.
+func (st *State) DefineCargoHold(issuedByID, shipID string, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.DefineCargoHold: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	ship := st.Ship(shipID)
+	if ship == nil {
+		return fieldError("ship_id", fmt.Errorf("invalid ship %q: %w", shipID, ERRBADREQUEST))
+	} else if !ship.acceptsOrdersFrom(issuedBy) {
+		return fieldError("ship_id", fmt.Errorf("ship refuses order: %w", ERRFORBIDDEN))
+	} else if quantity < 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	} else if free := ship.hull() + ship.cargoHold; quantity > free {
+		return fieldError("quantity", fmt.Errorf("hull has room for %s: %w", utils.Commas(free), ERRBADREQUEST))
+	} else if used := ship.depot().storageUsed(); float64(quantity) < used {
+		return fieldError("quantity", fmt.Errorf("cargo needs %.1f: %w", used, ERRBADREQUEST))
+	}
+	ship.cargoHold = quantity
+	ship.polity.logf("%s: cargo hold set to %s", shipID, utils.Commas(quantity))
+	return nil
+}
+
+// DesignShip saves a named ship design for the polity.
+//
+// 1. Name must not be blank. A design with the same name is replaced.
+// 2. Parts must be units that can be installed in a ship, with a quantity
+// greater than zero and a tech level the polity may assemble.
+// 3. The hull must enclose the working parts and the cargo hold. Ships
+// need structural or light structural units for a hull.
+func (st *State) DesignShip(issuedByID, name string, parts shipParts, cargoHold int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.DesignShip: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fieldError("name", fmt.Errorf("invalid name: %w", ERRBADREQUEST))
+	} else if cargoHold < 0 {
+		return fieldError("cargo_hold", fmt.Errorf("invalid cargo hold %d: %w", cargoHold, ERRBADREQUEST))
+	}
+	sd := &ShipDesign{name: name, cargoHold: cargoHold}
+	for i, part := range parts {
+		kind, ok := unitKindFromString(part.Item)
+		if !ok || !shipPart(kind) {
+			return fieldError(fmt.Sprintf("parts/%d/item", i), fmt.Errorf("invalid part %q: %w", part.Item, ERRBADREQUEST))
+		} else if part.TechLevel < 1 {
+			return fieldError(fmt.Sprintf("parts/%d/tech_level", i), fmt.Errorf("invalid tech level %d: %w", part.TechLevel, ERRBADREQUEST))
+		} else if part.Quantity <= 0 {
+			return fieldError(fmt.Sprintf("parts/%d/quantity", i), fmt.Errorf("invalid quantity %d: %w", part.Quantity, ERRBADREQUEST))
+		}
+		u := Unit{Kind: kind, TechLevel: part.TechLevel, Quantity: part.Quantity, Assembled: true}
+		if err := issuedBy.canAssemble(u); err != nil {
+			return fieldError(fmt.Sprintf("parts/%d/tech_level", i), err)
+		}
+		sd.parts = append(sd.parts, u)
+	}
+
+	ship := sd.ship()
+	if hullSpace(ship.units) == 0 {
+		return fieldError("parts", fmt.Errorf("design has no hull: %w", ERRBADREQUEST))
+	} else if installed := installedVolume(ship.units) + float64(cargoHold); installed > float64(hullSpace(ship.units)) {
+		return fieldError("parts", fmt.Errorf("parts and cargo hold need %.1f of %s hull: %w", installed, utils.Commas(hullSpace(ship.units)), ERRBADREQUEST))
+	}
+	issuedBy.designs[strings.ToLower(name)] = sd
+	issuedBy.logf("design %q: mass %.1f, speed %d, jump range %.1f, cargo hold %s, transport %s",
+		name, ship.mass(), ship.speed(), ship.jumpRange(), utils.Commas(cargoHold), utils.Commas(ship.depot().transportCapacity()))
+	return nil
+}
+
+// CommissionShip assembles ships at a colony from one of the polity's designs.
+//
+// 1. Colony identified by ColonyID must accept orders from the polity issuing the order.
+// 2. Design must be the name of one of the polity's ship designs.
+// 3. Quantity must be greater than zero.
+// 4. The parts are taken from the colony's storage as unassembled units.
+// 5. Assembling the parts takes the same labor and power as assembling
+// them as items.
+// 6. New ships are in the colony's orbit and have the colony as their home port.
+// 7. Quantity may exceed the parts, labor, or power available; the overage
+// is ignored and the order is partially executed. The order is rejected if
+// no ships can be commissioned.
+func (st *State) CommissionShip(issuedByID, colonyID, design string, quantity int) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.CommissionShip: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	colony := st.Colony(colonyID)
+	if colony == nil {
+		return fieldError("colony_id", fmt.Errorf("invalid colony %q: %w", colonyID, ERRBADREQUEST))
+	} else if !colony.acceptsOrdersFrom(issuedBy) {
+		return fieldError("colony_id", fmt.Errorf("colony refuses order: %w", ERRFORBIDDEN))
+	} else if quantity <= 0 {
+		return fieldError("quantity", fmt.Errorf("invalid quantity %d: %w", quantity, ERRBADREQUEST))
+	}
+	sd := issuedBy.design(design)
+	if sd == nil {
+		return fieldError("design", fmt.Errorf("invalid design %q: %w", design, ERRBADREQUEST))
+	}
+
+	d := colony.depot()
+	var commissioned int
+	for ; commissioned < quantity; commissioned++ {
+		if !d.canCommission(sd) {
+			break
+		}
+		ship := mkship(colony.polity, colony)
+		sd.commission(d, ship.depot())
+		ship.cargoHold = sd.cargoHold
+		st.ships[ship.id] = ship
+		colony.polity.logf("%s: commissioned %s %q", colonyID, ship.number, sd.name)
+	}
+	if commissioned == 0 {
+		return fmt.Errorf("not enough parts, labor, or power to commission %q: %w", sd.name, ERRBADREQUEST)
+	} else if commissioned < quantity {
+		colony.polity.logf("%s: commissioned %s of %s %q (not enough parts, labor, or power)", colonyID, utils.Commas(commissioned), utils.Commas(quantity), sd.name)
+		return fmt.Errorf("commissioned %d of %d: %w", commissioned, quantity, ERRPARTIAL)
+	}
+	return nil
+}
+
+// canCommission returns true if the depot has the parts, labor, and
+// power to assemble one ship of the design.
+func (d depot) canCommission(sd *ShipDesign) bool {
+	var workers, power int
+	for _, part := range sd.parts {
+		if d.countUnit(part.Kind, part.TechLevel, false) < part.Quantity {
+			return false
+		}
+		w, p := assemblyCost(part)
+		workers, power = workers+w, power+p
+	}
+	return workers <= d.constructors() && power <= d.power()
+}
+
+// commission moves the design's parts from the depot to the ship,
+// assembling them along the way.
+func (sd *ShipDesign) commission(from, to depot) {
+	for _, part := range sd.parts {
+		from.useAssembly(part)
+		from.removeUnit(part.Kind, part.TechLevel, false, part.Quantity)
+		to.addUnit(part)
+	}
+}
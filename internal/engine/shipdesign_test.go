@@ -0,0 +1,193 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+// scout returns the parts for a small ship: a hull of twenty tech level 1
+// structural units enclosing a single tech level 1 engine.
+func scout() shipParts {
+	return shipParts{
+		{Item: "SU", TechLevel: 1, Quantity: 20},
+		{Item: "ENGINE", TechLevel: 1, Quantity: 1},
+	}
+}
+
+func Test_DesignShip(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		design    string
+		parts     shipParts
+		cargoHold int
+		err       error
+	}{
+		{"scout", "Scout", scout(), 0, nil},
+		{"scout with cargo hold", "Scout", scout(), 10, nil},
+		{"cargo hold too large", "Scout", scout(), 11, ERRBADREQUEST},
+		{"negative cargo hold", "Scout", scout(), -1, ERRBADREQUEST},
+		{"blank name", " ", scout(), 0, ERRBADREQUEST},
+		{"no hull", "Engine", shipParts{{Item: "ENGINE", TechLevel: 1, Quantity: 1}}, 0, ERRBADREQUEST},
+		{"not a ship part", "Farm", shipParts{{Item: "SU", TechLevel: 1, Quantity: 20}, {Item: "FARM", TechLevel: 1, Quantity: 1}}, 0, ERRBADREQUEST},
+		{"zero quantity", "Scout", shipParts{{Item: "SU", TechLevel: 1, Quantity: 0}}, 0, ERRBADREQUEST},
+		{"zero tech level", "Scout", shipParts{{Item: "SU", TechLevel: 0, Quantity: 20}}, 0, ERRBADREQUEST},
+		{"tech level too high", "Scout", shipParts{{Item: "SU", TechLevel: 2, Quantity: 20}}, 0, ERRFORBIDDEN},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi := st.Polity("usagi")
+			err := st.DesignShip("usagi", tc.design, tc.parts, tc.cargoHold)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(usagi.design(tc.design) != nil, tc.err == nil)
+		})
+	}
+}
+
+func Test_DesignShipReplaces(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi := st.Polity("usagi")
+	is.NoErr(st.DesignShip("usagi", "Scout", scout(), 0))
+	is.NoErr(st.DesignShip("usagi", "SCOUT", scout(), 5))
+	is.Equal(len(usagi.designs), 1)              // names are not case sensitive
+	is.Equal(usagi.design("scout").cargoHold, 5) // the later design replaces the earlier one
+}
+
+func Test_DesignsReportInOrder(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	for _, name := range []string{"Zeta", "Alpha", "Mid", "Beta"} {
+		is.NoErr(st.DesignShip("usagi", name, scout(), 0))
+	}
+	for i := 0; i < 10; i++ {
+		report := st.String()
+		is.True(strings.Index(report, `(design (name "Alpha")`) < strings.Index(report, `(design (name "Beta")`)) // designs are sorted by name
+		is.True(strings.Index(report, `(design (name "Beta")`) < strings.Index(report, `(design (name "Mid")`))
+		is.True(strings.Index(report, `(design (name "Mid")`) < strings.Index(report, `(design (name "Zeta")`))
+	}
+}
+
+func Test_CommissionShip(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		design       string
+		kits         int // unassembled parts in storage, in complete ships
+		constructors int
+		power        int
+		quantity     int
+		err          error
+		commissioned int
+	}{
+		{"commissions", "scout", 2, 10, 10, 2, nil, 2},
+		{"limited by parts", "scout", 2, 10, 10, 3, ERRPARTIAL, 2},
+		{"limited by labor", "scout", 2, 3, 10, 2, ERRPARTIAL, 1},
+		{"limited by power", "scout", 2, 10, 5, 2, ERRPARTIAL, 1},
+		{"no parts", "scout", 0, 10, 10, 2, ERRBADREQUEST, 0},
+		{"zero quantity", "scout", 2, 10, 10, 0, ERRBADREQUEST, 0},
+		{"unknown design", "frigate", 2, 10, 10, 2, ERRBADREQUEST, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			c := st.Colony("tosa")
+			mktestcrew(c, tc.constructors, tc.power,
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 20 * tc.kits},
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: tc.kits})
+			is.NoErr(st.DesignShip("usagi", "Scout", scout(), 5))
+			ships := len(st.ships)
+			err := st.CommissionShip("usagi", "tosa", tc.design, tc.quantity)
+			is.True(errors.Is(err, tc.err))                                          // error
+			is.Equal(len(st.ships)-ships, tc.commissioned)                           // new ships
+			is.Equal(c.depot().countUnit(ENGINE, 1, false), tc.kits-tc.commissioned) // parts used
+			for _, s := range st.ships {
+				is.Equal(s.homePort, c)                           // home ported at the colony
+				is.Equal(s.orbit, c.orbitOf())                    // in the colony's orbit
+				is.Equal(s.cargoHold, 5)                          // with the design's cargo hold
+				is.Equal(s.depot().countUnit(ENGINE, 1, true), 1) // and working parts
+			}
+		})
+	}
+}
+
+func Test_DefineCargoHold(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		metal    int // cargo on board; two units of metal fill one volume unit
+		quantity int
+		err      error
+		hold     int
+	}{
+		{"sets hold", 0, 10, nil, 10},
+		{"clears hold", 0, 0, nil, 0},
+		{"hull too small", 0, 11, ERRBADREQUEST, 5},
+		{"negative", 0, -1, ERRBADREQUEST, 5},
+		{"cargo on board", 10, 5, nil, 5},
+		{"too small for cargo", 10, 4, ERRBADREQUEST, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1",
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 20, Assembled: true},
+				Unit{Kind: ENGINE, TechLevel: 1, Quantity: 1, Assembled: true})
+			s.cargoHold, s.storage.metal = 5, tc.metal
+			err := st.DefineCargoHold("usagi", "S1", tc.quantity)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(s.cargoHold, tc.hold)
+		})
+	}
+}
+
+func Test_CrewStaysOutOfTheHold(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		crew       int
+		passengers int // one hundred people fill one volume unit
+		quantity   int
+		err        error
+	}{
+		{"crew only", 1_000, 0, 0, nil},
+		{"room for passengers", 1_000, 500, 5, nil},
+		{"too small for passengers", 1_000, 500, 4, ERRBADREQUEST},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1",
+				Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 20, Assembled: true})
+			s.population = Population{unskilled: tc.crew + tc.passengers, total: tc.crew + tc.passengers}
+			s.passengers = Population{unskilled: tc.passengers, total: tc.passengers}
+			is.True(errors.Is(st.DefineCargoHold("usagi", "S1", tc.quantity), tc.err))
+		})
+	}
+}
+
+func Test_TransportCapacity(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	s := mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1",
+		Unit{Kind: TRANSPORT, TechLevel: 2, Quantity: 3, Assembled: true},
+		Unit{Kind: TRANSPORT, TechLevel: 1, Quantity: 5})
+	is.Equal(s.depot().transportCapacity(), 2*3*massPerTransport) // only assembled transports carry cargo
+}
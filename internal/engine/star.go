@@ -50,6 +50,7 @@ func mkstar(system *System) *Star {
 	default:
 		panic("assert(len(system.stars) < 8)")
 	}
+	system.stars = append(system.stars, star)
 	return star
 }
 
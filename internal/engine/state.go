@@ -33,8 +33,11 @@ type State struct {
 	planets  map[string]*Planet
 	colonies map[string]*Colony
 	ships    map[string]*Ship
+	mail     []*message // messages that have not been delivered
+	events   *eventLog  // events for the current turn
 
-	orders Orders
+	orders    Orders // orders for the turn, sorted by priority
+	submitted Orders // orders for the turn, in the order they were submitted
 }
 
 // NewState returns an initialized state with an administrator.
@@ -48,6 +51,10 @@ func NewState(admins ...string) (*State, error) {
 		planets:  cluster.planets,
 		colonies: cluster.colonies,
 		ships:    cluster.ships,
+		events:   mkeventLog(),
+	}
+	for _, p := range st.polities {
+		p.events = st.events
 	}
 
 	if len(admins) == 0 {
@@ -123,7 +130,6 @@ func (st *State) System(id string) *System {
 }
 
 type AutomationUnit struct{}
-type EngineUnit struct{}
 type FactoryUnit struct{}
 type FarmUnit struct {
 	techLevel int
@@ -142,14 +148,9 @@ type MineUnit struct {
 	quantity  int
 	resource  *Resource // resource being mined
 }
-type MissileUnit struct{}
-type AntiMissileUnit struct{}
 
 type RobotUnit struct{}
 
-type StructuralUnit struct{}
-type TransportUnit struct{}
-
 // hegemony defines the contract for accepting orders from either a ruler or a viceroy of the ruler.
 type hegemony interface {
 	// acceptsOrdersFrom returns true if the unit is controlled directly or indirectly by the ruler.
@@ -157,19 +158,21 @@ type hegemony interface {
 }
 
 // acceptsOrdersFrom implements the hegemony interface.
+// A colony controlled by a viceroy accepts orders from the viceroy's ruler.
 func (c *Colony) acceptsOrdersFrom(p *Polity) bool {
-	if c == nil {
+	if c == nil || c.polity == nil || p == nil {
 		return false
 	}
-	return c.polity == p
+	return c.polity == p || c.polity.isViceroyOf(p)
 }
 
 // acceptsOrdersFrom implements the hegemony interface.
+// A ship controlled by a viceroy accepts orders from the viceroy's ruler.
 func (s *Ship) acceptsOrdersFrom(p *Polity) bool {
-	if s == nil {
+	if s == nil || s.polity == nil || p == nil {
 		return false
 	}
-	return s.polity == p
+	return s.polity == p || s.polity.isViceroyOf(p)
 }
 
 func (st *State) assignColonyName(colony *Colony, name string) error {
@@ -179,7 +182,8 @@ func (st *State) assignColonyName(colony *Colony, name string) error {
 	if name = strings.TrimSpace(name); name == "" || len(name) > 50 {
 		return fmt.Errorf("invalid name %q: %w", name, ERRBADREQUEST)
 	}
-	return fmt.Errorf("State.assignColonyName: %w", ERRNOTIMPLEMENTED)
+	colony.name = name
+	return nil
 }
 
 func (st *State) assignColonyNote(colony *Colony, note Text) error {
@@ -193,14 +197,16 @@ func (st *State) assignColonyNote(colony *Colony, note Text) error {
 	return nil
 }
 
-func (st *State) assignPlanetName(planet *Planet, name string) error {
-	if planet == nil {
+func (st *State) assignPlanetName(polity *Polity, planet *Planet, name string) error {
+	if polity == nil {
+		return fmt.Errorf("missing polity: %w", ERRBADREQUEST)
+	} else if planet == nil {
 		return fmt.Errorf("missing planet: %w", ERRBADREQUEST)
 	}
 	if name = strings.TrimSpace(name); name == "" || len(name) > 50 {
 		return fmt.Errorf("invalid name %q: %w", name, ERRBADREQUEST)
 	}
-	return fmt.Errorf("State.assignPlanetName: %w", ERRNOTIMPLEMENTED)
+	return polity.names.assign(planet.id, name)
 }
 
 func (st *State) assignPolityName(polity *Polity, name string) error {
@@ -210,7 +216,13 @@ func (st *State) assignPolityName(polity *Polity, name string) error {
 	if name = strings.TrimSpace(name); name == "" || len(name) > 50 {
 		return fmt.Errorf("invalid name %q: %w", name, ERRBADREQUEST)
 	}
-	return fmt.Errorf("State.assignPolityName: %w", ERRNOTIMPLEMENTED)
+	for _, p := range st.polities {
+		if p != polity && strings.EqualFold(p.name, name) {
+			return fmt.Errorf("duplicate name %q: %w", name, ERRBADREQUEST)
+		}
+	}
+	polity.name = name
+	return nil
 }
 
 func (st *State) assignShipName(ship *Ship, name string) error {
@@ -220,7 +232,8 @@ func (st *State) assignShipName(ship *Ship, name string) error {
 	if name = strings.TrimSpace(name); name == "" || len(name) > 50 {
 		return fmt.Errorf("invalid name %q: %w", name, ERRBADREQUEST)
 	}
-	return fmt.Errorf("State.assignShipName: %w", ERRNOTIMPLEMENTED)
+	ship.name = name
+	return nil
 }
 
 func (st *State) assignShipNote(ship *Ship, note Text) error {
@@ -234,24 +247,28 @@ func (st *State) assignShipNote(ship *Ship, note Text) error {
 	return nil
 }
 
-func (st *State) assignStarName(star *Star, name string) error {
-	if star == nil {
+func (st *State) assignStarName(polity *Polity, star *Star, name string) error {
+	if polity == nil {
+		return fmt.Errorf("missing polity: %w", ERRBADREQUEST)
+	} else if star == nil {
 		return fmt.Errorf("missing star: %w", ERRBADREQUEST)
 	}
 	if name = strings.TrimSpace(name); name == "" || len(name) > 50 {
 		return fmt.Errorf("invalid name %q: %w", name, ERRBADREQUEST)
 	}
-	return fmt.Errorf("State.assignStarName: %w", ERRNOTIMPLEMENTED)
+	return polity.names.assign(star.id, name)
 }
 
-func (st *State) assignSystemName(system *System, name string) error {
-	if system == nil {
+func (st *State) assignSystemName(polity *Polity, system *System, name string) error {
+	if polity == nil {
+		return fmt.Errorf("missing polity: %w", ERRBADREQUEST)
+	} else if system == nil {
 		return fmt.Errorf("missing system: %w", ERRBADREQUEST)
 	}
 	if name = strings.TrimSpace(name); name == "" || len(name) > 50 {
 		return fmt.Errorf("invalid name %q: %w", name, ERRBADREQUEST)
 	}
-	return fmt.Errorf("State.assignSystemName: %w", ERRNOTIMPLEMENTED)
+	return polity.names.assign(system.id, name)
 }
 
 // isDuplicateID returns true if the id is already in a map.
@@ -280,8 +297,10 @@ func (st *State) permissionToColonize(planet *Planet, ship *Ship) error {
 	} else if ship == nil {
 		return fmt.Errorf("missing ship: %w", ERRBADREQUEST)
 	}
-
-	return fmt.Errorf("State.permissionToColonize: %w", ERRNOTIMPLEMENTED)
+	if !ship.mayColonize(planet) {
+		ship.colonize = append(ship.colonize, planet)
+	}
+	return nil
 }
 
 // setHomePort updates the home port of the given ship
@@ -308,9 +327,74 @@ func (st *State) transferColony(colony *Colony, from, to *Polity) error {
 	} else if colony.polity == to {
 		return nil // nothing to do
 	}
+	st.transferred(colony.id, colony.number, colony.polity, to)
+	if to == colony.previousPolity {
+		colony.previousPolity = nil // the colony is back with its former ruler
+	} else if colony.polity != nil {
+		colony.previousPolity = colony.polity
+	}
 	return to.xferColony(colony)
 }
 
+// checkOwnership returns an error for every colony or ship that is not
+// controlled by exactly one polity, the one it names as its controller,
+// and for every ship whose home port is not controlled by the ship's polity.
+func (st *State) checkOwnership() []error {
+	var errs []error
+	owners := make(map[string]int)
+	for _, p := range st.polities {
+		for id, c := range p.controls.colonies {
+			if owners[id]++; c.polity != p {
+				errs = append(errs, fmt.Errorf("colony %q: listed by %q, controlled by %q: %w", id, p.id, ownerID(c.polity), ERRBUG))
+			}
+		}
+		for id, s := range p.controls.ships {
+			if owners[id]++; s.polity != p {
+				errs = append(errs, fmt.Errorf("ship %q: listed by %q, controlled by %q: %w", id, p.id, ownerID(s.polity), ERRBUG))
+			}
+		}
+	}
+	for id, c := range st.colonies {
+		if c.polity != nil && owners[id] != 1 {
+			errs = append(errs, fmt.Errorf("colony %q: listed by %d polities: %w", id, owners[id], ERRBUG))
+		}
+		for sid, s := range c.controls.ships {
+			if s.homePort != c {
+				errs = append(errs, fmt.Errorf("colony %q: ship %q is home-ported elsewhere: %w", id, sid, ERRBUG))
+			}
+		}
+	}
+	for id, s := range st.ships {
+		if s.polity != nil && owners[id] != 1 {
+			errs = append(errs, fmt.Errorf("ship %q: listed by %d polities: %w", id, owners[id], ERRBUG))
+		}
+		if s.homePort != nil && s.homePort.polity != s.polity {
+			errs = append(errs, fmt.Errorf("ship %q: home port %q is controlled by another polity: %w", id, s.homePort.id, ERRBUG))
+		}
+	}
+	return errs
+}
+
+// ownerID returns the id of the polity for error messages, or "nobody"
+// if the asset has no controller.
+func ownerID(p *Polity) string {
+	if p == nil {
+		return "nobody"
+	}
+	return p.id
+}
+
+// transferred records the transfer of an asset for both polities.
+func (st *State) transferred(assetID, number string, from, to *Polity) {
+	entities := []string{assetID, to.id}
+	fromName := "independents"
+	if from != nil {
+		entities, fromName = append(entities, from.id), from.name
+		from.eventf(ASSETTRANSFERRED, entities, "%s: transferred to %s", number, to.name)
+	}
+	to.eventf(ASSETTRANSFERRED, entities, "%s: transferred from %s", number, fromName)
+}
+
 // transferPolity transfers control of a Polity to another Polity.
 // Can be used when a new player joins a game or when a player exits the game.
 //
@@ -348,5 +432,6 @@ func (st *State) transferShip(ship *Ship, from, to *Polity) error {
 	} else if ship.polity == to {
 		return nil // nothing to do
 	}
+	st.transferred(ship.id, ship.number, ship.polity, to)
 	return to.xferShip(ship)
 }
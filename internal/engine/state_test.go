@@ -20,6 +20,7 @@ package engine
 
 import (
 	"github.com/matryer/is"
+	"strings"
 	"testing"
 )
 
@@ -30,3 +31,97 @@ func Test_Make(t *testing.T) {
 	is.True(admin != "")
 	is.True(st.admins[admin])
 }
+
+// Make returns a state built from the default cluster along with the
+// id of its administrator.
+func Make() (*State, string) {
+	st, err := NewState("admin")
+	if err != nil {
+		panic(err)
+	}
+	return st, "admin"
+}
+
+// mkrival adds a second polity to the state.
+func mkrival(st *State, id string) *Polity {
+	p := polity()
+	p.id, p.name, p.events = id, id, st.events
+	st.polities[id] = p
+	return p
+}
+
+// mktestship adds a ship to the state, home-ported at the colony and
+// controlled by the polity, with the units assembled.
+func mktestship(st *State, p *Polity, homePort *Colony, id string, units ...Unit) *Ship {
+	s := mkship(p, homePort)
+	delete(p.controls.ships, s.id)
+	delete(homePort.controls.ships, s.id)
+	s.id = id
+	p.addShip(s)
+	homePort.addShip(s)
+	s.units = units
+	st.ships[s.id] = s
+	return s
+}
+
+func Test_CheckOwnership(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		corrupt func(st *State)
+		errs    int
+		text    string
+	}{
+		{"consistent", func(st *State) {}, 0, ""},
+		{"colony without a polity", func(st *State) {
+			st.Colony("tosa").polity = nil
+		}, 2, `controlled by "nobody"`},
+		{"ship without a polity", func(st *State) {
+			st.Ship("S1").polity = nil
+		}, 2, `controlled by "nobody"`},
+		{"listed twice", func(st *State) {
+			st.Polity("kuma").controls.ships["S1"] = st.Ship("S1")
+		}, 2, "listed by 2 polities"},
+		{"home port of another polity", func(st *State) {
+			st.Polity("usagi").delColony(st.Colony("tosa"))
+			st.Polity("kuma").addColony(st.Colony("tosa"))
+			st.Colony("tosa").polity = st.Polity("kuma")
+		}, 1, "home port"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			mkrival(st, "kuma")
+			mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1")
+			tc.corrupt(st)
+			errs := st.checkOwnership()
+			is.Equal(len(errs), tc.errs)
+			var found bool
+			for _, err := range errs {
+				found = found || strings.Contains(err.Error(), tc.text)
+			}
+			is.True(found || tc.errs == 0) // error message
+
+		})
+	}
+}
+
+// mktestcrew replaces the colony's people with the construction workers
+// given and adds tech level 1 power plants and the units given.
+func mktestcrew(c *Colony, constructors, power int, units ...Unit) {
+	c.population = Population{construction: constructors, total: constructors}
+	if power > 0 {
+		c.units = append(c.units, Unit{Kind: POWER, TechLevel: 1, Quantity: power, Assembled: true})
+	}
+	c.units = append(c.units, units...)
+}
+
+// mktestfreighter empties tosa of people, gives it room for one hundred
+// volume units of storage, and adds the ship S1 to tosa's orbit with a
+// cargo hold of ten volume units. Two units of metal fill one volume unit.
+func mktestfreighter(st *State) (tosa *Colony, s1 *Ship) {
+	tosa = st.Colony("tosa")
+	mktestcrew(tosa, 0, 0, Unit{Kind: STRUCTURAL, TechLevel: 1, Quantity: 100, Assembled: true})
+	s1 = mktestship(st, st.Polity("usagi"), tosa, "S1")
+	s1.cargoHold = 10
+	return tosa, s1
+}
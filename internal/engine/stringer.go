@@ -21,6 +21,7 @@ package engine
 import (
 	"fmt"
 	"github.com/mdhender/server/pkg/utils"
+	"sort"
 	"strings"
 )
 
@@ -34,6 +35,18 @@ func (st *State) String() string {
 		_, _ = fmt.Fprintf(w, "    (home (system %q)\n", polity.home.system.id)
 		_, _ = fmt.Fprintf(w, "          (planet %q)\n", polity.home.planet.id)
 		_, _ = fmt.Fprintf(w, "          (colony %q))\n", polity.home.colony.id)
+		if polity.viceroyOf != nil {
+			_, _ = fmt.Fprintf(w, "    (viceroy-of %q)\n", polity.viceroyOf.id)
+		}
+		var viceroys []string
+		for id := range polity.controls.polities {
+			viceroys = append(viceroys, id)
+		}
+		sort.Strings(viceroys)
+		for _, id := range viceroys {
+			colonies, ships := polity.controls.polities[id].delegated()
+			_, _ = fmt.Fprintf(w, "    (viceroy (id %q) (colonies %q) (ships %q))\n", id, colonies, ships)
+		}
 		for _, c := range polity.controls.colonies {
 			_, _ = fmt.Fprintf(w, "    (colony (id %q)\n", c.id)
 			_, _ = fmt.Fprintf(w, "      (hull-number %q)\n", c.number)
@@ -59,6 +72,87 @@ func (st *State) String() string {
 			//_,_=fmt.Fprintf(w, "      (%-13s %13s)))\n", "foodGoal", utils.Commas(c.foodStockpileGoal))
 			_, _ = fmt.Fprintf(w, "    ) ;; colony %s\n", c.id)
 		}
+		for _, s := range polity.controls.ships {
+			_, _ = fmt.Fprintf(w, "    (ship (id %q)\n", s.id)
+			_, _ = fmt.Fprintf(w, "      (hull-number %q)\n", s.number)
+			_, _ = fmt.Fprintf(w, "      (location    %q)\n", s.locationName())
+			if s.orbit != nil {
+				_, _ = fmt.Fprintf(w, "      (orbit       %q)\n", s.orbit.name)
+			}
+			_, _ = fmt.Fprintf(w, "      (offset      %d)\n", s.offset)
+			_, _ = fmt.Fprintf(w, "      (mass        %.1f)\n", s.mass())
+			_, _ = fmt.Fprintf(w, "      (cargo-hold  %d)\n", s.cargoHold)
+			_, _ = fmt.Fprintf(w, "      (speed       %d)\n", s.speed())
+			_, _ = fmt.Fprintf(w, "      (jump-range  %.1f)\n", s.jumpRange())
+			_, _ = fmt.Fprintf(w, "    ) ;; ship %s\n", s.id)
+		}
+		var designs []string
+		for name := range polity.designs {
+			designs = append(designs, name)
+		}
+		sort.Strings(designs)
+		for _, name := range designs {
+			sd := polity.designs[name]
+			_, _ = fmt.Fprintf(w, "    (design (name %q) (cargo-hold %d)\n", sd.name, sd.cargoHold)
+			for _, u := range sd.parts {
+				_, _ = fmt.Fprintf(w, "      %s\n", u.Sexpr())
+			}
+			_, _ = fmt.Fprintf(w, "    ) ;; design %s\n", sd.name)
+		}
+		_, _ = fmt.Fprintf(w, "    (research (points %s) (committed %s))\n", utils.Commas(polity.research.points), utils.Commas(polity.research.committed))
+		for kind := NOOP; kind <= TRANSPORT; kind++ {
+			if tl, ok := polity.techLevels[kind]; ok {
+				_, _ = fmt.Fprintf(w, "    (tech-level (kind %s) (tl %d))\n", kind, tl)
+			}
+		}
+		var others []string
+		for id := range st.polities {
+			if id != polity.id {
+				others = append(others, id)
+			}
+		}
+		sort.Strings(others)
+		if len(others) != 0 {
+			_, _ = fmt.Fprintf(w, "    (diplomacy\n")
+			for _, id := range others {
+				t := st.polities[id]
+				_, _ = fmt.Fprintf(w, "      (polity %q (stance %s) (their-stance %s) (standing %s))\n", id, polity.diplomaticStatus(t), t.diplomaticStatus(polity), polity.standing(t))
+			}
+			_, _ = fmt.Fprintf(w, "    ) ;; diplomacy\n")
+		}
+		if len(polity.names.names) != 0 {
+			var ids []string
+			for id := range polity.names.names {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			_, _ = fmt.Fprintf(w, "    (names\n")
+			for _, id := range ids {
+				_, _ = fmt.Fprintf(w, "      (name (id %q) %q)\n", id, polity.names.names[id])
+			}
+			_, _ = fmt.Fprintf(w, "    ) ;; names\n")
+		}
+		polity.intel.report(w, st.turn, polity.names)
+		for _, m := range polity.inbox {
+			if m.delivered == st.turn {
+				_, _ = fmt.Fprintf(w, "    (message (id %q) (from %q) (source %q) (target %q) (sent %d)\n", m.id, m.from.id, m.sourceID, m.targetID, m.sent)
+				_, _ = fmt.Fprintf(w, "      (text %q))\n", m.text)
+			}
+		}
+		if results, _ := st.OrderResults(polity.id); len(results) != 0 {
+			_, _ = fmt.Fprintf(w, "    (orders\n")
+			for _, result := range results {
+				_, _ = fmt.Fprintf(w, "      (order (id %q) (kind %q) (status %q) (reason %q) (pointer %q))\n", result.ID, result.Kind, result.Status, result.Reason, result.Pointer)
+			}
+			_, _ = fmt.Fprintf(w, "    ) ;; orders\n")
+		}
+		if journal := polity.journal(); len(journal) != 0 {
+			_, _ = fmt.Fprintf(w, "    (journal\n")
+			for _, line := range journal {
+				_, _ = fmt.Fprintf(w, "      %q\n", line)
+			}
+			_, _ = fmt.Fprintf(w, "    ) ;; journal\n")
+		}
 		_, _ = fmt.Fprintf(w, "  ) ;; polity %s\n", polity.id)
 	}
 	if len(st.systems) != 0 {
@@ -121,6 +215,11 @@ func (st *State) String() string {
 			//_, _ = fmt.Fprintf(w, "      (created-by  %q)\n", c.CreatedBy())
 			//_, _ = fmt.Fprintf(w, "      (owned-by    %q)\n", c.OwnedBy)
 			_, _ = fmt.Fprintf(w, "      (ration      %7s)\n", utils.Percentage(c.ration))
+			_, _ = fmt.Fprintf(w, "      (pay         %7s)\n", utils.Percentage(c.pay))
+			_, _ = fmt.Fprintf(w, "      (rebels      %7s)\n", utils.Percentage(c.rebelFraction()))
+			if c.polity == nil {
+				_, _ = fmt.Fprintf(w, "      (independent)\n")
+			}
 			_, _ = fmt.Fprintf(w, "      (batteries   (charged %s) (used %s))\n", utils.Commas(c.batteries.charged), utils.Commas(c.batteries.used))
 			if len(c.units) != 0 {
 				_, _ = fmt.Fprintf(w, "      (units\n")
@@ -129,6 +228,13 @@ func (st *State) String() string {
 				}
 				_, _ = fmt.Fprintf(w, "      ) ;; units\n")
 			}
+			if len(c.factories) != 0 {
+				_, _ = fmt.Fprintf(w, "      (factories\n")
+				for _, g := range c.factories {
+					_, _ = fmt.Fprintf(w, "        %s\n", g.Sexpr())
+				}
+				_, _ = fmt.Fprintf(w, "      ) ;; factories\n")
+			}
 			if c.storage.food != 0 || c.storage.fuel != 0 || c.storage.gold != 0 || c.storage.metal != 0 || c.storage.nonmetal != 0 {
 				_, _ = fmt.Fprintf(w, "      (storage\n")
 				if c.storage.food != 0 {
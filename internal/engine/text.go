@@ -72,6 +72,26 @@ func (t Text) Tainted() bool {
 	return !t.untainted
 }
 
+// Truncate returns the text cut to at most n runes.
+func (t Text) Truncate(n int) Text {
+	if utf8.RuneCountInString(t.text) <= n {
+		return t
+	}
+	return Text{
+		untainted: t.untainted,
+		text:      string([]rune(t.text)[:n]),
+	}
+}
+
+// Sanitize returns the text with problematic characters replaced.
+// The result is safe to display, so it is untainted.
+func (t Text) Sanitize() Text {
+	return Text{
+		untainted: true,
+		text:      sanitize(t.text),
+	}
+}
+
 func (t Text) Untaint() Text {
 	return Text{
 		untainted: true,
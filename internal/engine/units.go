@@ -65,8 +65,16 @@ func (u Unit) Mass() float64 {
 
 	var massPerUnit float64
 	switch u.Kind {
+	case ANTIMISSILE:
+		massPerUnit = techLevel + 3
 	case CONSUMERGOOD:
 		massPerUnit = 0.6
+	case ENERGYWEAPON:
+		massPerUnit = (2 * techLevel) + 6
+	case ENGINE:
+		massPerUnit = (2 * techLevel) + 8
+	case FACTORY:
+		massPerUnit = (2 * techLevel) + 12
 	case FARM:
 		massPerUnit = (2 * techLevel) + 6
 	case FOOD:
@@ -75,12 +83,16 @@ func (u Unit) Mass() float64 {
 		massPerUnit = 1
 	case GOLD:
 		massPerUnit = 0.6
+	case LIGHTSTRUCTURAL:
+		massPerUnit = 0.5
 	case METAL:
 		massPerUnit = 1
 	case MINE:
 		massPerUnit = (2 * techLevel) + 10
 	case MINEGROUP:
 		massPerUnit = 0
+	case MISSILE:
+		massPerUnit = techLevel + 4
 	case NONMETAL:
 		massPerUnit = 1
 	case NOOP:
@@ -89,6 +101,10 @@ func (u Unit) Mass() float64 {
 		massPerUnit = 1
 	case POWER:
 		massPerUnit = (2 * techLevel) + 10
+	case STRUCTURAL:
+		massPerUnit = 5
+	case TRANSPORT:
+		massPerUnit = (2 * techLevel) + 4
 	default:
 		panic(fmt.Sprintf("assert(kind != %d)", u.Kind))
 	}
@@ -100,8 +116,16 @@ func (u Unit) Mass() float64 {
 func (u Unit) Materials() (metals, nonMetals float64) {
 	techLevel := float64(u.TechLevel)
 	switch u.Kind {
+	case ANTIMISSILE:
+		return 2 + techLevel, 2 + techLevel
 	case CONSUMERGOOD:
 		return 0.2, 0.4
+	case ENERGYWEAPON:
+		return 4 + techLevel, 2 + techLevel
+	case ENGINE:
+		return 6 + techLevel, 2 + techLevel
+	case FACTORY:
+		return 8 + techLevel, 4 + techLevel
 	case FARM:
 		return 4 + techLevel, 2 + techLevel
 	case FOOD:
@@ -110,12 +134,16 @@ func (u Unit) Materials() (metals, nonMetals float64) {
 		return 0, 0
 	case GOLD:
 		return 0, 0
+	case LIGHTSTRUCTURAL:
+		return 0.3, 0.2
 	case METAL:
 		return 0, 0
 	case MINE:
 		return 5 + techLevel, 5 + techLevel
 	case MINEGROUP:
 		return 0, 0
+	case MISSILE:
+		return 3 + techLevel, 2 + techLevel
 	case NONMETAL:
 		return 0, 0
 	case NOOP:
@@ -124,6 +152,10 @@ func (u Unit) Materials() (metals, nonMetals float64) {
 		return 0, 0
 	case POWER:
 		return 5 + techLevel, 5 + techLevel
+	case STRUCTURAL:
+		return 3, 2
+	case TRANSPORT:
+		return 3 + techLevel, 1 + techLevel
 	}
 	panic(fmt.Sprintf("assert(kind != %d)", u.Kind))
 }
@@ -153,6 +185,8 @@ func (u Unit) Sexpr() string {
 	switch u.Kind {
 	case CONSUMERGOOD:
 		return fmt.Sprintf("(goods %s)", utils.Commas(u.Quantity))
+	case FACTORY:
+		return fmt.Sprintf("(factory (tl %d) (qty %s))", u.TechLevel, utils.Commas(u.Quantity))
 	case FARM:
 		return fmt.Sprintf("(farm (tl %d) (qty %s))", u.TechLevel, utils.Commas(u.Quantity))
 	case FOOD:
@@ -220,8 +254,28 @@ func (u Unit) Volume() float64 {
 
 	var containersPerUnit float64
 	switch u.Kind {
+	case ANTIMISSILE:
+		containersPerUnit = techLevel + 2
+		if u.Assembled {
+			containersPerUnit *= 2
+		}
 	case CONSUMERGOOD:
 		containersPerUnit = 0.3
+	case ENERGYWEAPON:
+		containersPerUnit = techLevel + 3
+		if u.Assembled {
+			containersPerUnit *= 2
+		}
+	case ENGINE:
+		containersPerUnit = techLevel + 4
+		if u.Assembled {
+			containersPerUnit *= 2
+		}
+	case FACTORY:
+		containersPerUnit = techLevel + 6
+		if u.Assembled {
+			containersPerUnit *= 2
+		}
 	case FARM:
 		containersPerUnit = techLevel + 3
 		if u.Assembled {
@@ -233,6 +287,8 @@ func (u Unit) Volume() float64 {
 		containersPerUnit = 0.5
 	case GOLD:
 		containersPerUnit = 0.3
+	case LIGHTSTRUCTURAL:
+		containersPerUnit = 0.05
 	case METAL:
 		containersPerUnit = 0.5
 	case MINE:
@@ -242,6 +298,11 @@ func (u Unit) Volume() float64 {
 		}
 	case MINEGROUP:
 		containersPerUnit = 0
+	case MISSILE:
+		containersPerUnit = techLevel + 2
+		if u.Assembled {
+			containersPerUnit *= 2
+		}
 	case NONMETAL:
 		containersPerUnit = 0.5
 	case NOOP:
@@ -253,6 +314,13 @@ func (u Unit) Volume() float64 {
 		if u.Assembled {
 			containersPerUnit *= 2
 		}
+	case STRUCTURAL:
+		containersPerUnit = 0.5
+	case TRANSPORT:
+		containersPerUnit = techLevel + 2
+		if u.Assembled {
+			containersPerUnit *= 2
+		}
 	default:
 		panic(fmt.Sprintf("assert(kind != %d)", u.Kind))
 	}
@@ -0,0 +1,60 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_WeaponUnits(t *testing.T) {
+	for _, tc := range []struct {
+		kind      UnitKind
+		name      string
+		techLevel int
+		mass      float64
+		volume    float64 // unassembled; assembled units take twice the space
+		metals    float64
+		nonMetals float64
+	}{
+		{ANTIMISSILE, "ANTIMISSILE", 1, 4, 3, 3, 3},
+		{ANTIMISSILE, "ANTIMISSILE", 3, 6, 5, 5, 5},
+		{ENERGYWEAPON, "ENERGYWEAPON", 1, 8, 4, 5, 3},
+		{ENERGYWEAPON, "ENERGYWEAPON", 3, 12, 6, 7, 5},
+		{MISSILE, "MISSILE", 1, 5, 3, 4, 3},
+		{MISSILE, "MISSILE", 3, 7, 5, 6, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			kind, ok := unitKindFromString(tc.name)
+			is.True(ok)
+			is.Equal(kind, tc.kind)
+			is.True(assemblable(tc.kind))
+			is.True(researchable(tc.kind))
+			u := Unit{Kind: tc.kind, TechLevel: tc.techLevel, Quantity: 1}
+			metals, nonMetals := u.Materials()
+			is.Equal(u.Mass(), tc.mass)
+			is.Equal(u.Volume(), tc.volume)
+			is.Equal(metals, tc.metals)
+			is.Equal(nonMetals, tc.nonMetals)
+			u.Assembled = true
+			is.Equal(u.Volume(), 2*tc.volume)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import "fmt"
+
+// Verify checks the links between the entities in the state and the
+// values that must never go negative. It returns an error, wrapping
+// ERRBUG, for every broken invariant it finds.
+func (st *State) Verify() []error {
+	var errs []error
+	bug := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), ERRBUG))
+	}
+
+	// ids must be unique across all of the maps and match their keys
+	seen := make(map[string]string)
+	unique := func(kind, key, id string) {
+		if key != id {
+			bug("%s %q: listed under %q", kind, id, key)
+		}
+		if other, ok := seen[id]; ok {
+			bug("%s %q: id is also used by a %s", kind, id, other)
+		}
+		seen[id] = kind
+	}
+	for key, p := range st.polities {
+		unique("polity", key, p.id)
+	}
+	for key, s := range st.systems {
+		unique("system", key, s.id)
+	}
+	for key, s := range st.stars {
+		unique("star", key, s.id)
+	}
+	for key, p := range st.planets {
+		unique("planet", key, p.id)
+	}
+	for key, c := range st.colonies {
+		unique("colony", key, c.id)
+	}
+	for key, s := range st.ships {
+		unique("ship", key, s.id)
+	}
+
+	for id, p := range st.polities {
+		if p.controls.colonies == nil || p.controls.polities == nil || p.controls.ships == nil {
+			bug("polity %q: missing controls", id)
+		}
+		if p.designs == nil || p.diplomacy == nil || p.intel == nil || p.names == nil || p.research.progress == nil || p.techLevels == nil {
+			bug("polity %q: missing tables", id)
+		}
+		if r := p.viceroyOf; r != nil {
+			if r.viceroyOf != nil {
+				bug("polity %q: viceroy of a viceroy", id)
+			} else if r.controls.polities[id] != p {
+				bug("polity %q: not listed as a viceroy of %q", id, r.id)
+			}
+		}
+		for vid, v := range p.controls.polities {
+			if v.viceroyOf != p {
+				bug("polity %q: lists %q as a viceroy", id, vid)
+			}
+		}
+	}
+
+	errs = append(errs, st.checkOwnership()...)
+
+	for id, c := range st.colonies {
+		if c.planet != nil {
+			if !containsColony(c.planet.colonies, c) {
+				bug("colony %q: not listed by planet %q", id, c.planet.id)
+			}
+		} else if c.orbit == nil {
+			bug("colony %q: not on a planet or in an orbit", id)
+		} else if !containsColony(c.orbit.colonies, c) {
+			bug("colony %q: not listed by orbit %q", id, c.orbit.id)
+		}
+		errs = append(errs, verifyDepot("colony", id, c.depot())...)
+	}
+	for id, p := range st.planets {
+		for _, c := range p.colonies {
+			if c.planet != p {
+				bug("planet %q: lists colony %q", id, c.id)
+			}
+		}
+	}
+
+	for id, s := range st.ships {
+		if s.orbit != nil {
+			if !containsShip(s.orbit.ships, s) {
+				bug("ship %q: not listed by orbit %q", id, s.orbit.id)
+			} else if s.system != s.orbit.system {
+				bug("ship %q: in orbit %q but not its system", id, s.orbit.id)
+			}
+		}
+		if s.homePort != nil && s.homePort.controls.ships[id] != s {
+			bug("ship %q: not listed by home port %q", id, s.homePort.id)
+		}
+		errs = append(errs, verifyDepot("ship", id, s.depot())...)
+	}
+	for _, star := range st.stars {
+		for _, o := range star.orbits {
+			if o == nil {
+				continue
+			}
+			for _, s := range o.ships {
+				if s.orbit != o {
+					bug("orbit %q: lists ship %q", o.id, s.id)
+				}
+			}
+			for _, c := range o.colonies {
+				if c.orbit != o {
+					bug("orbit %q: lists colony %q", o.id, c.id)
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// verifyDepot checks that the storage, population, and units of a
+// colony or ship are not negative and that the population adds up.
+// People in training at a colony are part of its total.
+func verifyDepot(kind, id string, d depot) []error {
+	var errs []error
+	bug := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s %q: %s: %w", kind, id, fmt.Sprintf(format, args...), ERRBUG))
+	}
+	s := d.storage
+	if s.food < 0 || s.fuel < 0 || s.gold < 0 || s.metal < 0 || s.nonmetal < 0 {
+		bug("negative storage %+v", *s)
+	}
+	p := d.population
+	if p.construction < 0 || p.professionals < 0 || p.soldiers < 0 || p.spies < 0 || p.trainees < 0 || p.unskilled < 0 || p.others < 0 {
+		bug("negative population %+v", *p)
+	} else if sum := p.construction + p.professionals + p.soldiers + p.spies + p.trainees + p.unskilled + p.others + d.inTraining(); sum != p.total {
+		bug("population total %d does not match its parts %d", p.total, sum)
+	}
+	for _, u := range *d.units {
+		if u.Quantity < 0 {
+			bug("negative quantity of %s", u.Sexpr())
+		}
+	}
+	return errs
+}
+
+func containsColony(colonies []*Colony, c *Colony) bool {
+	for _, other := range colonies {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
+
+func containsShip(ships []*Ship, s *Ship) bool {
+	for _, other := range ships {
+		if other == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func Test_Verify(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		corrupt func(st *State)
+		text    string // empty if the state is consistent
+	}{
+		{"consistent", func(st *State) {}, ""},
+		{"people in training", func(st *State) {
+			c := st.Colony("tosa")
+			c.population = Population{unskilled: 90, total: 100}
+			c.training = []*training{{kind: SOLDIERS, quantity: 10, turns: 1}}
+		}, ""},
+		{"key does not match id", func(st *State) {
+			st.colonies["other"] = st.Colony("tosa")
+		}, `listed under "other"`},
+		{"id used twice", func(st *State) {
+			st.Ship("S1").id = "tosa"
+			st.ships["tosa"] = st.Ship("S1")
+			delete(st.ships, "S1")
+		}, "id is also used by a"},
+		{"missing tables", func(st *State) {
+			st.Polity("usagi").intel = nil
+		}, "missing tables"},
+		{"viceroy not listed", func(st *State) {
+			st.Polity("kuma").viceroyOf = st.Polity("usagi")
+		}, "not listed as a viceroy"},
+		{"negative storage", func(st *State) {
+			st.Colony("tosa").storage.fuel = -1
+		}, "negative storage"},
+		{"negative population", func(st *State) {
+			st.Ship("S1").population.spies = -1
+		}, "negative population"},
+		{"population does not add up", func(st *State) {
+			st.Colony("tosa").population.total++
+		}, "does not match its parts"},
+		{"negative units", func(st *State) {
+			st.Ship("S1").units = []Unit{{Kind: MISSILE, TechLevel: 1, Quantity: -1}}
+		}, "negative quantity"},
+		{"ship not in its orbit", func(st *State) {
+			st.Ship("S1").orbit.ships = nil
+		}, "not listed by orbit"},
+		{"not listed by home port", func(st *State) {
+			delete(st.Colony("tosa").controls.ships, "S1")
+		}, "not listed by home port"},
+		{"colony nowhere", func(st *State) {
+			st.Colony("tosa").orbit = nil
+		}, "not on a planet or in an orbit"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			mkrival(st, "kuma")
+			mktestship(st, st.Polity("usagi"), st.Colony("tosa"), "S1")
+			tc.corrupt(st)
+			errs := st.Verify()
+			if tc.text == "" {
+				is.Equal(len(errs), 0) // consistent
+				return
+			}
+			var found bool
+			for _, err := range errs {
+				is.True(errors.Is(err, ERRBUG)) // broken invariants are bugs
+				found = found || strings.Contains(err.Error(), tc.text)
+			}
+			is.True(found) // error message
+		})
+	}
+}
+
+func Test_VerifyAfterStage(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	st.Colony("tosa").storage.fuel = -1
+	var found bool
+	for _, err := range st.ExecuteOrders(nil, true) {
+		if errors.Is(err, ERRBUG) && strings.HasPrefix(err.Error(), "gameDataCleanup: ") {
+			found = true // reported against the first stage
+		}
+	}
+	is.True(found)
+
+	st, _ = Make()
+	st.Colony("tosa").storage.fuel = -1
+	for _, err := range st.ExecuteOrders(nil, false) {
+		is.True(!errors.Is(err, ERRBUG)) // only checked in debug mode
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// AppointViceroy order makes another polity a viceroy of the polity issuing the order.
+type AppointViceroy struct {
+	PolityID string `json:"polity_id"` // id of polity being appointed
+}
+
+// DismissViceroy order ends a viceroy's appointment.
+type DismissViceroy struct {
+	PolityID string `json:"polity_id"` // id of viceroy being dismissed
+}
+
+// AppointViceroy makes a polity a viceroy of the polity issuing the order.
+// Assets given to the viceroy continue to accept orders from the ruler.
+//
+// 1. Polity identified by PolityID must not be the polity issuing the order.
+// 2. The polity issuing the order must not be a viceroy.
+// 3. The appointee must not be a viceroy and must not have viceroys of its own.
+// 4. The appointee must regard the polity issuing the order as an ally.
+func (st *State) AppointViceroy(issuedByID, polityID string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.AppointViceroy: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	viceroy := st.Polity(polityID)
+	if viceroy == nil || viceroy == issuedBy {
+		return fieldError("polity_id", fmt.Errorf("invalid polity %q: %w", polityID, ERRBADREQUEST))
+	} else if issuedBy.viceroyOf != nil {
+		return fieldError("polity_id", fmt.Errorf("viceroys may not appoint viceroys: %w", ERRFORBIDDEN))
+	} else if viceroy.viceroyOf != nil {
+		return fieldError("polity_id", fmt.Errorf("polity is already a viceroy: %w", ERRFORBIDDEN))
+	} else if len(viceroy.controls.polities) != 0 {
+		return fieldError("polity_id", fmt.Errorf("polity has viceroys of its own: %w", ERRFORBIDDEN))
+	} else if viceroy.diplomaticStatus(issuedBy) != ALLY {
+		return fieldError("polity_id", fmt.Errorf("polity refuses appointment: %w", ERRFORBIDDEN))
+	}
+	viceroy.viceroyOf = issuedBy
+	viceroy.entrusted = make(map[string]bool)
+	issuedBy.controls.polities[viceroy.id] = viceroy
+	issuedBy.logf("appointed %s as viceroy", viceroy.name)
+	st.notify(issuedBy, viceroy, fmt.Sprintf("%s has appointed you as viceroy.", issuedBy.name))
+	return nil
+}
+
+// DismissViceroy ends a viceroy's appointment and returns the colonies
+// and ships that the ruler gave the viceroy during the appointment.
+//
+// 1. Polity identified by PolityID must be a viceroy of the polity issuing the order.
+// 2. Assets that the viceroy controlled before the appointment, or gained
+// some other way, stay with the viceroy.
+func (st *State) DismissViceroy(issuedByID, polityID string) error {
+	issuedBy := st.Polity(issuedByID)
+	if issuedBy == nil {
+		log.Printf("[bug] State.DismissViceroy: issuedByID is invalid\n")
+		return ERRBUG
+	}
+	viceroy := st.Polity(polityID)
+	if viceroy == nil {
+		return fieldError("polity_id", fmt.Errorf("invalid polity %q: %w", polityID, ERRBADREQUEST))
+	} else if !viceroy.isViceroyOf(issuedBy) {
+		return fieldError("polity_id", fmt.Errorf("polity is not a viceroy: %w", ERRFORBIDDEN))
+	}
+	colonies, ships := viceroy.delegated()
+	for _, id := range colonies {
+		if err := st.transferColony(viceroy.controls.colonies[id], viceroy, issuedBy); err != nil {
+			return err
+		}
+	}
+	for _, id := range ships {
+		if s := viceroy.controls.ships[id]; s != nil {
+			if err := st.transferShip(s, viceroy, issuedBy); err != nil {
+				return err
+			}
+		}
+	}
+	viceroy.viceroyOf, viceroy.entrusted = nil, make(map[string]bool)
+	delete(issuedBy.controls.polities, viceroy.id)
+	issuedBy.logf("dismissed %s as viceroy", viceroy.name)
+	st.notify(issuedBy, viceroy, fmt.Sprintf("%s has dismissed you as viceroy.", issuedBy.name))
+	return nil
+}
+
+// delegated returns the ids of the colonies and ships that the viceroy
+// controls on behalf of its ruler.
+func (p *Polity) delegated() (colonies, ships []string) {
+	for id := range p.controls.colonies {
+		if p.entrusted[id] {
+			colonies = append(colonies, id)
+		}
+	}
+	for id := range p.controls.ships {
+		if p.entrusted[id] {
+			ships = append(ships, id)
+		}
+	}
+	sort.Strings(colonies)
+	sort.Strings(ships)
+	return colonies, ships
+}
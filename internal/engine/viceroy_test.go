@@ -0,0 +1,154 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_AppointViceroy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		polity  string
+		stance  DiplomaticStatus // kuma toward usagi
+		setup   func(st *State, usagi, kuma *Polity)
+		err     error
+		viceroy bool
+	}{
+		{"appoints", "kuma", ALLY, nil, nil, true},
+		{"refuses", "kuma", FRIEND, nil, ERRFORBIDDEN, false},
+		{"self", "usagi", ALLY, nil, ERRBADREQUEST, false},
+		{"unknown polity", "tora", ALLY, nil, ERRBADREQUEST, false},
+		{"already a viceroy", "kuma", ALLY, func(st *State, usagi, kuma *Polity) {
+			kuma.viceroyOf = mkrival(st, "tora")
+		}, ERRFORBIDDEN, false},
+		{"has viceroys", "kuma", ALLY, func(st *State, usagi, kuma *Polity) {
+			kuma.controls.polities["tora"] = mkrival(st, "tora")
+		}, ERRFORBIDDEN, false},
+		{"viceroy appointing", "kuma", ALLY, func(st *State, usagi, kuma *Polity) {
+			usagi.viceroyOf = mkrival(st, "tora")
+		}, ERRFORBIDDEN, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			kuma.diplomacy["usagi"] = tc.stance
+			if tc.setup != nil {
+				tc.setup(st, usagi, kuma)
+			}
+			err := st.AppointViceroy("usagi", tc.polity)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(kuma.isViceroyOf(usagi), tc.viceroy)
+			_, ok := usagi.controls.polities["kuma"]
+			is.Equal(ok, tc.viceroy)
+			if tc.viceroy {
+				is.Equal(len(st.mail), 1) // the viceroy is told
+			}
+		})
+	}
+	is := is.New(t)
+	st, _ := Make()
+	is.Equal(st.AppointViceroy("nobody", "kuma"), ERRBUG)
+}
+
+func Test_ViceroyCommands(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	kuma.diplomacy["usagi"] = ALLY
+	is.NoErr(st.AppointViceroy("usagi", "kuma"))
+	tosa := st.Colony("tosa")
+	s := mktestship(st, usagi, tosa, "S1")
+	is.NoErr(st.transferColony(tosa, usagi, kuma))
+	is.NoErr(st.transferShip(s, usagi, kuma))
+	is.True(tosa.acceptsOrdersFrom(kuma))  // the viceroy commands delegated assets
+	is.True(tosa.acceptsOrdersFrom(usagi)) // and so does the ruler
+	is.True(s.acceptsOrdersFrom(usagi))
+	is.True(!st.Colony("sanuki").acceptsOrdersFrom(kuma)) // but not the ruler's own
+	colonies, ships := kuma.delegated()
+	is.Equal(colonies, []string{"tosa"})
+	is.Equal(ships, []string{"S1"})
+}
+
+func Test_DismissViceroy(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		issuedBy  string
+		polity    string
+		err       error
+		dismissed bool
+	}{
+		{"dismisses", "usagi", "kuma", nil, true},
+		{"not a viceroy", "usagi", "tora", ERRFORBIDDEN, false},
+		{"not the ruler", "tora", "kuma", ERRFORBIDDEN, false},
+		{"unknown polity", "usagi", "nobody", ERRBADREQUEST, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			st, _ := Make()
+			usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+			kuma.diplomacy["usagi"] = ALLY
+			mkrival(st, "tora")
+			is.NoErr(st.AppointViceroy("usagi", "kuma"))
+			tosa := st.Colony("tosa")
+			s := mktestship(st, usagi, tosa, "S1")
+			is.NoErr(st.transferColony(tosa, usagi, kuma))
+			is.NoErr(st.transferShip(s, usagi, kuma))
+			err := st.DismissViceroy(tc.issuedBy, tc.polity)
+			is.True(errors.Is(err, tc.err)) // error
+			is.Equal(!kuma.isViceroyOf(usagi), tc.dismissed)
+			if tc.dismissed {
+				is.Equal(tosa.polity, usagi) // assets return to the ruler
+				is.Equal(s.polity, usagi)
+				is.Equal(len(kuma.controls.colonies), 0)
+				is.Equal(len(kuma.controls.ships), 0)
+			} else {
+				is.Equal(tosa.polity, kuma)
+			}
+			is.Equal(len(st.checkOwnership()), 0)
+		})
+	}
+}
+
+func Test_DismissViceroyKeepsOwnAssets(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	kuma.diplomacy["usagi"] = ALLY
+	tosa, sanuki := st.Colony("tosa"), st.Colony("sanuki")
+	home := mktestcolony(st, kuma, tosa.orbitOf(), "kumamoto")
+	k1 := mktestship(st, kuma, home, "K1")
+	s1 := mktestship(st, usagi, sanuki, "S1")
+	is.NoErr(st.transferShip(s1, usagi, kuma)) // given before the appointment
+	is.NoErr(st.AppointViceroy("usagi", "kuma"))
+	is.NoErr(st.transferColony(tosa, usagi, kuma))
+	colonies, ships := kuma.delegated()
+	is.Equal(colonies, []string{"tosa"}) // only what the ruler gave during the appointment
+	is.Equal(len(ships), 0)
+
+	is.NoErr(st.DismissViceroy("usagi", "kuma"))
+	is.Equal(tosa.polity, usagi) // entrusted assets return to the ruler
+	is.Equal(home.polity, kuma)  // the viceroy keeps its own
+	is.Equal(k1.polity, kuma)
+	is.Equal(s1.polity, kuma)
+	is.Equal(len(st.checkOwnership()), 0)
+}
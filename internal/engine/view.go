@@ -0,0 +1,129 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PolityView is what a polity can see of itself.
+type PolityView struct {
+	ID           string
+	Name         string
+	HomeColonyID string
+	ViceroyOf    string   // id of the polity this polity is a viceroy to, if any
+	ColonyIDs    []string // colonies controlled by the polity, sorted by id
+	ShipIDs      []string // ships controlled by the polity, sorted by id
+}
+
+// ColonyView is what a polity can see of one of its colonies.
+type ColonyView struct {
+	ID         string
+	Name       string
+	Kind       string
+	PolityID   string
+	SystemID   string
+	OrbitID    string
+	PlanetID   string // empty if the colony is not on a planet
+	Population int
+}
+
+// ShipView is what a polity can see of one of its ships.
+type ShipView struct {
+	ID         string
+	Name       string
+	PolityID   string
+	HomePortID string
+	SystemID   string // empty if the ship is in deep space
+	OrbitID    string // empty if the ship is at the jump point or in deep space
+	Population int
+}
+
+// PolityView returns the view of a polity.
+func (st *State) PolityView(polityID string) (PolityView, error) {
+	p := st.Polity(polityID)
+	if p == nil {
+		return PolityView{}, fmt.Errorf("invalid polity %q: %w", polityID, ERRNOTFOUND)
+	}
+	v := PolityView{ID: p.id, Name: p.name, ColonyIDs: []string{}, ShipIDs: []string{}}
+	if p.home.colony != nil {
+		v.HomeColonyID = p.home.colony.id
+	}
+	if p.viceroyOf != nil {
+		v.ViceroyOf = p.viceroyOf.id
+	}
+	for id := range p.controls.colonies {
+		v.ColonyIDs = append(v.ColonyIDs, id)
+	}
+	sort.Strings(v.ColonyIDs)
+	for id := range p.controls.ships {
+		v.ShipIDs = append(v.ShipIDs, id)
+	}
+	sort.Strings(v.ShipIDs)
+	return v, nil
+}
+
+// ColonyViews returns the views of the colonies that a polity controls, sorted by id.
+func (st *State) ColonyViews(polityID string) ([]ColonyView, error) {
+	pv, err := st.PolityView(polityID)
+	if err != nil {
+		return nil, err
+	}
+	views := []ColonyView{}
+	for _, id := range pv.ColonyIDs {
+		c := st.Colony(id)
+		v := ColonyView{ID: c.id, Name: c.name, Kind: c.kind.String(), PolityID: polityID, Population: c.population.total}
+		if c.system != nil {
+			v.SystemID = c.system.id
+		}
+		if o := c.orbitOf(); o != nil {
+			v.OrbitID = o.id
+		}
+		if c.planet != nil {
+			v.PlanetID = c.planet.id
+		}
+		views = append(views, v)
+	}
+	return views, nil
+}
+
+// ShipViews returns the views of the ships that a polity controls, sorted by id.
+func (st *State) ShipViews(polityID string) ([]ShipView, error) {
+	pv, err := st.PolityView(polityID)
+	if err != nil {
+		return nil, err
+	}
+	views := []ShipView{}
+	for _, id := range pv.ShipIDs {
+		s := st.Ship(id)
+		v := ShipView{ID: s.id, Name: s.name, PolityID: polityID, Population: s.population.total}
+		if s.homePort != nil {
+			v.HomePortID = s.homePort.id
+		}
+		if s.system != nil {
+			v.SystemID = s.system.id
+		}
+		if s.orbit != nil {
+			v.OrbitID = s.orbit.id
+		}
+		views = append(views, v)
+	}
+	return views, nil
+}
@@ -0,0 +1,66 @@
+/*
+ * server - a game engine
+ * Copyright (C) 2021  Michael D Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"testing"
+)
+
+func Test_Views(t *testing.T) {
+	is := is.New(t)
+	st, _ := Make()
+	usagi, kuma := st.Polity("usagi"), mkrival(st, "kuma")
+	kuma.viceroyOf = usagi
+	tosa, sanuki := st.Colony("tosa"), st.Colony("sanuki")
+	mktestship(st, usagi, tosa, "S2")
+	mktestship(st, usagi, tosa, "S1")
+
+	pv, err := st.PolityView("usagi")
+	is.NoErr(err)
+	is.Equal(pv.HomeColonyID, "sanuki")
+	is.Equal(pv.ColonyIDs, []string{"sanuki", "tosa"}) // sorted by id
+	is.Equal(pv.ShipIDs, []string{"S1", "S2"})
+	pv, err = st.PolityView("kuma")
+	is.NoErr(err)
+	is.Equal(pv.ViceroyOf, "usagi")
+	is.Equal(len(pv.ColonyIDs), 0)
+
+	colonies, err := st.ColonyViews("usagi")
+	is.NoErr(err)
+	is.Equal(len(colonies), 2)
+	is.Equal(colonies[0].PlanetID, "suisei")           // sanuki is on the planet
+	is.Equal(colonies[0].OrbitID, sanuki.orbitOf().id) // and in the planet's orbit
+	is.Equal(colonies[1].PlanetID, "")                 // tosa is in orbit
+	is.Equal(colonies[1].OrbitID, tosa.orbit.id)
+
+	ships, err := st.ShipViews("usagi")
+	is.NoErr(err)
+	is.Equal(len(ships), 2)
+	is.Equal(ships[0].HomePortID, "tosa")
+
+	for _, err := range []error{
+		func() error { _, err := st.PolityView("tora"); return err }(),
+		func() error { _, err := st.ColonyViews("tora"); return err }(),
+		func() error { _, err := st.ShipViews("tora"); return err }(),
+	} {
+		is.True(errors.Is(err, ERRNOTFOUND)) // unknown polity
+	}
+}
@@ -0,0 +1,302 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of the JSON:API specification that we implement.
+const Version = "1.0"
+
+// MediaType is the content type for JSON:API documents.
+const MediaType = "application/vnd.api+json"
+
+// Document is a JSON:API top-level document.
+// Data is either a Resource (a single resource) or a []Resource (a collection).
+type Document struct {
+	Data     interface{}            `json:"data"`
+	Included []Resource             `json:"included,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	Links    Links                  `json:"links,omitempty"`
+	JSONAPI  struct {
+		Version string `json:"version"`
+	} `json:"jsonapi"`
+}
+
+// Resource is a JSON:API resource object.
+type Resource struct {
+	Type          string                  `json:"type"`
+	ID            string                  `json:"id"`
+	Attributes    map[string]interface{}  `json:"attributes,omitempty"`
+	Relationships map[string]Relationship `json:"relationships,omitempty"`
+	Links         Links                   `json:"links,omitempty"`
+}
+
+// Identifier is a JSON:API resource identifier object.
+type Identifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relationship is a JSON:API relationship object.
+// Data is either an *Identifier (to-one) or an []Identifier (to-many).
+// It is omitted when the relationship only has links.
+type Relationship struct {
+	Data  interface{} `json:"data,omitempty"`
+	Links Links       `json:"links,omitempty"`
+}
+
+// Links is a JSON:API links object, for example "self" or "related".
+type Links map[string]string
+
+// ToOne returns a relationship to a single resource.
+func ToOne(typ, id string) Relationship {
+	return Relationship{Data: &Identifier{Type: typ, ID: id}}
+}
+
+// ToMany returns a relationship to a list of resources.
+// The list is never nil so that an empty relationship is sent as [].
+func ToMany(typ string, ids ...string) Relationship {
+	data := []Identifier{}
+	for _, id := range ids {
+		data = append(data, Identifier{Type: typ, ID: id})
+	}
+	return Relationship{Data: data}
+}
+
+// Related returns a relationship that only links to the related resources.
+func Related(link string) Relationship {
+	return Relationship{Links: Links{"related": link}}
+}
+
+// DefaultPageLimit is the number of resources in a page when the request doesn't set one.
+const DefaultPageLimit = 25
+
+// MaxPageLimit is the largest number of resources that a page may have.
+const MaxPageLimit = 100
+
+// Query is the JSON:API parameters from a request that shape the response:
+// the relationship paths to include, the sparse fieldsets, the sort keys,
+// the filters, and the page to return.
+type Query struct {
+	include map[string]bool
+	fields  map[string]map[string]bool
+	Sort    []string          // sort keys, a leading "-" sorts in descending order
+	Filter  map[string]string // filters, from "filter[name]=value"
+	Offset  int               // from "page[offset]"
+	Limit   int               // from "page[limit]", never more than MaxPageLimit
+}
+
+// ParseQuery returns the include and fields parameters of the request.
+// The include paths must be in the list of paths that the handler supports.
+func ParseQuery(r *http.Request, includes ...string) (Query, error) {
+	q := Query{
+		include: make(map[string]bool),
+		fields:  make(map[string]map[string]bool),
+		Filter:  make(map[string]string),
+		Limit:   DefaultPageLimit,
+	}
+	allowed := make(map[string]bool)
+	for _, path := range includes {
+		allowed[path] = true
+	}
+	values := r.URL.Query()
+	for _, path := range splitList(values.Get("include")) {
+		if !allowed[path] {
+			return Query{}, fmt.Errorf("include: unsupported relationship path %q", path)
+		}
+		// including a path includes every resource along it
+		for i := range path {
+			if path[i] == '.' {
+				q.include[path[:i]] = true
+			}
+		}
+		q.include[path] = true
+	}
+	for key, list := range values {
+		if typ, ok := bracketed(key, "fields"); ok {
+			q.fields[typ] = make(map[string]bool)
+			for _, field := range splitList(strings.Join(list, ",")) {
+				q.fields[typ][field] = true
+			}
+		} else if name, ok := bracketed(key, "filter"); ok {
+			q.Filter[name] = values.Get(key)
+		}
+	}
+	q.Sort = splitList(values.Get("sort"))
+	if value := values.Get("page[offset]"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return Query{}, fmt.Errorf("page[offset]: invalid value %q", value)
+		}
+		q.Offset = n
+	}
+	if value := values.Get("page[limit]"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxPageLimit {
+			return Query{}, fmt.Errorf("page[limit]: invalid value %q: must be 1 to %d", value, MaxPageLimit)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+// Paginate sets the meta and the first, prev, next, and last links of
+// the document for the page of results that the query selected. Total is
+// the number of results before paging. The links are the request's URL
+// with only the page parameters changed, so they keep the sort and filters.
+func (q Query) Paginate(r *http.Request, doc *Document, total int) {
+	link := func(offset int) string {
+		u := *r.URL
+		values := u.Query()
+		values.Set("page[offset]", strconv.Itoa(offset))
+		values.Set("page[limit]", strconv.Itoa(q.Limit))
+		u.RawQuery = values.Encode()
+		return u.RequestURI()
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / q.Limit * q.Limit
+	}
+	if doc.Links == nil {
+		doc.Links = Links{}
+	}
+	doc.Links["first"] = link(0)
+	doc.Links["last"] = link(last)
+	if q.Offset > 0 {
+		prev := q.Offset - q.Limit
+		if prev < 0 {
+			prev = 0
+		} else if prev > last {
+			prev = last
+		}
+		doc.Links["prev"] = link(prev)
+	}
+	if q.Offset+q.Limit < total {
+		doc.Links["next"] = link(q.Offset + q.Limit)
+	}
+	if doc.Meta == nil {
+		doc.Meta = make(map[string]interface{})
+	}
+	doc.Meta["total"] = total
+	doc.Meta["offset"] = q.Offset
+	doc.Meta["limit"] = q.Limit
+}
+
+// Includes returns true if the relationship path was requested.
+func (q Query) Includes(path string) bool {
+	return q.include[path]
+}
+
+// sparse returns the resource with only the fields requested for its type.
+func (q Query) sparse(rs Resource) Resource {
+	fields, ok := q.fields[rs.Type]
+	if !ok {
+		return rs
+	}
+	out := Resource{Type: rs.Type, ID: rs.ID, Links: rs.Links}
+	for k, v := range rs.Attributes {
+		if fields[k] {
+			if out.Attributes == nil {
+				out.Attributes = make(map[string]interface{})
+			}
+			out.Attributes[k] = v
+		}
+	}
+	for k, v := range rs.Relationships {
+		if fields[k] {
+			if out.Relationships == nil {
+				out.Relationships = make(map[string]Relationship)
+			}
+			out.Relationships[k] = v
+		}
+	}
+	return out
+}
+
+// Render writes the document after applying the sparse fieldsets from
+// the query. Included resources are sent once, even if they were added
+// more than once, and never repeat a resource that is in the primary data.
+func Render(w http.ResponseWriter, r *http.Request, status int, doc Document, q Query) {
+	seen := make(map[Identifier]bool)
+	switch data := doc.Data.(type) {
+	case Resource:
+		seen[Identifier{data.Type, data.ID}] = true
+		doc.Data = q.sparse(data)
+	case []Resource:
+		list := []Resource{}
+		for _, rs := range data {
+			seen[Identifier{rs.Type, rs.ID}] = true
+			list = append(list, q.sparse(rs))
+		}
+		doc.Data = list
+	}
+	var included []Resource
+	for _, rs := range doc.Included {
+		if id := (Identifier{rs.Type, rs.ID}); !seen[id] {
+			seen[id] = true
+			included = append(included, q.sparse(rs))
+		}
+	}
+	sort.SliceStable(included, func(i, j int) bool {
+		if included[i].Type != included[j].Type {
+			return included[i].Type < included[j].Type
+		}
+		return included[i].ID < included[j].ID
+	})
+	doc.Included = included
+	if doc.Links == nil {
+		doc.Links = Links{}
+	}
+	if _, ok := doc.Links["self"]; !ok {
+		doc.Links["self"] = r.URL.RequestURI()
+	}
+	doc.JSONAPI.Version = Version
+
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		log.Printf("[http] error writing response: %+v\n", err)
+	}
+}
+
+// bracketed returns the name from a parameter like "prefix[name]".
+func bracketed(key, prefix string) (string, bool) {
+	if !strings.HasPrefix(key, prefix+"[") || !strings.HasSuffix(key, "]") {
+		return "", false
+	}
+	return key[len(prefix)+1 : len(key)-1], true
+}
+
+// splitList returns the non-empty, comma separated values in the string.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,182 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"github.com/matryer/is"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func Test_ParseQuery(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		query    string
+		err      bool
+		includes []string // paths that must be included
+		excludes []string // paths that must not be included
+	}{
+		{"nothing", "", false, nil, []string{"colonies", "ships"}},
+		{"one path", "include=colonies", false, []string{"colonies"}, []string{"ships"}},
+		{"two paths", "include=colonies,%20ships", false, []string{"colonies", "ships"}, nil},
+		{"nested path includes its parents", "include=ships.home_port", false, []string{"ships", "ships.home_port"}, []string{"colonies"}},
+		{"unsupported path", "include=systems", true, nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			q, err := ParseQuery(httptest.NewRequest("GET", "/api/polity/usagi?"+tc.query, nil), "colonies", "ships", "ships.home_port")
+			is.Equal(err != nil, tc.err) // error
+			for _, path := range tc.includes {
+				is.True(q.Includes(path)) // included
+			}
+			for _, path := range tc.excludes {
+				is.True(!q.Includes(path)) // not included
+			}
+		})
+	}
+}
+
+func Test_ToMany(t *testing.T) {
+	is := is.New(t)
+	data, err := json.Marshal(ToMany("ships"))
+	is.NoErr(err)
+	is.Equal(string(data), `{"data":[]}`) // an empty relationship is sent as []
+	data, err = json.Marshal(ToOne("colonies", "tosa"))
+	is.NoErr(err)
+	is.Equal(string(data), `{"data":{"type":"colonies","id":"tosa"}}`)
+}
+
+func Test_Render(t *testing.T) {
+	is := is.New(t)
+	polity := Resource{
+		Type:          "polities",
+		ID:            "usagi",
+		Attributes:    map[string]interface{}{"name": "Usagi", "motto": "hop"},
+		Relationships: map[string]Relationship{"ships": ToMany("ships", "S2", "S1")},
+	}
+	ship := func(id string) Resource {
+		return Resource{Type: "ships", ID: id, Attributes: map[string]interface{}{"name": id}}
+	}
+	r := httptest.NewRequest("GET", "/api/polity/usagi?include=ships&fields[polities]=name", nil)
+	q, err := ParseQuery(r, "ships")
+	is.NoErr(err)
+	doc := Document{
+		Data: polity,
+		Included: []Resource{
+			ship("S2"),
+			ship("S1"),
+			ship("S2"),                      // added twice
+			{Type: "polities", ID: "usagi"}, // already the primary data
+		},
+	}
+	w := httptest.NewRecorder()
+	Render(w, r, 200, doc, q)
+	is.Equal(w.Code, 200)
+	is.Equal(w.Header().Get("Content-Type"), MediaType)
+
+	var got struct {
+		Data     Resource   `json:"data"`
+		Included []Resource `json:"included"`
+		Links    Links      `json:"links"`
+		JSONAPI  struct {
+			Version string `json:"version"`
+		} `json:"jsonapi"`
+	}
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+	is.Equal(got.Data.Attributes, map[string]interface{}{"name": "Usagi"}) // sparse fieldset
+	is.Equal(len(got.Data.Relationships), 0)                               // relationships are fields too
+	is.Equal(len(got.Included), 2)                                         // each resource is sent once
+	is.Equal(got.Included[0].ID, "S1")                                     // included resources are sorted
+	is.Equal(got.Included[1].ID, "S2")
+	is.Equal(got.Links["self"], "/api/polity/usagi?include=ships&fields[polities]=name")
+	is.Equal(got.JSONAPI.Version, Version)
+}
+
+func Test_ParsePage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		query  string
+		err    bool
+		offset int
+		limit  int
+	}{
+		{"defaults", "", false, 0, DefaultPageLimit},
+		{"offset and limit", "page[offset]=20&page[limit]=10", false, 20, 10},
+		{"largest limit", "page[limit]=100", false, 0, MaxPageLimit},
+		{"negative offset", "page[offset]=-1", true, 0, 0},
+		{"offset not a number", "page[offset]=two", true, 0, 0},
+		{"zero limit", "page[limit]=0", true, 0, 0},
+		{"limit too large", "page[limit]=101", true, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			q, err := ParseQuery(httptest.NewRequest("GET", "/api/polity/usagi/systems?"+tc.query, nil))
+			is.Equal(err != nil, tc.err) // error
+			is.Equal(q.Offset, tc.offset)
+			is.Equal(q.Limit, tc.limit)
+		})
+	}
+}
+
+func Test_Paginate(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		offset, limit, total    int
+		first, prev, next, last string // offsets in the links, "" if the link is not set
+	}{
+		{"empty", 0, 10, 0, "0", "", "", "0"},
+		{"one page", 0, 10, 7, "0", "", "", "0"},
+		{"exactly one page", 0, 10, 10, "0", "", "", "0"},
+		{"first of three", 0, 10, 25, "0", "", "10", "20"},
+		{"middle of three", 10, 10, 25, "0", "0", "20", "20"},
+		{"last of three", 20, 10, 25, "0", "10", "", "20"},
+		{"unaligned offset", 5, 10, 25, "0", "0", "15", "20"},
+		{"past the end", 40, 10, 25, "0", "20", "", "20"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			r := httptest.NewRequest("GET", "/api/polity/usagi/systems?sort=name", nil)
+			q, err := ParseQuery(r)
+			is.NoErr(err)
+			q.Offset, q.Limit = tc.offset, tc.limit
+			doc := Document{}
+			q.Paginate(r, &doc, tc.total)
+			offsetOf := func(name string) string {
+				link, ok := doc.Links[name]
+				if !ok {
+					return ""
+				}
+				u, err := url.Parse(link)
+				is.NoErr(err)
+				is.Equal(u.Path, "/api/polity/usagi/systems")                  // links keep the path
+				is.Equal(u.Query().Get("sort"), "name")                        // links keep the query
+				is.Equal(u.Query().Get("page[limit]"), strconv.Itoa(tc.limit)) // links keep the limit
+				return u.Query().Get("page[offset]")
+			}
+			is.Equal(offsetOf("first"), tc.first)
+			is.Equal(offsetOf("prev"), tc.prev)
+			is.Equal(offsetOf("next"), tc.next)
+			is.Equal(offsetOf("last"), tc.last)
+			is.Equal(doc.Meta["total"], tc.total)
+			is.Equal(doc.Meta["offset"], tc.offset)
+			is.Equal(doc.Meta["limit"], tc.limit)
+		})
+	}
+}
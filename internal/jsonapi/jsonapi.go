@@ -19,6 +19,7 @@ package jsonapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -44,7 +45,16 @@ func Errors(w http.ResponseWriter, r *http.Request, status int, details interfac
 	}
 }
 
-func Error(w http.ResponseWriter, r *http.Request, status int, errors ...error) {
+// Coded is implemented by errors that can fill in the code, title, and
+// source pointer of a JSON:API error object.
+type Coded interface {
+	error
+	ErrorCode() string
+	ErrorTitle() string
+	ErrorPointer() string
+}
+
+func Error(w http.ResponseWriter, r *http.Request, status int, errs ...error) {
 	type errorSource struct {
 		Pointer   string `json:"pointer,omitempty"`
 		Parameter string `json:"parameter,omitempty"`
@@ -75,7 +85,21 @@ func Error(w http.ResponseWriter, r *http.Request, status int, errors ...error)
 	})
 
 	// then append any error details that the user provided
-	for _, err := range errors {
+	for _, err := range errs {
+		var coded Coded
+		if errors.As(err, &coded) {
+			obj := errorObject{
+				Status: fmt.Sprintf("%d", status),
+				Code:   coded.ErrorCode(),
+				Title:  coded.ErrorTitle(),
+				Detail: coded.Error(),
+			}
+			if pointer := coded.ErrorPointer(); pointer != "" {
+				obj.Source = &errorSource{Pointer: pointer}
+			}
+			failure.Errors = append(failure.Errors, obj)
+			continue
+		}
 		failure.Errors = append(failure.Errors, errorObject{
 			Detail: fmt.Sprintf("%+v", err),
 		})
@@ -0,0 +1,122 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package lists implements the sorting, filtering, and paging options
+// shared by the services that return lists of resources.
+package lists
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidFilter is returned when a filter is not supported by the list
+// or has a value that the list does not accept.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ErrInvalidSort is returned when a sort key is not supported by the list.
+var ErrInvalidSort = errors.New("invalid sort key")
+
+// Options selects, orders, and pages the results of a list.
+type Options struct {
+	Sort   []string          // keys to sort by, a leading "-" sorts in descending order
+	Filter map[string]string // filters to apply, by name
+	Offset int               // number of results to skip
+	Limit  int               // maximum number of results, zero for no limit
+}
+
+// Comparer returns less than zero, zero, or greater than zero when
+// item i sorts before, the same as, or after item j.
+type Comparer func(i, j int) int
+
+// Sort sorts the list by the keys. Items that are the same for every
+// key are sorted by the tie-breaker so that results are stable between requests.
+func Sort(list interface{}, keys []string, cmps map[string]Comparer, tie Comparer) error {
+	type key struct {
+		cmp  Comparer
+		desc bool
+	}
+	var order []key
+	for _, name := range keys {
+		k := key{desc: strings.HasPrefix(name, "-")}
+		if k.cmp = cmps[strings.TrimPrefix(name, "-")]; k.cmp == nil {
+			return fmt.Errorf("%w %q", ErrInvalidSort, name)
+		}
+		order = append(order, k)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		for _, k := range order {
+			if c := k.cmp(i, j); c != 0 {
+				return (c < 0) != k.desc
+			}
+		}
+		return tie(i, j) < 0
+	})
+	return nil
+}
+
+// Page returns the bounds of the page of results.
+func (o Options) Page(total int) (lo, hi int) {
+	lo, hi = o.Offset, total
+	if lo < 0 {
+		lo = 0
+	} else if lo > total {
+		lo = total
+	}
+	if o.Limit > 0 && lo+o.Limit < hi {
+		hi = lo + o.Limit
+	}
+	return lo, hi
+}
+
+// CheckFilters returns an error if a filter isn't one that the list supports.
+func (o Options) CheckFilters(supported ...string) error {
+	for name := range o.Filter {
+		ok := false
+		for _, s := range supported {
+			ok = ok || name == s
+		}
+		if !ok {
+			return fmt.Errorf("%w %q", ErrInvalidFilter, name)
+		}
+	}
+	return nil
+}
+
+// OwnerIsMe returns true if the "owner" filter is set to "me".
+// The only other value allowed is "any", which is the same as not setting it.
+func (o Options) OwnerIsMe() (bool, error) {
+	switch value := o.Filter["owner"]; value {
+	case "", "any":
+		return false, nil
+	case "me":
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w owner %q", ErrInvalidFilter, value)
+	}
+}
+
+// CompareInts returns the order of two integers.
+func CompareInts(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package lists
+
+import (
+	"errors"
+	"github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func Test_Sort(t *testing.T) {
+	type item struct {
+		id   string
+		name string
+		size int
+	}
+	for _, tc := range []struct {
+		name string
+		keys []string
+		err  error
+		ids  string
+	}{
+		{"no keys sorts by the tie-breaker", nil, nil, "a b c d"},
+		{"one key", []string{"size"}, nil, "c a d b"},
+		{"descending", []string{"-size"}, nil, "b d a c"},
+		{"ties are broken by the next key", []string{"name", "size"}, nil, "d b c a"},
+		{"ties are broken by the tie-breaker", []string{"name"}, nil, "b d a c"},
+		{"invalid key", []string{"size", "mass"}, ErrInvalidSort, ""},
+		{"invalid descending key", []string{"-mass"}, ErrInvalidSort, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			list := []item{{"d", "kuma", 30}, {"a", "usagi", 20}, {"c", "usagi", 10}, {"b", "kuma", 40}}
+			err := Sort(list, tc.keys, map[string]Comparer{
+				"name": func(i, j int) int { return strings.Compare(list[i].name, list[j].name) },
+				"size": func(i, j int) int { return CompareInts(list[i].size, list[j].size) },
+			}, func(i, j int) int { return strings.Compare(list[i].id, list[j].id) })
+			is.True(errors.Is(err, tc.err)) // error
+			if err != nil {
+				return
+			}
+			var ids []string
+			for _, v := range list {
+				ids = append(ids, v.id)
+			}
+			is.Equal(strings.Join(ids, " "), tc.ids)
+		})
+	}
+}
+
+func Test_Page(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		offset, limit int
+		total         int
+		lo, hi        int
+	}{
+		{"no limit", 0, 0, 7, 0, 7},
+		{"first page", 0, 3, 7, 0, 3},
+		{"middle page", 3, 3, 7, 3, 6},
+		{"short last page", 6, 3, 7, 6, 7},
+		{"offset without a limit", 4, 0, 7, 4, 7},
+		{"past the end", 9, 3, 7, 7, 7},
+		{"negative offset", -2, 3, 7, 0, 3},
+		{"empty list", 0, 3, 0, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			lo, hi := Options{Offset: tc.offset, Limit: tc.limit}.Page(tc.total)
+			is.Equal(lo, tc.lo)
+			is.Equal(hi, tc.hi)
+		})
+	}
+}
+
+func Test_Filters(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		filter    map[string]string
+		err       error
+		ownerIsMe bool
+	}{
+		{"none", nil, nil, false},
+		{"owner me", map[string]string{"owner": "me"}, nil, true},
+		{"owner any", map[string]string{"owner": "any", "kind": "open"}, nil, false},
+		{"invalid owner", map[string]string{"owner": "kuma"}, ErrInvalidFilter, false},
+		{"unsupported filter", map[string]string{"within": "10ly of 01-01-01"}, ErrInvalidFilter, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+			o := Options{Filter: tc.filter}
+			err := o.CheckFilters("owner", "kind")
+			if err == nil {
+				var ownerIsMe bool
+				ownerIsMe, err = o.OwnerIsMe()
+				is.Equal(ownerIsMe, tc.ownerIsMe)
+			}
+			is.True(errors.Is(err, tc.err)) // error
+		})
+	}
+}
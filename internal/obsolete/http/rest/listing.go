@@ -25,19 +25,21 @@ import (
 	"github.com/mdhender/server/internal/obsolete/listing"
 	"github.com/mdhender/server/internal/way"
 	"net/http"
+	"strings"
 )
 
-// GetGame returns a specific game
+// GetGame returns a specific game.
+// It supports including the game's players and their users.
 func GetGame(ls listing.Service) http.HandlerFunc {
-	type okResult struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
-	}
-
 	a := &auth.Authorization{ID: "usagi", Roles: make(map[string]bool)}
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := jsonapi.ParseQuery(r, "players", "players.user")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
 		id := way.Param(r.Context(), "id")
 		game, err := ls.GetGame(a, id)
 		if err != nil {
@@ -48,20 +50,34 @@ func GetGame(ls listing.Service) http.HandlerFunc {
 			jsonapi.Error(w, r, http.StatusInternalServerError, err)
 			return
 		}
-		jsonapi.Ok(w, r, http.StatusOK, okResult{game.ID, game.Name})
+		players, err := ls.GetGamePlayers(a, id)
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		doc := jsonapi.Document{Data: gameResource(game, players)}
+		if q.Includes("players") {
+			if doc.Included, err = includePlayers(ls, a, id, players, q.Includes("players.user")); err != nil {
+				jsonapi.Error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
 	}
 }
 
-// GetGamePlayer returns details for a player in specific game
+// GetGamePlayer returns details for a player in specific game.
+// It supports including the player's game and user.
 func GetGamePlayer(ls listing.Service) http.HandlerFunc {
-	type okResult struct {
-		Name string `json:"name"`
-	}
-
 	a := &auth.Authorization{ID: "usagi", Roles: make(map[string]bool)}
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := jsonapi.ParseQuery(r, "game", "user")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
 		id := way.Param(r.Context(), "id")
 		name := way.Param(r.Context(), "player_name")
 		player, err := ls.GetGamePlayer(a, id, name)
@@ -73,46 +89,76 @@ func GetGamePlayer(ls listing.Service) http.HandlerFunc {
 			jsonapi.Error(w, r, http.StatusInternalServerError, err)
 			return
 		}
-		jsonapi.Ok(w, r, http.StatusOK, okResult{player.Name})
+		doc := jsonapi.Document{Data: playerResource(id, player)}
+		if q.Includes("game") {
+			included, err := includeGame(ls, a, id)
+			if err != nil {
+				jsonapi.Error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			doc.Included = append(doc.Included, included...)
+		}
+		if q.Includes("user") {
+			included, err := includeUser(ls, a, player.UserName)
+			if err != nil {
+				jsonapi.Error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			doc.Included = append(doc.Included, included...)
+		}
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
 	}
 }
 
-// GetGamePlayers returns all the players specific game
+// GetGamePlayers returns a page of the players in a specific game.
+// It supports sorting, filtering, and including the users of the players.
 func GetGamePlayers(ls listing.Service) http.HandlerFunc {
-	type okResult []string
-
 	a := &auth.Authorization{ID: "usagi", Roles: make(map[string]bool)}
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := jsonapi.ParseQuery(r, "user")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
 		id := way.Param(r.Context(), "id")
-		players, err := ls.GetGamePlayers(a, id)
+		players, total, err := ls.ListGamePlayers(a, id, listOptions(q))
 		if err != nil {
-			if errors.Is(err, listing.ErrGameNotFound) {
-				jsonapi.Error(w, r, http.StatusNotFound, err)
-				return
-			}
-			jsonapi.Error(w, r, http.StatusInternalServerError, err)
+			listError(w, r, err)
 			return
 		}
-		var list okResult = []string{} // create an empty list since we never return nil
-		for _, p := range players {
-			list = append(list, p)
+		list := []jsonapi.Resource{} // create an empty list since we never return nil
+		doc := jsonapi.Document{}
+		for _, player := range players {
+			list = append(list, playerResource(id, player))
+			if q.Includes("user") {
+				included, err := includeUser(ls, a, player.UserName)
+				if err != nil {
+					jsonapi.Error(w, r, http.StatusInternalServerError, err)
+					return
+				}
+				doc.Included = append(doc.Included, included...)
+			}
 		}
-		jsonapi.Ok(w, r, http.StatusOK, list)
+		doc.Data = list
+		q.Paginate(r, &doc, total)
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
 	}
 }
 
-// GetGameSystem returns a specific system in a game
+// GetGameSystem returns a specific system in a game.
+// It supports including the system's game.
 func GetGameSystem(ls listing.Service) http.HandlerFunc {
-	type okResult struct {
-		Name string `json:"name"`
-	}
-
 	a := &auth.Authorization{ID: "usagi", Roles: make(map[string]bool)}
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := jsonapi.ParseQuery(r, "game")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
 		id := way.Param(r.Context(), "id")
 		name := way.Param(r.Context(), "system_name")
 		system, err := ls.GetGameSystem(a, id, name)
@@ -124,20 +170,32 @@ func GetGameSystem(ls listing.Service) http.HandlerFunc {
 			jsonapi.Error(w, r, http.StatusBadRequest, err)
 			return
 		}
-		jsonapi.Ok(w, r, http.StatusOK, okResult{system.Name})
+		doc := jsonapi.Document{Data: systemResource(id, system.ID, system.Name)}
+		if q.Includes("game") {
+			if doc.Included, err = includeGame(ls, a, id); err != nil {
+				jsonapi.Error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
 	}
 }
 
-// GetGameSystems returns a list of all systems in a game
+// GetGameSystems returns a list of all systems in a game.
+// It is not paged, sorted, or filtered because the repository does not
+// list systems yet; it returns at most one system.
 func GetGameSystems(ls listing.Service) http.HandlerFunc {
-	type okResult []string
-
 	a := &auth.Authorization{ID: "usagi", Roles: make(map[string]bool)}
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := jsonapi.ParseQuery(r)
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
 		id := way.Param(r.Context(), "id")
-		_, err := ls.GetGameSystems(a, id)
+		systems, err := ls.GetGameSystems(a, id)
 		if err != nil {
 			if errors.Is(err, listing.ErrGameNotFound) {
 				jsonapi.Error(w, r, http.StatusNotFound, err)
@@ -146,50 +204,66 @@ func GetGameSystems(ls listing.Service) http.HandlerFunc {
 			jsonapi.Error(w, r, http.StatusBadRequest, err)
 			return
 		}
-		jsonapi.Ok(w, r, http.StatusOK, okResult{})
+		list := []jsonapi.Resource{} // create an empty list since we never return nil
+		if systems.ID != "" {
+			list = append(list, systemResource(id, systems.ID, systems.Name))
+		}
+		jsonapi.Render(w, r, http.StatusOK, jsonapi.Document{Data: list}, q)
 	}
 }
 
-// GetGames returns all games
+// GetGames returns a page of the games.
+// It supports sorting, filtering, and including the players of the games and their users.
 func GetGames(ls listing.Service) http.HandlerFunc {
-	type detail struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
-	}
-	type okResult []detail
-
-	type formInput struct {
-		Data []string `json:"data"`
-	}
-
 	a := &auth.Authorization{ID: "usagi", Roles: make(map[string]bool)}
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		var ids []string
-		var list okResult = []detail{} // create an empty list since we never return nil
-		for _, game := range ls.GetGames(a, ids...) {
-			list = append(list, detail{
-				ID:   game.ID,
-				Name: game.Name,
-			})
+		q, err := jsonapi.ParseQuery(r, "players", "players.user")
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		games, total, err := ls.ListGames(a, listOptions(q))
+		if err != nil {
+			listError(w, r, err)
+			return
+		}
+		list := []jsonapi.Resource{} // create an empty list since we never return nil
+		doc := jsonapi.Document{}
+		for _, game := range games {
+			players, err := ls.GetGamePlayers(a, game.ID)
+			if err != nil {
+				jsonapi.Error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			list = append(list, gameResource(game, players))
+			if q.Includes("players") {
+				included, err := includePlayers(ls, a, game.ID, players, q.Includes("players.user"))
+				if err != nil {
+					jsonapi.Error(w, r, http.StatusInternalServerError, err)
+					return
+				}
+				doc.Included = append(doc.Included, included...)
+			}
 		}
-		jsonapi.Ok(w, r, http.StatusOK, list)
+		doc.Data = list
+		q.Paginate(r, &doc, total)
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
 	}
 }
 
 // GetUser returns a specific user
 func GetUser(ls listing.Service) http.HandlerFunc {
-	type okResult struct {
-		ID    string `json:"id"`
-		Name  string `json:"name"`
-		Email string `json:"email"`
-	}
-
 	a := &auth.Authorization{ID: "usagi", Roles: make(map[string]bool)}
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := jsonapi.ParseQuery(r)
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
 		id := way.Param(r.Context(), "id")
 		user, err := ls.GetUser(a, id)
 		if err != nil {
@@ -200,19 +274,13 @@ func GetUser(ls listing.Service) http.HandlerFunc {
 			jsonapi.Error(w, r, http.StatusInternalServerError, err)
 			return
 		}
-		jsonapi.Ok(w, r, http.StatusOK, okResult{user.ID, user.Name, user.Email})
+		jsonapi.Render(w, r, http.StatusOK, jsonapi.Document{Data: userResource(user)}, q)
 	}
 }
 
-// GetUsers returns all users
+// GetUsers returns a page of the users.
+// It supports sorting and filtering by id.
 func GetUsers(ls listing.Service) http.HandlerFunc {
-	type detail struct {
-		ID    string `json:"id"`
-		Name  string `json:"name"`
-		Email string `json:"email"`
-	}
-	type okResult []detail
-
 	type formInput struct {
 		Data []string `json:"data"`
 	}
@@ -221,7 +289,11 @@ func GetUsers(ls listing.Service) http.HandlerFunc {
 	a.Roles["admin"] = true
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		var ids []string
+		q, err := jsonapi.ParseQuery(r)
+		if err != nil {
+			jsonapi.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
 		if r.Method == "POST" { // support sending a list of ids to fetch
 			// Enforce a maximum read of 1MB from the request body.
 			dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
@@ -233,19 +305,22 @@ func GetUsers(ls listing.Service) http.HandlerFunc {
 				return
 			}
 			if len(input.Data) != 0 {
-				ids = input.Data
+				q.Filter["id"] = strings.Join(input.Data, ",")
 			}
 		}
 
-		var list okResult = []detail{} // create an empty list since we never return nil
-		for _, user := range ls.GetUsers(a, ids...) {
-			list = append(list, detail{
-				ID:    user.ID,
-				Email: user.Email,
-				Name:  user.Name,
-			})
+		users, total, err := ls.ListUsers(a, listOptions(q))
+		if err != nil {
+			listError(w, r, err)
+			return
+		}
+		list := []jsonapi.Resource{} // create an empty list since we never return nil
+		for _, user := range users {
+			list = append(list, userResource(user))
 		}
-		jsonapi.Ok(w, r, http.StatusOK, list)
+		doc := jsonapi.Document{Data: list}
+		q.Paginate(r, &doc, total)
+		jsonapi.Render(w, r, http.StatusOK, doc, q)
 	}
 }
 
@@ -0,0 +1,170 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package rest
+
+import (
+	"errors"
+	"github.com/mdhender/server/internal/jsonapi"
+	"github.com/mdhender/server/internal/lists"
+	"github.com/mdhender/server/internal/obsolete/auth"
+	"github.com/mdhender/server/internal/obsolete/listing"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// This file builds the JSON:API resource objects returned by the listing handlers.
+
+// playerID returns the resource id for a player.
+// Player names are only unique within a game, so the id includes the game.
+func playerID(gameID, name string) string {
+	return gameID + "." + name
+}
+
+// gameResource returns the resource for a game and its players.
+func gameResource(game listing.Game, players listing.PlayerList) jsonapi.Resource {
+	var ids []string
+	for _, name := range players {
+		ids = append(ids, playerID(game.ID, name))
+	}
+	rsPlayers := jsonapi.ToMany("players", ids...)
+	rsPlayers.Links = jsonapi.Links{"related": "/api/game/" + url.PathEscape(game.ID) + "/players"}
+	return jsonapi.Resource{
+		Type: "games",
+		ID:   game.ID,
+		Attributes: map[string]interface{}{
+			"name": game.Name,
+		},
+		Relationships: map[string]jsonapi.Relationship{
+			"players": rsPlayers,
+			"systems": jsonapi.Related("/api/game/" + url.PathEscape(game.ID) + "/systems"),
+		},
+		Links: jsonapi.Links{"self": "/api/game/" + url.PathEscape(game.ID)},
+	}
+}
+
+// playerResource returns the resource for a player in a game.
+func playerResource(gameID string, player listing.Player) jsonapi.Resource {
+	return jsonapi.Resource{
+		Type: "players",
+		ID:   playerID(gameID, player.Name),
+		Attributes: map[string]interface{}{
+			"name": player.Name,
+		},
+		Relationships: map[string]jsonapi.Relationship{
+			"game": jsonapi.ToOne("games", gameID),
+			"user": jsonapi.ToOne("users", player.UserName),
+		},
+		Links: jsonapi.Links{"self": "/api/game/" + url.PathEscape(gameID) + "/player/" + url.PathEscape(player.Name)},
+	}
+}
+
+// systemResource returns the resource for a system in a game.
+// The id is the engine's id for the system, the same id that the polity
+// endpoints use.
+func systemResource(gameID, id, name string) jsonapi.Resource {
+	return jsonapi.Resource{
+		Type: "systems",
+		ID:   id,
+		Attributes: map[string]interface{}{
+			"name": name,
+		},
+		Relationships: map[string]jsonapi.Relationship{
+			"game": jsonapi.ToOne("games", gameID),
+		},
+		Links: jsonapi.Links{"self": "/api/game/" + url.PathEscape(gameID) + "/system/" + url.PathEscape(name)},
+	}
+}
+
+// userResource returns the resource for a user.
+func userResource(user listing.User) jsonapi.Resource {
+	return jsonapi.Resource{
+		Type: "users",
+		ID:   user.ID,
+		Attributes: map[string]interface{}{
+			"name":    user.Name,
+			"email":   user.Email,
+			"created": user.Created.UTC().Format(time.RFC3339),
+		},
+		Links: jsonapi.Links{"self": "/api/user/" + url.PathEscape(user.ID)},
+	}
+}
+
+// includeGame returns the game as a list of included resources.
+func includeGame(ls listing.Service, a *auth.Authorization, id string) ([]jsonapi.Resource, error) {
+	game, err := ls.GetGame(a, id)
+	if err != nil {
+		return nil, err
+	}
+	players, err := ls.GetGamePlayers(a, id)
+	if err != nil {
+		return nil, err
+	}
+	return []jsonapi.Resource{gameResource(game, players)}, nil
+}
+
+// includePlayers returns the players in a game, and optionally their users,
+// as a list of included resources. Users that the caller is not allowed
+// to see are left out.
+func includePlayers(ls listing.Service, a *auth.Authorization, gameID string, names listing.PlayerList, withUsers bool) ([]jsonapi.Resource, error) {
+	var included []jsonapi.Resource
+	for _, name := range names {
+		player, err := ls.GetGamePlayer(a, gameID, name)
+		if err != nil {
+			return nil, err
+		}
+		included = append(included, playerResource(gameID, player))
+		if withUsers {
+			rs, err := includeUser(ls, a, player.UserName)
+			if err != nil {
+				return nil, err
+			}
+			included = append(included, rs...)
+		}
+	}
+	return included, nil
+}
+
+// includeUser returns the user as a list of included resources.
+// The list is empty if the caller is not allowed to see the user.
+func includeUser(ls listing.Service, a *auth.Authorization, id string) ([]jsonapi.Resource, error) {
+	user, err := ls.GetUser(a, id)
+	if err != nil {
+		if errors.Is(err, listing.ErrUserNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []jsonapi.Resource{userResource(user)}, nil
+}
+
+// listOptions returns the options for a listing service from the query.
+func listOptions(q jsonapi.Query) lists.Options {
+	return lists.Options{Sort: q.Sort, Filter: q.Filter, Offset: q.Offset, Limit: q.Limit}
+}
+
+// listError writes the error returned by a listing service's List method.
+func listError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, lists.ErrInvalidSort) || errors.Is(err, lists.ErrInvalidFilter) {
+		jsonapi.Error(w, r, http.StatusBadRequest, err)
+		return
+	} else if errors.Is(err, listing.ErrGameNotFound) {
+		jsonapi.Error(w, r, http.StatusNotFound, err)
+		return
+	}
+	jsonapi.Error(w, r, http.StatusInternalServerError, err)
+}
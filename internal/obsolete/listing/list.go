@@ -0,0 +1,141 @@
+// server - a game engine
+// Copyright (C) 2020  Michael D Henderson
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package listing
+
+import (
+	"fmt"
+	"github.com/mdhender/server/internal/lists"
+	"github.com/mdhender/server/internal/obsolete/auth"
+	"strings"
+	"time"
+)
+
+// compareTimes returns the order of two times.
+func compareTimes(a, b time.Time) int {
+	if a.Before(b) {
+		return -1
+	} else if a.After(b) {
+		return 1
+	}
+	return 0
+}
+
+// ListGames returns a page of the games that the caller is authorized to list.
+//
+// 1. Sort keys are "name" and "created". The default is by id.
+// 2. Filters are "status" ("open" or "completed") and "owner" ("me" for
+// games that the caller is a player in).
+func (s *service) ListGames(a *auth.Authorization, opts lists.Options) ([]Game, int, error) {
+	if err := opts.CheckFilters("status", "owner"); err != nil {
+		return nil, 0, err
+	}
+	ownerIsMe, err := opts.OwnerIsMe()
+	if err != nil {
+		return nil, 0, err
+	}
+	status, filterStatus := opts.Filter["status"]
+	if filterStatus && status != "open" && status != "completed" {
+		return nil, 0, fmt.Errorf("%w status %q", lists.ErrInvalidFilter, status)
+	}
+
+	list := []Game{}
+	for _, game := range s.r.GetGames(a) {
+		if filterStatus && game.Status != status {
+			continue
+		}
+		if ownerIsMe {
+			players, _, err := s.ListGamePlayers(a, game.ID, lists.Options{Filter: map[string]string{"owner": "me"}})
+			if err != nil {
+				return nil, 0, err
+			} else if len(players) == 0 {
+				continue
+			}
+		}
+		list = append(list, game)
+	}
+	err = lists.Sort(list, opts.Sort, map[string]lists.Comparer{
+		"name":    func(i, j int) int { return strings.Compare(list[i].Name, list[j].Name) },
+		"created": func(i, j int) int { return compareTimes(list[i].Created, list[j].Created) },
+	}, func(i, j int) int { return strings.Compare(list[i].ID, list[j].ID) })
+	if err != nil {
+		return nil, 0, err
+	}
+	lo, hi := opts.Page(len(list))
+	return list[lo:hi], len(list), nil
+}
+
+// ListGamePlayers returns a page of the players in a game.
+//
+// 1. The sort key is "name", which is also the default.
+// 2. The filter is "owner" ("me" for the caller's players).
+func (s *service) ListGamePlayers(a *auth.Authorization, id string, opts lists.Options) ([]Player, int, error) {
+	if err := opts.CheckFilters("owner"); err != nil {
+		return nil, 0, err
+	}
+	ownerIsMe, err := opts.OwnerIsMe()
+	if err != nil {
+		return nil, 0, err
+	}
+	names, err := s.r.GetGamePlayers(a, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	list := []Player{}
+	for _, name := range names {
+		player, err := s.r.GetGamePlayer(a, id, name)
+		if err != nil {
+			return nil, 0, err
+		} else if ownerIsMe && player.UserName != a.ID {
+			continue
+		}
+		list = append(list, player)
+	}
+	err = lists.Sort(list, opts.Sort, map[string]lists.Comparer{
+		"name": func(i, j int) int { return strings.Compare(list[i].Name, list[j].Name) },
+	}, func(i, j int) int { return strings.Compare(list[i].Name, list[j].Name) })
+	if err != nil {
+		return nil, 0, err
+	}
+	lo, hi := opts.Page(len(list))
+	return list[lo:hi], len(list), nil
+}
+
+// ListUsers returns a page of the users that the caller is authorized to list.
+//
+// 1. Sort keys are "name" and "created". The default is by id.
+// 2. The filter is "id", a comma separated list of the users to return.
+func (s *service) ListUsers(a *auth.Authorization, opts lists.Options) ([]User, int, error) {
+	if err := opts.CheckFilters("id"); err != nil {
+		return nil, 0, err
+	}
+	var ids []string
+	for _, id := range strings.Split(opts.Filter["id"], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	list := s.r.GetUsers(a, ids...)
+	err := lists.Sort(list, opts.Sort, map[string]lists.Comparer{
+		"name":    func(i, j int) int { return strings.Compare(list[i].Name, list[j].Name) },
+		"created": func(i, j int) int { return compareTimes(list[i].Created, list[j].Created) },
+	}, func(i, j int) int { return strings.Compare(list[i].ID, list[j].ID) })
+	if err != nil {
+		return nil, 0, err
+	}
+	lo, hi := opts.Page(len(list))
+	return list[lo:hi], len(list), nil
+}
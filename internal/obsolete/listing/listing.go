@@ -18,6 +18,7 @@ package listing
 
 import (
 	"errors"
+	"github.com/mdhender/server/internal/lists"
 	"github.com/mdhender/server/internal/obsolete/auth"
 	"time"
 )
@@ -52,13 +53,22 @@ type Service interface {
 	GetUser(a *auth.Authorization, id string) (User, error)
 	GetUsers(a *auth.Authorization, ids ...string) []User
 
+	// ListGames, ListGamePlayers, and ListUsers return one page of the
+	// filtered and sorted results along with the number of results that
+	// matched the filters.
+	ListGames(a *auth.Authorization, opts lists.Options) ([]Game, int, error)
+	ListGamePlayers(a *auth.Authorization, id string, opts lists.Options) ([]Player, int, error)
+	ListUsers(a *auth.Authorization, opts lists.Options) ([]User, int, error)
+
 	GetVersion() Version
 }
 
 // Game defines the properties of a game.
 type Game struct {
-	ID   string
-	Name string
+	ID      string
+	Name    string
+	Created time.Time
+	Status  string // "open" or "completed"
 }
 
 // Player defines the properties of a player.
@@ -72,11 +82,13 @@ type PlayerList []string
 
 // SystemDetail defines the properties of a system.
 type SystemDetail struct {
+	ID   string // the engine's id for the system
 	Name string
 }
 
 // SystemList defines a listing of system summaries
 type SystemList struct {
+	ID   string // the engine's id for the system
 	Name string
 }
 
@@ -31,14 +31,24 @@ func (m *Store) GetGame(a *auth.Authorization, id string) (listing.Game, error)
 	if isAdmin {
 		if game, ok := m.games.id[id]; ok {
 			return listing.Game{
-				ID:   game.id,
-				Name: game.name,
+				ID:      game.id,
+				Name:    game.name,
+				Created: game.created,
+				Status:  gameStatus(game),
 			}, nil
 		}
 	}
 	return listing.Game{}, listing.ErrGameNotFound
 }
 
+// gameStatus returns "completed" if the game has completed, otherwise "open".
+func gameStatus(game *game) string {
+	if game.completed.IsZero() {
+		return "open"
+	}
+	return "completed"
+}
+
 // GetGamePlayer returns data for a player in a game.
 // If the caller is not authorized or the game/player does not exist, it returns the not found error.
 func (m *Store) GetGamePlayer(a *auth.Authorization, id, name string) (listing.Player, error) {
@@ -97,8 +107,10 @@ func (m *Store) GetGames(a *auth.Authorization, ids ...string) []listing.Game {
 			isAuthorized := isAdmin || a.ID == game.id
 			if isAuthorized {
 				list = append(list, listing.Game{
-					ID:   game.id,
-					Name: game.name,
+					ID:      game.id,
+					Name:    game.name,
+					Created: game.created,
+					Status:  gameStatus(game),
 				})
 			}
 		}
@@ -110,8 +122,10 @@ func (m *Store) GetGames(a *auth.Authorization, ids ...string) []listing.Game {
 		if isAuthorized {
 			if game, ok := m.games.id[id]; ok {
 				list = append(list, listing.Game{
-					ID:   game.id,
-					Name: game.name,
+					ID:      game.id,
+					Name:    game.name,
+					Created: game.created,
+					Status:  gameStatus(game),
 				})
 			}
 		}